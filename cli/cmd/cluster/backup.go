@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/manager"
+	"github.com/spf13/cobra"
+)
+
+var (
+	BackupCmd = &cobra.Command{
+		Use:  "backup",
+		RunE: backupRun,
+	}
+
+	backupCmdOptions struct {
+		output string
+	}
+)
+
+func init() {
+	BackupCmd.Flags().StringVarP(&backupCmdOptions.output, "output", "o", "", "File to write the backup to (stdout if not provided)")
+}
+
+func backupRun(_ *cobra.Command, _ []string) error {
+	resp, err := httpclient.Get(clusterCmdOptions.managerAddr, "/cluster/backup")
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	var backup manager.Backup
+	if err := httpinternal.Body(resp, &backup); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if backupCmdOptions.output == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if err := os.WriteFile(backupCmdOptions.output, data, 0600); err != nil {
+		return err
+	}
+
+	tasks := 0
+	for _, w := range backup.Workers {
+		tasks += len(w.Tasks)
+	}
+	fmt.Printf("Backed up %d workers and %d tasks at index %d\n", len(backup.Workers), tasks, backup.LastIndex)
+	return nil
+}
@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ClusterCmd = &cobra.Command{
+		Use:               "cluster",
+		PersistentPreRunE: clusterPreRun,
+	}
+
+	clusterCmdOptions struct {
+		managerAddr string
+	}
+)
+
+func init() {
+	ClusterCmd.PersistentFlags().StringVar(&clusterCmdOptions.managerAddr, "manager", "", "Address and port of the manager node")
+	ClusterCmd.AddCommand(BackupCmd)
+	ClusterCmd.AddCommand(RestoreCmd)
+}
+
+func clusterPreRun(_ *cobra.Command, _ []string) error {
+	if clusterCmdOptions.managerAddr == "" {
+		return errors.New("manager address is not provided")
+	}
+	return nil
+}
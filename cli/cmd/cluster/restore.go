@@ -0,0 +1,55 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/manager"
+	"github.com/spf13/cobra"
+)
+
+var RestoreCmd = &cobra.Command{
+	Use:  "restore",
+	RunE: restoreRun,
+}
+
+func restoreRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no backup file provided")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	var backup manager.Backup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return fmt.Errorf("invalid backup file: %w", err)
+	}
+
+	resp, err := httpclient.Post(clusterCmdOptions.managerAddr, "/cluster/restore", backup)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	var report manager.RestoreReport
+	if err := httpinternal.Body(resp, &report); err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Rescheduled %d tasks, skipped %d, restored %d resources\n",
+		report.Rescheduled, report.Skipped, report.Resources,
+	)
+	return nil
+}
@@ -0,0 +1,42 @@
+package cron
+
+import (
+	"errors"
+	"net/http"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/parse"
+	"github.com/spf13/cobra"
+)
+
+var CreateCmd = &cobra.Command{
+	Use:  "create",
+	RunE: createRun,
+}
+
+func createRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no manifest file provided")
+	}
+
+	manifest, err := parse.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(manifest.Crons) == 0 {
+		return errors.New("no crons in the manifest file")
+	}
+
+	resp, err := httpclient.Post(cronCmdOptions.managerAddr, "/cron/create", manifest.Crons)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package cron
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	CronCmd = &cobra.Command{
+		Use:               "cron",
+		PersistentPreRunE: cronPreRun,
+	}
+
+	cronCmdOptions struct {
+		managerAddr string
+	}
+)
+
+func init() {
+	CronCmd.PersistentFlags().StringVar(&cronCmdOptions.managerAddr, "manager", "", "Address and port of the manager node")
+	CronCmd.AddCommand(CreateCmd)
+	CronCmd.AddCommand(ListCmd)
+	CronCmd.AddCommand(SuspendCmd)
+	CronCmd.AddCommand(ResumeCmd)
+	CronCmd.AddCommand(DeleteCmd)
+}
+
+func cronPreRun(_ *cobra.Command, _ []string) error {
+	if cronCmdOptions.managerAddr == "" {
+		return errors.New("manager address is not provided")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/spf13/cobra"
+)
+
+var DeleteCmd = &cobra.Command{
+	Use:  "delete",
+	RunE: deleteRun,
+}
+
+func deleteRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no name of the cron provided")
+	}
+
+	endpoint := fmt.Sprintf("/cron/%s", args[0])
+	resp, err := httpclient.Delete(cronCmdOptions.managerAddr, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	return nil
+}
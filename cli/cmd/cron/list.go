@@ -0,0 +1,76 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/cron"
+	"github.com/SergeyCherepiuk/fleet/pkg/format"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/spf13/cobra"
+)
+
+var ListCmd = &cobra.Command{
+	Use:  "list",
+	RunE: listRun,
+}
+
+func listRun(_ *cobra.Command, _ []string) error {
+	resp, err := httpclient.Get(cronCmdOptions.managerAddr, "/cron/list")
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	var crons []cron.Cron
+	if err := httpinternal.Body(resp, &crons); err != nil {
+		return err
+	}
+
+	headers := []string{"NAME", "SCHEDULE", "TIMEZONE", "POLICY", "SUSPENDED", "ACTIVE", "LAST SCHEDULE", "LAST SUCCESS", "NEXT SCHEDULE"}
+	accessMap := format.AccessMap[cron.Cron]{
+		"NAME":          func(c cron.Cron) any { return c.Name },
+		"SCHEDULE":      func(c cron.Cron) any { return c.Schedule },
+		"TIMEZONE":      func(c cron.Cron) any { return formatTimezone(c.Timezone) },
+		"POLICY":        func(c cron.Cron) any { return c.ConcurrencyPolicy },
+		"SUSPENDED":     func(c cron.Cron) any { return c.Suspended },
+		"ACTIVE":        func(c cron.Cron) any { return c.Active },
+		"LAST SCHEDULE": func(c cron.Cron) any { return formatTime(c.LastScheduledAt) },
+		"LAST SUCCESS":  func(c cron.Cron) any { return formatTime(c.LastSuccessfulAt) },
+		"NEXT SCHEDULE": func(c cron.Cron) any { return formatNext(c) },
+	}
+	fmt.Print(format.Table[cron.Cron](headers, accessMap, crons))
+	return nil
+}
+
+func formatTimezone(tz string) string {
+	if tz == "" {
+		return "UTC"
+	}
+	return tz
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+func formatNext(c cron.Cron) string {
+	if c.Suspended {
+		return "-"
+	}
+
+	next, err := c.Next(time.Now())
+	if err != nil {
+		return "-"
+	}
+	return formatTime(next)
+}
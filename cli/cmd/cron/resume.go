@@ -0,0 +1,20 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var ResumeCmd = &cobra.Command{
+	Use:  "resume",
+	RunE: resumeRun,
+}
+
+func resumeRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no name of the cron provided")
+	}
+	return post(fmt.Sprintf("/cron/resume/%s", args[0]))
+}
@@ -0,0 +1,36 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/spf13/cobra"
+)
+
+var SuspendCmd = &cobra.Command{
+	Use:  "suspend",
+	RunE: suspendRun,
+}
+
+func suspendRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no name of the cron provided")
+	}
+	return post(fmt.Sprintf("/cron/suspend/%s", args[0]))
+}
+
+func post(endpoint string) error {
+	resp, err := httpclient.Post(cronCmdOptions.managerAddr, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	return nil
+}
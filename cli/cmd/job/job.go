@@ -0,0 +1,30 @@
+package job
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	JobCmd = &cobra.Command{
+		Use:               "job",
+		PersistentPreRunE: jobPreRun,
+	}
+
+	jobCmdOptions struct {
+		managerAddr string
+	}
+)
+
+func init() {
+	JobCmd.PersistentFlags().StringVar(&jobCmdOptions.managerAddr, "manager", "", "Address and port of the manager node")
+	JobCmd.AddCommand(ListCmd)
+}
+
+func jobPreRun(_ *cobra.Command, _ []string) error {
+	if jobCmdOptions.managerAddr == "" {
+		return errors.New("manager address is not provided")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/format"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/job"
+	"github.com/spf13/cobra"
+)
+
+var ListCmd = &cobra.Command{
+	Use:  "list",
+	RunE: listRun,
+}
+
+func listRun(_ *cobra.Command, _ []string) error {
+	resp, err := httpclient.Get(jobCmdOptions.managerAddr, "/job/list")
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	var jobs []job.Job
+	if err := httpinternal.Body(resp, &jobs); err != nil {
+		return err
+	}
+
+	headers := []string{"JOB ID", "IMAGE", "STATUS", "COMPLETIONS", "ACTIVE", "FAILED", "DURATION"}
+	accessMap := format.AccessMap[job.Job]{
+		"JOB ID":      func(j job.Job) any { return j.Id },
+		"IMAGE":       func(j job.Job) any { return trimImageRef(j.Container.Image.Ref) },
+		"STATUS":      func(j job.Job) any { return formatStatus(j) },
+		"COMPLETIONS": func(j job.Job) any { return fmt.Sprintf("%d/%d", j.Succeeded, j.Completions) },
+		"ACTIVE":      func(j job.Job) any { return j.Active },
+		"FAILED":      func(j job.Job) any { return j.Failed },
+		"DURATION":    func(j job.Job) any { return formatDuration(j) },
+	}
+	fmt.Print(format.Table[job.Job](headers, accessMap, jobs))
+	return nil
+}
+
+func formatStatus(j job.Job) string {
+	if j.Reason != "" {
+		return fmt.Sprintf("%s (%s)", j.Status, j.Reason)
+	}
+	return string(j.Status)
+}
+
+func formatDuration(j job.Job) string {
+	end := j.FinishedAt
+	if !j.Done() {
+		end = time.Now()
+	}
+	return end.Sub(j.StartedAt).Round(time.Second).String()
+}
+
+func trimImageRef(ref string) string {
+	index := strings.LastIndexByte(ref, '/')
+	if index == -1 || index == len(ref)-1 {
+		return ref
+	}
+	return ref[index+1:]
+}
@@ -2,20 +2,51 @@ package manager
 
 import (
 	"github.com/SergeyCherepiuk/fleet/cli/cmd/context"
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
 	backend "github.com/SergeyCherepiuk/fleet/pkg/manager"
 	"github.com/SergeyCherepiuk/fleet/pkg/node"
 	"github.com/SergeyCherepiuk/fleet/pkg/scheduler"
 	"github.com/spf13/cobra"
 )
 
-var ManagerCmd = &cobra.Command{
-	Use:  "manager",
-	RunE: managerRun,
+var (
+	ManagerCmd = &cobra.Command{
+		Use:  "manager",
+		RunE: managerRun,
+	}
+
+	managerCmdOptions struct {
+		port    uint16
+		peers   []string
+		dataDir string
+	}
+)
+
+func init() {
+	ManagerCmd.Flags().Uint16Var(&managerCmdOptions.port, "port", 0, "Port of the manager node (random if not provided)")
+	ManagerCmd.Flags().StringSliceVar(&managerCmdOptions.peers, "peers", nil, "Addresses and ports of the other manager nodes")
+	ManagerCmd.Flags().StringVar(&managerCmdOptions.dataDir, "data-dir", "", "Directory to persist the store in (kept in memory if not provided)")
 }
 
 func managerRun(cmd *cobra.Command, _ []string) error {
 	n := cmd.Context().Value(context.NodeKey).(node.Node)
+	if managerCmdOptions.port != 0 {
+		n.Addr.Port = managerCmdOptions.port
+	}
+
+	store, err := newStore()
+	if err != nil {
+		return err
+	}
+
 	scheduler := scheduler.NewEpvm(scheduler.EpvmStrategyBestFit)
-	manager := backend.New(n, scheduler)
+	manager := backend.New(n, scheduler, store, managerCmdOptions.peers)
 	return backend.StartServer(n.Addr.String(), manager)
 }
+
+func newStore() (consensus.Store, error) {
+	if managerCmdOptions.dataDir == "" {
+		return consensus.NewLocalStore(), nil
+	}
+	return consensus.NewDurableStore(managerCmdOptions.dataDir)
+}
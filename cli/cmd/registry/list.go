@@ -0,0 +1,50 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/format"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/manager"
+	"github.com/spf13/cobra"
+)
+
+var ListCmd = &cobra.Command{
+	Use:  "list",
+	RunE: listRun,
+}
+
+func listRun(_ *cobra.Command, _ []string) error {
+	resp, err := httpclient.Get(registryCmdOptions.managerAddr, "/registry/list")
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	var infos []manager.CredentialsInfo
+	if err := httpinternal.Body(resp, &infos); err != nil {
+		return err
+	}
+
+	headers := []string{"NAME", "SERVER", "USERNAME"}
+	accessMap := format.AccessMap[manager.CredentialsInfo]{
+		"NAME":     func(i manager.CredentialsInfo) any { return i.Name },
+		"SERVER":   func(i manager.CredentialsInfo) any { return orDash(i.Server) },
+		"USERNAME": func(i manager.CredentialsInfo) any { return i.Username },
+	}
+	fmt.Print(format.Table[manager.CredentialsInfo](headers, accessMap, infos))
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/spf13/cobra"
+)
+
+var (
+	LoginCmd = &cobra.Command{
+		Use:  "login",
+		RunE: loginRun,
+	}
+
+	loginCmdOptions struct {
+		server        string
+		username      string
+		password      string
+		passwordStdin bool
+	}
+)
+
+func init() {
+	LoginCmd.Flags().StringVar(&loginCmdOptions.server, "server", "", "Address of the registry, the credentials are used for any registry if empty")
+	LoginCmd.Flags().StringVarP(&loginCmdOptions.username, "username", "u", "", "Username")
+	LoginCmd.Flags().StringVarP(&loginCmdOptions.password, "password", "p", "", "Password or token")
+	LoginCmd.Flags().BoolVar(&loginCmdOptions.passwordStdin, "password-stdin", false, "Read the password or token from stdin")
+}
+
+func loginRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no name of the credentials provided")
+	}
+
+	password := loginCmdOptions.password
+	if loginCmdOptions.passwordStdin {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+	}
+
+	if loginCmdOptions.username == "" || password == "" {
+		return errors.New("username and password must be provided")
+	}
+
+	creds := image.Credentials{
+		Server:   loginCmdOptions.server,
+		Username: loginCmdOptions.username,
+		Password: password,
+	}
+
+	endpoint := fmt.Sprintf("/registry/%s", args[0])
+	resp, err := httpclient.Put(registryCmdOptions.managerAddr, endpoint, creds)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/spf13/cobra"
+)
+
+var LogoutCmd = &cobra.Command{
+	Use:  "logout",
+	RunE: logoutRun,
+}
+
+func logoutRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no name of the credentials provided")
+	}
+
+	endpoint := fmt.Sprintf("/registry/%s", args[0])
+	resp, err := httpclient.Delete(registryCmdOptions.managerAddr, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	return nil
+}
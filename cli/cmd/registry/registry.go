@@ -0,0 +1,32 @@
+package registry
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	RegistryCmd = &cobra.Command{
+		Use:               "registry",
+		PersistentPreRunE: registryPreRun,
+	}
+
+	registryCmdOptions struct {
+		managerAddr string
+	}
+)
+
+func init() {
+	RegistryCmd.PersistentFlags().StringVar(&registryCmdOptions.managerAddr, "manager", "", "Address and port of the manager node")
+	RegistryCmd.AddCommand(LoginCmd)
+	RegistryCmd.AddCommand(LogoutCmd)
+	RegistryCmd.AddCommand(ListCmd)
+}
+
+func registryPreRun(_ *cobra.Command, _ []string) error {
+	if registryCmdOptions.managerAddr == "" {
+		return errors.New("manager address is not provided")
+	}
+	return nil
+}
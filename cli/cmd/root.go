@@ -3,9 +3,16 @@ package cmd
 import (
 	"context"
 
+	"github.com/SergeyCherepiuk/fleet/cli/cmd/cluster"
 	cmdcontext "github.com/SergeyCherepiuk/fleet/cli/cmd/context"
+	"github.com/SergeyCherepiuk/fleet/cli/cmd/cron"
+	"github.com/SergeyCherepiuk/fleet/cli/cmd/job"
 	"github.com/SergeyCherepiuk/fleet/cli/cmd/manager"
+	"github.com/SergeyCherepiuk/fleet/cli/cmd/registry"
+	"github.com/SergeyCherepiuk/fleet/cli/cmd/service"
+	"github.com/SergeyCherepiuk/fleet/cli/cmd/store"
 	"github.com/SergeyCherepiuk/fleet/cli/cmd/task"
+	"github.com/SergeyCherepiuk/fleet/cli/cmd/top"
 	"github.com/SergeyCherepiuk/fleet/cli/cmd/worker"
 	"github.com/SergeyCherepiuk/fleet/pkg/node"
 	"github.com/spf13/cobra"
@@ -22,6 +29,13 @@ func init() {
 	RootCmd.AddCommand(manager.ManagerCmd)
 	RootCmd.AddCommand(worker.WorkerCmd)
 	RootCmd.AddCommand(task.TaskCmd)
+	RootCmd.AddCommand(job.JobCmd)
+	RootCmd.AddCommand(cron.CronCmd)
+	RootCmd.AddCommand(service.ServiceCmd)
+	RootCmd.AddCommand(store.StoreCmd)
+	RootCmd.AddCommand(cluster.ClusterCmd)
+	RootCmd.AddCommand(registry.RegistryCmd)
+	RootCmd.AddCommand(top.TopCmd)
 }
 
 func rootPreRun(cmd *cobra.Command, _ []string) error {
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/format"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/service"
+	"github.com/spf13/cobra"
+)
+
+var ListCmd = &cobra.Command{
+	Use:  "list",
+	RunE: listRun,
+}
+
+func listRun(_ *cobra.Command, _ []string) error {
+	resp, err := httpclient.Get(serviceCmdOptions.managerAddr, "/service/list")
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	var services []service.Service
+	if err := httpinternal.Body(resp, &services); err != nil {
+		return err
+	}
+
+	headers := []string{"NAME", "IMAGE", "READY", "RUNNING", "REVISION", "ROLLOUT", "FAILURES", "AGE"}
+	accessMap := format.AccessMap[service.Service]{
+		"NAME":     func(s service.Service) any { return s.Name },
+		"IMAGE":    func(s service.Service) any { return trimImageRef(s.Container.Image.Ref) },
+		"READY":    func(s service.Service) any { return fmt.Sprintf("%d/%d", s.Ready, s.Replicas) },
+		"RUNNING":  func(s service.Service) any { return s.Running },
+		"REVISION": func(s service.Service) any { return s.Revision },
+		"ROLLOUT":  func(s service.Service) any { return s.Rollout.Status },
+		"FAILURES": func(s service.Service) any { return s.Failures },
+		"AGE":      func(s service.Service) any { return time.Since(s.CreatedAt).Round(time.Second) },
+	}
+	fmt.Print(format.Table[service.Service](headers, accessMap, services))
+	return nil
+}
+
+func trimImageRef(ref string) string {
+	index := strings.LastIndexByte(ref, '/')
+	if index == -1 || index == len(ref)-1 {
+		return ref
+	}
+	return ref[index+1:]
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/spf13/cobra"
+)
+
+var RolloutCmd = &cobra.Command{
+	Use: "rollout",
+}
+
+func init() {
+	RolloutCmd.AddCommand(RolloutStatusCmd)
+	RolloutCmd.AddCommand(RolloutPauseCmd)
+	RolloutCmd.AddCommand(RolloutResumeCmd)
+	RolloutCmd.AddCommand(RolloutUndoCmd)
+}
+
+func postRollout(endpoint string) error {
+	resp, err := httpclient.Post(serviceCmdOptions.managerAddr, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	return nil
+}
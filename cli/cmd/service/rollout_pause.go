@@ -0,0 +1,20 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var RolloutPauseCmd = &cobra.Command{
+	Use:  "pause",
+	RunE: rolloutPauseRun,
+}
+
+func rolloutPauseRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no name of the service provided")
+	}
+	return postRollout(fmt.Sprintf("/service/rollout/pause/%s", args[0]))
+}
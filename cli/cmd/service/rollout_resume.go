@@ -0,0 +1,20 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var RolloutResumeCmd = &cobra.Command{
+	Use:  "resume",
+	RunE: rolloutResumeRun,
+}
+
+func rolloutResumeRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no name of the service provided")
+	}
+	return postRollout(fmt.Sprintf("/service/rollout/resume/%s", args[0]))
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/format"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/service"
+	"github.com/spf13/cobra"
+)
+
+const StatusWatchInterval = time.Second
+
+var (
+	RolloutStatusCmd = &cobra.Command{
+		Use:  "status",
+		RunE: rolloutStatusRun,
+	}
+
+	rolloutStatusCmdOptions struct {
+		watch bool
+	}
+)
+
+func init() {
+	RolloutStatusCmd.Flags().BoolVarP(&rolloutStatusCmdOptions.watch, "watch", "w", false, "Wait until the rollout is complete or paused")
+}
+
+func rolloutStatusRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no name of the service provided")
+	}
+
+	s, err := getService(args[0])
+	if err != nil {
+		return err
+	}
+
+	if !rolloutStatusCmdOptions.watch {
+		fmt.Println(formatRollout(s))
+		fmt.Println()
+		printRevisions(s)
+		return nil
+	}
+
+	var last string
+	for {
+		if status := formatRollout(s); status != last {
+			fmt.Println(status)
+			last = status
+		}
+
+		if s.Rollout.Status != service.Progressing {
+			return nil
+		}
+
+		time.Sleep(StatusWatchInterval)
+		if s, err = getService(args[0]); err != nil {
+			return err
+		}
+	}
+}
+
+func getService(name string) (service.Service, error) {
+	var s service.Service
+	resp, err := httpclient.Get(serviceCmdOptions.managerAddr, "/service/"+name)
+	if err != nil {
+		return s, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return s, errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	err = httpinternal.Body(resp, &s)
+	return s, err
+}
+
+func formatRollout(s service.Service) string {
+	r := s.Rollout
+	status := fmt.Sprintf(
+		"revision %d is %s: %d of %d updated replicas are available",
+		s.Revision, r.Status, r.UpdatedAvailable, s.Replicas,
+	)
+	if r.Old > 0 {
+		status += fmt.Sprintf(", %d old replicas are left", r.Old)
+	}
+	if r.Reason != "" {
+		status += fmt.Sprintf(" (%s)", r.Reason)
+	}
+	return status
+}
+
+func printRevisions(s service.Service) {
+	headers := []string{"REVISION", "IMAGE", "CREATED", "CURRENT"}
+	accessMap := format.AccessMap[service.Revision]{
+		"REVISION": func(r service.Revision) any { return r.Number },
+		"IMAGE":    func(r service.Revision) any { return trimImageRef(r.Container.Image.Ref) },
+		"CREATED":  func(r service.Revision) any { return r.CreatedAt.Local().Format(time.DateTime) },
+		"CURRENT":  func(r service.Revision) any { return r.Number == s.Revision },
+	}
+	fmt.Print(format.Table[service.Revision](headers, accessMap, s.Revisions))
+}
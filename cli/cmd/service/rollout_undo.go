@@ -0,0 +1,32 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	RolloutUndoCmd = &cobra.Command{
+		Use:  "undo",
+		RunE: rolloutUndoRun,
+	}
+
+	rolloutUndoCmdOptions struct {
+		revision int
+	}
+)
+
+func init() {
+	RolloutUndoCmd.Flags().IntVar(&rolloutUndoCmdOptions.revision, "to-revision", 0, "Revision to roll back to, the previous one if not provided")
+}
+
+func rolloutUndoRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no name of the service provided")
+	}
+
+	endpoint := fmt.Sprintf("/service/rollout/undo/%s?revision=%d", args[0], rolloutUndoCmdOptions.revision)
+	return postRollout(endpoint)
+}
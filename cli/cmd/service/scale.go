@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/spf13/cobra"
+)
+
+var ScaleCmd = &cobra.Command{
+	Use:  "scale",
+	RunE: scaleRun,
+}
+
+func scaleRun(_ *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return errors.New("name of the service and the number of replicas must be provided")
+	}
+
+	replicas, err := strconv.Atoi(args[1])
+	if err != nil || replicas < 0 {
+		return fmt.Errorf("invalid number of replicas: %q", args[1])
+	}
+
+	endpoint := fmt.Sprintf("/service/scale/%s", args[0])
+	resp, err := httpclient.Post(serviceCmdOptions.managerAddr, endpoint, replicas)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	return nil
+}
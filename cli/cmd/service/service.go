@@ -0,0 +1,34 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ServiceCmd = &cobra.Command{
+		Use:               "service",
+		PersistentPreRunE: servicePreRun,
+	}
+
+	serviceCmdOptions struct {
+		managerAddr string
+	}
+)
+
+func init() {
+	ServiceCmd.PersistentFlags().StringVar(&serviceCmdOptions.managerAddr, "manager", "", "Address and port of the manager node")
+	ServiceCmd.AddCommand(ApplyCmd)
+	ServiceCmd.AddCommand(ListCmd)
+	ServiceCmd.AddCommand(ScaleCmd)
+	ServiceCmd.AddCommand(DeleteCmd)
+	ServiceCmd.AddCommand(RolloutCmd)
+}
+
+func servicePreRun(_ *cobra.Command, _ []string) error {
+	if serviceCmdOptions.managerAddr == "" {
+		return errors.New("manager address is not provided")
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+)
+
+var (
+	DiffCmd = &cobra.Command{
+		Use:  "diff",
+		RunE: diffRun,
+	}
+
+	diffCmdOptions struct {
+		workerId string
+	}
+)
+
+func init() {
+	DiffCmd.Flags().StringVarP(&diffCmdOptions.workerId, "worker", "w", "", "Worker ID to compare its replica against the manager's store")
+}
+
+func diffRun(_ *cobra.Command, _ []string) error {
+	if diffCmdOptions.workerId == "" {
+		return errors.New("worker id is not provided")
+	}
+
+	managerDump, err := getDump("")
+	if err != nil {
+		return err
+	}
+
+	workerDump, err := getDump(diffCmdOptions.workerId)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Last index: manager %v (term %v), worker %v (term %v)\n",
+		field(managerDump, "LastIndex"), field(managerDump, "LastTerm"),
+		field(workerDump, "LastIndex"), field(workerDump, "LastTerm"),
+	)
+
+	if err := diffLogs(); err != nil {
+		return err
+	}
+
+	expected, actual := make(map[string]string), make(map[string]string)
+	for _, name := range []string{"Workers", "Resources"} {
+		flatten(name, field(managerDump, name), expected)
+		flatten(name, field(workerDump, name), actual)
+	}
+
+	paths := append(maps.Keys(expected), maps.Keys(actual)...)
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+
+	differs := false
+	for _, path := range paths {
+		e, inExpected := expected[path]
+		a, inActual := actual[path]
+		if inExpected && inActual && e == a {
+			continue
+		}
+
+		differs = true
+		if inExpected {
+			fmt.Printf("- %s: %s\n", path, e)
+		}
+		if inActual {
+			fmt.Printf("+ %s: %s\n", path, a)
+		}
+	}
+
+	if !differs {
+		fmt.Println("State matches")
+	}
+	return nil
+}
+
+// diffLogs reports the first command both logs hold but disagree on, since
+// every command after it is applied to a different state
+func diffLogs() error {
+	managerLog, err := getLog("", 1)
+	if err != nil {
+		return err
+	}
+
+	workerLog, err := getLog(diffCmdOptions.workerId, 1)
+	if err != nil {
+		return err
+	}
+
+	workerEntries := make(map[string]string, len(workerLog))
+	for _, entry := range workerLog {
+		workerEntries[fmt.Sprint(field(entry, "Index"))] = marshal(entry)
+	}
+
+	for _, entry := range managerLog {
+		index := fmt.Sprint(field(entry, "Index"))
+		if w, ok := workerEntries[index]; ok && w != marshal(entry) {
+			fmt.Printf("Log diverges at index %s\n", index)
+			return nil
+		}
+	}
+
+	fmt.Println("Log matches on the common indexes")
+	return nil
+}
+
+func flatten(path string, v any, into map[string]string) {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			into[path] = "{}"
+		}
+		for key, value := range v {
+			flatten(path+"."+key, value, into)
+		}
+	case []any:
+		if len(v) == 0 {
+			into[path] = "[]"
+		}
+		for i, value := range v {
+			flatten(fmt.Sprintf("%s[%d]", path, i), value, into)
+		}
+	default:
+		into[path] = strings.TrimSpace(marshal(v))
+	}
+}
+
+func marshal(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
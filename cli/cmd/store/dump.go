@@ -0,0 +1,40 @@
+package store
+
+import (
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	DumpCmd = &cobra.Command{
+		Use:  "dump",
+		RunE: dumpRun,
+	}
+
+	dumpCmdOptions struct {
+		workerId string
+		output   string
+	}
+)
+
+func init() {
+	DumpCmd.Flags().StringVarP(&dumpCmdOptions.workerId, "worker", "w", "", "Worker ID to dump its replica of the state")
+	DumpCmd.Flags().StringVarP(&dumpCmdOptions.output, "output", "o", "json", "Output format (json or yaml)")
+}
+
+func dumpRun(_ *cobra.Command, _ []string) error {
+	dump, err := getDump(dumpCmdOptions.workerId)
+	if err != nil {
+		return err
+	}
+	return print(dump, dumpCmdOptions.output)
+}
+
+func getDump(workerId string) (any, error) {
+	endpoint := "/store/dump"
+	if workerId != "" {
+		endpoint += "?worker=" + url.QueryEscape(workerId)
+	}
+	return get(endpoint)
+}
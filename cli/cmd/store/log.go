@@ -0,0 +1,77 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/format"
+	"github.com/spf13/cobra"
+)
+
+var (
+	LogCmd = &cobra.Command{
+		Use:  "log",
+		RunE: logRun,
+	}
+
+	logCmdOptions struct {
+		from     int
+		workerId string
+		output   string
+	}
+)
+
+func init() {
+	LogCmd.Flags().IntVar(&logCmdOptions.from, "from", 1, "Index of the first command to print")
+	LogCmd.Flags().StringVarP(&logCmdOptions.workerId, "worker", "w", "", "Worker ID to print the log of its replica")
+	LogCmd.Flags().StringVarP(&logCmdOptions.output, "output", "o", "table", "Output format (table, json or yaml)")
+}
+
+func logRun(_ *cobra.Command, _ []string) error {
+	entries, err := getLog(logCmdOptions.workerId, logCmdOptions.from)
+	if err != nil {
+		return err
+	}
+
+	if logCmdOptions.output != "table" {
+		return print(entries, logCmdOptions.output)
+	}
+
+	headers := []string{"INDEX", "TERM", "TYPE", "VERSION", "DATA"}
+	accessMap := format.AccessMap[any]{
+		"INDEX":   func(e any) any { return field(e, "Index") },
+		"TERM":    func(e any) any { return field(e, "Term") },
+		"TYPE":    func(e any) any { return field(e, "Type") },
+		"VERSION": func(e any) any { return field(e, "Version") },
+		"DATA": func(e any) any {
+			data, _ := json.Marshal(field(e, "Data"))
+			return string(data)
+		},
+	}
+	fmt.Print(format.Table[any](headers, accessMap, entries))
+	return nil
+}
+
+func getLog(workerId string, from int) ([]any, error) {
+	query := url.Values{}
+	query.Set("from", fmt.Sprint(from))
+	if workerId != "" {
+		query.Set("worker", workerId)
+	}
+
+	v, err := get("/store/log?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	entries, _ := v.([]any)
+	return entries, nil
+}
+
+func field(v any, name string) any {
+	if m, ok := v.(map[string]any); ok {
+		return m[name]
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	StoreCmd = &cobra.Command{
+		Use:               "store",
+		PersistentPreRunE: storePreRun,
+	}
+
+	storeCmdOptions struct {
+		managerAddr string
+	}
+)
+
+func init() {
+	StoreCmd.PersistentFlags().StringVar(&storeCmdOptions.managerAddr, "manager", "", "Address and port of the manager node")
+	StoreCmd.AddCommand(LogCmd)
+	StoreCmd.AddCommand(DumpCmd)
+	StoreCmd.AddCommand(DiffCmd)
+}
+
+func storePreRun(_ *cobra.Command, _ []string) error {
+	if storeCmdOptions.managerAddr == "" {
+		return errors.New("manager address is not provided")
+	}
+	return nil
+}
+
+// get decodes the response into a generic value, so it keeps every field
+// sent by the manager no matter which version of it is running
+func get(endpoint string) (any, error) {
+	resp, err := httpclient.Get(storeCmdOptions.managerAddr, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	var v any
+	err = httpinternal.Body(resp, &v)
+	return v, err
+}
+
+func print(v any, output string) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch output {
+	case "json":
+		data, err = json.MarshalIndent(v, "", "  ")
+		data = append(data, '\n')
+	case "yaml":
+		data, err = yaml.Marshal(v)
+	default:
+		return fmt.Errorf("unknown output format %q", output)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(data))
+	return nil
+}
@@ -0,0 +1,105 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/attach"
+	"github.com/google/uuid"
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ExecCmd = &cobra.Command{
+		Use:  "exec <task-id> -- <command>...",
+		RunE: execRun,
+	}
+
+	execCmdOptions struct {
+		interactive bool
+		tty         bool
+		env         []string
+	}
+)
+
+func init() {
+	ExecCmd.Flags().BoolVarP(&execCmdOptions.interactive, "interactive", "i", false, "Keep stdin attached to the command")
+	ExecCmd.Flags().BoolVarP(&execCmdOptions.tty, "tty", "t", false, "Allocate a terminal for the command")
+	ExecCmd.Flags().StringArrayVarP(&execCmdOptions.env, "env", "e", nil, "Additional environment variables (KEY=VALUE)")
+}
+
+func execRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no task id provided")
+	}
+
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 2 {
+		return errors.New("no command provided")
+	}
+
+	code, err := execCommand(id, args[1:])
+	if err != nil {
+		return err
+	}
+
+	// NOTE(SergeyCherepiuk): The terminal is restored by execCommand since
+	// os.Exit skips the deferred calls
+	if code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+func execCommand(id uuid.UUID, cmd []string) (int, error) {
+	query := url.Values{"cmd": cmd, "env": execCmdOptions.env}
+	query.Set("stdin", fmt.Sprint(execCmdOptions.interactive))
+	query.Set("tty", fmt.Sprint(execCmdOptions.tty))
+
+	stdinFd, isTerminal := term.GetFdInfo(os.Stdin)
+	if execCmdOptions.tty && isTerminal {
+		if size, err := term.GetWinsize(stdinFd); err == nil {
+			query.Set("width", fmt.Sprint(size.Width))
+			query.Set("height", fmt.Sprint(size.Height))
+		}
+	}
+
+	endpoint := fmt.Sprintf("/task/exec/%s?%s", id, query.Encode())
+	conn, resp, err := attach.Dial(taskCmdOptions.managerAddr, endpoint)
+	if errors.Is(err, attach.ErrNotUpgraded) {
+		defer resp.Body.Close()
+		return 0, errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if execCmdOptions.tty && isTerminal {
+		state, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return 0, err
+		}
+		defer term.RestoreTerminal(stdinFd, state)
+	}
+
+	if execCmdOptions.interactive {
+		go func() {
+			io.Copy(conn, os.Stdin)
+			conn.CloseWrite()
+		}()
+	} else {
+		conn.CloseWrite()
+	}
+
+	return attach.ReadOutput(conn, os.Stdout)
+}
@@ -1,7 +1,10 @@
 package task
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -22,14 +25,55 @@ var (
 
 	listCmdOptions struct {
 		workerId string
+		watch    bool
 	}
 )
 
 func init() {
 	ListCmd.Flags().StringVarP(&listCmdOptions.workerId, "worker", "w", "", "Worker ID to list the tasks of a specific worker")
+	ListCmd.Flags().BoolVar(&listCmdOptions.watch, "watch", false, "Print the tasks again whenever they change")
 }
 
 func listRun(_ *cobra.Command, _ []string) error {
+	if err := printTasks(); err != nil {
+		return err
+	}
+
+	if !listCmdOptions.watch {
+		return nil
+	}
+
+	endpoint := "/store/watch"
+	if listCmdOptions.workerId != "" {
+		endpoint += "?worker=" + url.QueryEscape(listCmdOptions.workerId)
+	}
+
+	resp, err := httpclient.Get(taskCmdOptions.managerAddr, endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		fmt.Println()
+		if err := printTasks(); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("watch is closed by the manager")
+}
+
+func printTasks() error {
 	endpoint, _ := url.JoinPath("/task/list", listCmdOptions.workerId)
 	resp, err := httpclient.Get(taskCmdOptions.managerAddr, endpoint)
 	if err != nil {
@@ -45,7 +89,7 @@ func listRun(_ *cobra.Command, _ []string) error {
 	accessMap := format.AccessMap[task.Task]{
 		"TASK ID":     func(t task.Task) any { return formatId(t.Id) },
 		"IMAGE":       func(t task.Task) any { return trimImageRef(t.Container.Image.Ref) },
-		"STATE":       func(t task.Task) any { return t.State },
+		"STATE":       func(t task.Task) any { return formatState(t) },
 		"RESTARTS":    func(t task.Task) any { return max(0, len(t.StartedAt)-1) },
 		"START TIME":  func(t task.Task) any { return formatLastTime(t.StartedAt) },
 		"FINISH TIME": func(t task.Task) any { return formatLastTime(t.FinishedAt) },
@@ -54,6 +98,16 @@ func listRun(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
+func formatState(t task.Task) string {
+	if t.State == task.Running && !t.Ready {
+		return fmt.Sprintf("%s (not ready)", t.State)
+	}
+	if t.State == task.FailedOnStartup && t.Reason != "" {
+		return fmt.Sprintf("%s (%s)", t.State, t.Reason)
+	}
+	return string(t.State)
+}
+
 func formatId(id uuid.UUID) string {
 	if id == uuid.Nil {
 		return "-"
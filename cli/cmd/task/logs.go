@@ -0,0 +1,67 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	LogsCmd = &cobra.Command{
+		Use:  "logs",
+		RunE: logsRun,
+	}
+
+	logsCmdOptions struct {
+		follow bool
+		tail   int
+		since  time.Duration
+	}
+)
+
+func init() {
+	LogsCmd.Flags().BoolVarP(&logsCmdOptions.follow, "follow", "f", false, "Keep printing the logs as they are written")
+	LogsCmd.Flags().IntVar(&logsCmdOptions.tail, "tail", 0, "Number of lines to print from the end of the logs (all if not provided)")
+	LogsCmd.Flags().DurationVar(&logsCmdOptions.since, "since", 0, "Print only the logs written within the duration (e.g. 10m)")
+}
+
+func logsRun(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no task id provided")
+	}
+
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("follow", fmt.Sprint(logsCmdOptions.follow))
+	query.Set("tail", fmt.Sprint(logsCmdOptions.tail))
+	if logsCmdOptions.since > 0 {
+		query.Set("since", time.Now().Add(-logsCmdOptions.since).Format(time.RFC3339))
+	}
+
+	endpoint := fmt.Sprintf("/task/logs/%s?%s", id, query.Encode())
+	resp, err := httpclient.Get(taskCmdOptions.managerAddr, endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	_, err = io.Copy(os.Stdout, resp.Body)
+	return err
+}
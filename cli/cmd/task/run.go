@@ -20,12 +20,43 @@ func runRun(_ *cobra.Command, args []string) error {
 		return errors.New("no manifest file provided")
 	}
 
-	tasks, err := parse.Parse(args[0])
+	manifest, err := parse.Parse(args[0])
 	if err != nil {
 		return err
 	}
 
-	resp, err := httpclient.Post(taskCmdOptions.managerAddr, "/task/run", tasks)
+	if len(manifest.Tasks) > 0 {
+		if err := post("/task/run", manifest.Tasks); err != nil {
+			return err
+		}
+	}
+
+	if len(manifest.Jobs) > 0 {
+		if err := post("/job/run", manifest.Jobs); err != nil {
+			return err
+		}
+	}
+
+	if len(manifest.Crons) > 0 {
+		if err := post("/cron/create", manifest.Crons); err != nil {
+			return err
+		}
+	}
+
+	if len(manifest.Services) > 0 {
+		if err := post("/service/apply", manifest.Services); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func post(endpoint string, body any) error {
+	resp, err := httpclient.Post(taskCmdOptions.managerAddr, endpoint, body)
+	if err != nil {
+		return err
+	}
 
 	if resp.StatusCode != http.StatusCreated {
 		message := httpinternal.ErrorMessage(resp.Body)
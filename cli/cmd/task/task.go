@@ -22,6 +22,8 @@ func init() {
 	TaskCmd.AddCommand(RunCmd)
 	TaskCmd.AddCommand(StopCmd)
 	TaskCmd.AddCommand(ListCmd)
+	TaskCmd.AddCommand(LogsCmd)
+	TaskCmd.AddCommand(ExecCmd)
 }
 
 func taskPreRun(_ *cobra.Command, _ []string) error {
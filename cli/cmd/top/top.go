@@ -0,0 +1,106 @@
+package top
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/format"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/worker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	TopCmd = &cobra.Command{
+		Use:  "top",
+		RunE: topRun,
+	}
+
+	topCmdOptions struct {
+		managerAddr string
+		workerId    string
+		sort        string
+	}
+)
+
+func init() {
+	TopCmd.Flags().StringVar(&topCmdOptions.managerAddr, "manager", "", "Address and port of the manager node")
+	TopCmd.Flags().StringVarP(&topCmdOptions.workerId, "worker", "w", "", "Worker ID to show the tasks of a specific worker")
+	TopCmd.Flags().StringVar(&topCmdOptions.sort, "sort", "cpu", "Usage to sort the tasks by (cpu, memory, net or io)")
+}
+
+var usages = map[string]func(worker.TaskStats) float64{
+	"cpu":    func(s worker.TaskStats) float64 { return s.Stats.CPU },
+	"memory": func(s worker.TaskStats) float64 { return float64(s.Stats.MemoryUsage) },
+	"net":    func(s worker.TaskStats) float64 { return float64(s.Stats.NetRx + s.Stats.NetTx) },
+	"io":     func(s worker.TaskStats) float64 { return float64(s.Stats.BlockRead + s.Stats.BlockWrite) },
+}
+
+func topRun(_ *cobra.Command, _ []string) error {
+	if topCmdOptions.managerAddr == "" {
+		return errors.New("manager address is not provided")
+	}
+
+	usage, ok := usages[topCmdOptions.sort]
+	if !ok {
+		return fmt.Errorf("unknown sort order %q", topCmdOptions.sort)
+	}
+
+	endpoint := "/task/stats"
+	if topCmdOptions.workerId != "" {
+		endpoint += "?worker=" + url.QueryEscape(topCmdOptions.workerId)
+	}
+
+	resp, err := httpclient.Get(topCmdOptions.managerAddr, endpoint)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	var stats []worker.TaskStats
+	if err := httpinternal.Body(resp, &stats); err != nil {
+		return err
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return usage(stats[i]) > usage(stats[j])
+	})
+
+	headers := []string{"TASK ID", "IMAGE", "CPU %", "MEM USAGE / LIMIT", "MEM %", "NET I/O", "BLOCK I/O"}
+	accessMap := format.AccessMap[worker.TaskStats]{
+		"TASK ID":           func(s worker.TaskStats) any { return s.TaskId },
+		"IMAGE":             func(s worker.TaskStats) any { return s.Image },
+		"CPU %":             func(s worker.TaskStats) any { return fmt.Sprintf("%.2f%%", s.Stats.CPU*100) },
+		"MEM USAGE / LIMIT": func(s worker.TaskStats) any { return formatMemory(s) },
+		"MEM %":             func(s worker.TaskStats) any { return formatMemoryPercent(s) },
+		"NET I/O":           func(s worker.TaskStats) any { return formatIO(s.Stats.NetRx, s.Stats.NetTx) },
+		"BLOCK I/O":         func(s worker.TaskStats) any { return formatIO(s.Stats.BlockRead, s.Stats.BlockWrite) },
+	}
+	fmt.Print(format.Table[worker.TaskStats](headers, accessMap, stats))
+	return nil
+}
+
+func formatMemory(s worker.TaskStats) string {
+	if s.Stats.MemoryLimit == 0 {
+		return format.Bytes(s.Stats.MemoryUsage) + " / -"
+	}
+	return format.Bytes(s.Stats.MemoryUsage) + " / " + format.Bytes(s.Stats.MemoryLimit)
+}
+
+func formatMemoryPercent(s worker.TaskStats) string {
+	if s.Stats.MemoryLimit == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", float64(s.Stats.MemoryUsage)/float64(s.Stats.MemoryLimit)*100)
+}
+
+func formatIO(in, out uint64) string {
+	return format.Bytes(in) + " / " + format.Bytes(out)
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/format"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/worker"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	GCCmd = &cobra.Command{
+		Use:  "gc",
+		RunE: gcRun,
+	}
+
+	gcCmdOptions struct {
+		all bool
+	}
+)
+
+func init() {
+	GCCmd.Flags().BoolVar(&gcCmdOptions.all, "all", false, "Remove every image none of the tasks need, regardless of the disk usage")
+}
+
+func gcRun(_ *cobra.Command, args []string) error {
+	if len(workerCmdOptions.managerAddrs) == 0 {
+		return errors.New("manager address is not provided")
+	}
+
+	if len(args) == 0 {
+		return errors.New("no worker id provided")
+	}
+
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("/worker/%s/gc?all=%t", id, gcCmdOptions.all)
+	resp, err := httpclient.Post(workerCmdOptions.managerAddrs[0], endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	var report worker.GCReport
+	if err := httpinternal.Body(resp, &report); err != nil {
+		return err
+	}
+
+	for _, name := range report.Removed {
+		fmt.Printf("Removed %s\n", name)
+	}
+	fmt.Printf("Reclaimed %s\n", format.Bytes(report.Reclaimed))
+	return nil
+}
@@ -1,6 +1,7 @@
 package worker
 
 import (
+	"errors"
 	"fmt"
 
 	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
@@ -16,7 +17,11 @@ var ListCmd = &cobra.Command{
 }
 
 func listRun(_ *cobra.Command, _ []string) error {
-	resp, err := httpclient.Get(workerCmdOptions.managerAddr, "/worker/list")
+	if len(workerCmdOptions.managerAddrs) == 0 {
+		return errors.New("manager address is not provided")
+	}
+
+	resp, err := httpclient.Get(workerCmdOptions.managerAddrs[0], "/worker/list")
 	if err != nil {
 		return err
 	}
@@ -26,13 +31,14 @@ func listRun(_ *cobra.Command, _ []string) error {
 		return err
 	}
 
-	headers := []string{"WORKER ID", "IP ADDRESS", "MANAGER IP", "TASKS COUNT", "RUNTIME"}
+	headers := []string{"WORKER ID", "IP ADDRESS", "MANAGER IP", "TASKS COUNT", "RUNTIME", "RECLAIMED"}
 	accessMap := format.AccessMap[worker.Info]{
 		"WORKER ID":   func(i worker.Info) any { return i.Id },
 		"IP ADDRESS":  func(i worker.Info) any { return i.Addr },
 		"MANAGER IP":  func(i worker.Info) any { return i.ManagerAddr },
 		"TASKS COUNT": func(i worker.Info) any { return i.TasksCount },
 		"RUNTIME":     func(i worker.Info) any { return i.RuntimeName },
+		"RECLAIMED":   func(i worker.Info) any { return format.Bytes(i.ReclaimedBytes) },
 	}
 	fmt.Print(format.Table[worker.Info](headers, accessMap, workers))
 	return nil
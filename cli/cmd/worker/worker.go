@@ -2,11 +2,14 @@ package worker
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/SergeyCherepiuk/fleet/cli/cmd/context"
 	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/containerd"
 	"github.com/SergeyCherepiuk/fleet/pkg/docker"
 	"github.com/SergeyCherepiuk/fleet/pkg/node"
+	"github.com/SergeyCherepiuk/fleet/pkg/process"
 	backend "github.com/SergeyCherepiuk/fleet/pkg/worker"
 	"github.com/spf13/cobra"
 )
@@ -19,29 +22,51 @@ var (
 	}
 
 	workerCmdOptions struct {
-		managerAddr string
+		managerAddrs      []string
+		runtime           string
+		containerdAddress string
+		execDir           string
 	}
 
 	workerRuntime c14n.Runtime
 )
 
 func init() {
-	WorkerCmd.PersistentFlags().StringVar(&workerCmdOptions.managerAddr, "manager", "", "Address and port of the manager node")
+	WorkerCmd.PersistentFlags().StringSliceVar(&workerCmdOptions.managerAddrs, "manager", nil, "Addresses and ports of the manager nodes")
+	WorkerCmd.Flags().StringVar(&workerCmdOptions.runtime, "runtime", "docker", "Container runtime to run the tasks with (docker, containerd or exec)")
+	WorkerCmd.Flags().StringVar(&workerCmdOptions.containerdAddress, "containerd-address", containerd.DefaultAddress, "Address of the containerd socket")
+	WorkerCmd.Flags().StringVar(&workerCmdOptions.execDir, "exec-dir", process.DefaultDir, "Directory to keep the files of the processes run by the exec runtime")
 	WorkerCmd.AddCommand(ListCmd)
+	WorkerCmd.AddCommand(GCCmd)
 }
 
 func workerPreRun(_ *cobra.Command, _ []string) error {
-	if workerCmdOptions.managerAddr == "" {
+	if len(workerCmdOptions.managerAddrs) == 0 {
 		return errors.New("manager address is not provided")
 	}
 
 	var err error
-	workerRuntime, err = docker.New()
+	workerRuntime, err = newRuntime()
 	return err
 }
 
+func newRuntime() (c14n.Runtime, error) {
+	switch workerCmdOptions.runtime {
+	case "docker":
+		return docker.New()
+	case "containerd":
+		return containerd.New(workerCmdOptions.containerdAddress)
+	case "exec":
+		return process.New(workerCmdOptions.execDir)
+	default:
+		return nil, fmt.Errorf("unknown runtime %q", workerCmdOptions.runtime)
+	}
+}
+
 func workerRun(cmd *cobra.Command, _ []string) error {
 	n := cmd.Context().Value(context.NodeKey).(node.Node)
-	worker := backend.New(n, workerRuntime, workerCmdOptions.managerAddr)
+	worker := backend.New(n, workerRuntime, workerCmdOptions.managerAddrs)
+	worker.GuardProcess()
+	worker.Start()
 	return backend.StartServer(n.Addr.String(), worker)
 }
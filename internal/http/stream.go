@@ -0,0 +1,33 @@
+package http
+
+import (
+	"io"
+	"net/http"
+)
+
+type FlushWriter interface {
+	io.Writer
+	http.Flusher
+}
+
+// Stream copies the reader flushing after every read, so a client sees the
+// data as soon as it is produced (e.g. logs that are being followed)
+func Stream(w FlushWriter, r io.Reader) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			w.Flush()
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
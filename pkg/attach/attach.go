@@ -0,0 +1,248 @@
+// Package attach carries an interactive session (e.g. exec into a container)
+// over an upgraded HTTP connection. The client writes its input as is and
+// closes the writing side once the input ends. The server sends framed
+// output followed by exactly one exit or error frame.
+package attach
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	Protocol          = "fleet-attach"
+	MaxRedirects      = 3
+	MaxFrameSize      = 1 << 20
+	KeepAliveInterval = 5 * time.Second
+)
+
+type FrameType byte
+
+const (
+	Output FrameType = iota + 1
+	Exit
+	Error
+)
+
+var (
+	ErrNotUpgraded      = errors.New("connection is not upgraded")
+	ErrFrameTooLarge    = errors.New("frame is too large")
+	ErrUnexpectedFrame  = errors.New("unexpected frame type")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// Frame layout is [type u8][length u32 BE][payload], the frame is written
+// at once so that a SyncWriter keeps it whole
+func WriteFrame(w io.Writer, t FrameType, payload []byte) error {
+	frame := make([]byte, 5, 5+len(payload))
+	frame[0] = byte(t)
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+
+	_, err := w.Write(append(frame, payload...))
+	return err
+}
+
+func ReadFrame(r io.Reader) (FrameType, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > MaxFrameSize {
+		return 0, nil, ErrFrameTooLarge
+	}
+
+	payload := make([]byte, size)
+	_, err := io.ReadFull(r, payload)
+	return FrameType(header[0]), payload, err
+}
+
+// SyncWriter lets several goroutines write frames to the same connection
+type SyncWriter struct {
+	mu sync.Mutex
+	W  io.Writer
+}
+
+func (w *SyncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.W.Write(p)
+}
+
+// KeepAlive writes an empty output frame every KeepAliveInterval until the
+// context is done. Reading can not tell a dropped client from the end of
+// its input, so the first failed write is what calls gone
+func KeepAlive(ctx context.Context, w io.Writer, gone func()) {
+	ticker := time.NewTicker(KeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := WriteFrame(w, Output, nil); err != nil {
+			gone()
+			return
+		}
+	}
+}
+
+// OutputWriter frames everything written to it as the output
+type OutputWriter struct {
+	W io.Writer
+}
+
+func (w OutputWriter) Write(p []byte) (int, error) {
+	if err := WriteFrame(w.W, Output, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func WriteExit(w io.Writer, code int) error {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, uint32(int32(code)))
+	return WriteFrame(w, Exit, payload)
+}
+
+// ReadOutput copies the output into w until the exit frame, which carries
+// the exit code of the session
+func ReadOutput(r io.Reader, w io.Writer) (int, error) {
+	for {
+		t, payload, err := ReadFrame(r)
+		if err != nil {
+			return 0, err
+		}
+
+		switch t {
+		case Output:
+			if _, err := w.Write(payload); err != nil {
+				return 0, err
+			}
+		case Exit:
+			if len(payload) != 4 {
+				return 0, ErrUnexpectedFrame
+			}
+			return int(int32(binary.BigEndian.Uint32(payload))), nil
+		case Error:
+			return 0, errors.New(string(payload))
+		default:
+			return 0, ErrUnexpectedFrame
+		}
+	}
+}
+
+// Conn is the upgraded connection, reads go through the buffer that might
+// already hold the beginning of the stream
+type Conn struct {
+	net.Conn
+	Reader *bufio.Reader
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	return c.Reader.Read(p)
+}
+
+// CloseWrite signals the end of the input to the other side
+func (c *Conn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// Dial requests the upgrade, following the redirects (e.g. to the leader).
+// If the server refuses to upgrade, the response is returned along with
+// ErrNotUpgraded so the caller can report the reason
+func Dial(addr, endpoint string) (*Conn, *http.Response, error) {
+	for i := 0; i < MaxRedirects; i++ {
+		conn, resp, err := dial(addr, endpoint)
+		if resp == nil || resp.StatusCode != http.StatusTemporaryRedirect {
+			return conn, resp, err
+		}
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil {
+			return nil, resp, err
+		}
+		addr, endpoint = location.Host, location.RequestURI()
+	}
+	return nil, nil, ErrTooManyRedirects
+}
+
+func dial(addr, endpoint string) (*Conn, *http.Response, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+endpoint, nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", Protocol)
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer conn.Close()
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return nil, resp, ErrNotUpgraded
+	}
+
+	return &Conn{Conn: conn, Reader: reader}, resp, nil
+}
+
+// Upgrade hijacks the connection of the request and confirms the upgrade
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Header.Get("Upgrade") != Protocol {
+		return nil, fmt.Errorf("%w: expected %q upgrade", ErrNotUpgraded, Protocol)
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, ErrNotUpgraded
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: " + Protocol + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Conn{Conn: conn, Reader: rw.Reader}, nil
+}
@@ -0,0 +1,80 @@
+package c14n
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+)
+
+const EventsBufferSize = 64
+
+var ErrEventsDropped = errors.New("subscriber fell behind, events are dropped")
+
+// Event reports that the container has changed its state, only the starts
+// and the exits are reported
+type Event struct {
+	ContainerId string
+	State       container.State
+}
+
+// Broadcaster fans the events out to the subscribers, for the runtimes
+// that track the containers by themselves. The zero value is ready to use
+type Broadcaster struct {
+	mu            sync.Mutex
+	subscriptions map[*subscription]struct{}
+}
+
+type subscription struct {
+	events chan Event
+	errs   chan error
+}
+
+// Subscribe streams the events until the context is done. The stream ends
+// with an error once the subscriber falls behind, so it can resubscribe and
+// catch up on the state instead of blocking the others
+func (b *Broadcaster) Subscribe(ctx context.Context) (<-chan Event, <-chan error) {
+	s := subscription{
+		events: make(chan Event, EventsBufferSize),
+		errs:   make(chan error, 1),
+	}
+
+	b.mu.Lock()
+	if b.subscriptions == nil {
+		b.subscriptions = make(map[*subscription]struct{})
+	}
+	b.subscriptions[&s] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.end(&s, ctx.Err())
+	}()
+
+	return s.events, s.errs
+}
+
+func (b *Broadcaster) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscriptions {
+		select {
+		case s.events <- event:
+		default:
+			b.end(s, ErrEventsDropped)
+		}
+	}
+}
+
+// NOTE(SergeyCherepiuk): Must be called with the mutex held
+func (b *Broadcaster) end(s *subscription, err error) {
+	if _, ok := b.subscriptions[s]; !ok {
+		return
+	}
+	delete(b.subscriptions, s)
+	s.errs <- err
+}
@@ -0,0 +1,24 @@
+package c14n
+
+import (
+	"io"
+)
+
+type ExecOptions struct {
+	Cmd []string
+	Env []string
+	Tty bool
+
+	// Width and Height are the initial size of the terminal, ignored
+	// unless Tty is set
+	Width  uint
+	Height uint
+}
+
+// ExecStreams connect the process to its caller. Stdin is nil if the input
+// is not attached, stdout and stderr share Output (the same way they do on
+// a terminal)
+type ExecStreams struct {
+	Stdin  io.Reader
+	Output io.Writer
+}
@@ -0,0 +1,324 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrFailedOnStart     = errors.New("container failed to start")
+	ErrContainerNotFound = errors.New("container is not found")
+	ErrImageNotFound     = errors.New("image is not found")
+)
+
+// Behavior scripts the lifecycle of the containers created from an image
+type Behavior struct {
+	// FailOnStart makes CreateAndRun return the error without creating anything
+	FailOnStart error
+	// ExitAfter makes the container exit with ExitCode once it has been
+	// running for that long, zero keeps it running until it is stopped
+	ExitAfter time.Duration
+	ExitCode  int
+	// HangOnStop makes StopAndRemove block until the context is done
+	HangOnStop bool
+	// Output is returned as the logs of the container
+	Output string
+	// ExecExitCode is returned by every exec, which echoes its input back
+	ExecExitCode int
+	// PullError is returned by every pull of the image
+	PullError error
+	// ImageSize is the size of the image once it is pulled
+	ImageSize uint64
+	// Stats are reported as the resource usage of the container
+	Stats c14n.Stats
+}
+
+// Crash scripts the container to fail with the exit code 1 after d
+func Crash(d time.Duration) Behavior {
+	return Behavior{ExitAfter: d, ExitCode: 1}
+}
+
+// Exit scripts the container to finish successfully after d
+func Exit(d time.Duration) Behavior {
+	return Behavior{ExitAfter: d}
+}
+
+func FailOnStart() Behavior {
+	return Behavior{FailOnStart: ErrFailedOnStart}
+}
+
+func HangOnStop() Behavior {
+	return Behavior{HangOnStop: true}
+}
+
+type fakeContainer struct {
+	container container.Container
+	behavior  Behavior
+	startedAt time.Time
+}
+
+// Runtime keeps the containers in memory, nothing is actually run. The
+// behavior of the containers is looked up by the reference of their image
+type Runtime struct {
+	// HostNetwork makes the runtime behave as the ones that share the network
+	// of the host, it is set before the runtime is used
+	HostNetwork bool
+
+	mu         sync.Mutex
+	behaviors  map[string]Behavior
+	containers map[string]*fakeContainer
+	starts     map[string]int
+	pulls      map[string]int
+	auths      map[string]*image.Credentials
+	images     map[string]bool
+	events     c14n.Broadcaster
+}
+
+func New() *Runtime {
+	return &Runtime{
+		behaviors:  make(map[string]Behavior),
+		containers: make(map[string]*fakeContainer),
+		starts:     make(map[string]int),
+		pulls:      make(map[string]int),
+		auths:      make(map[string]*image.Credentials),
+		images:     make(map[string]bool),
+	}
+}
+
+func (r *Runtime) Name() string {
+	return "fake"
+}
+
+func (r *Runtime) SharesHostNetwork() bool {
+	return r.HostNetwork
+}
+
+func (r *Runtime) Script(imageRef string, behavior Behavior) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.behaviors[imageRef] = behavior
+}
+
+// Starts returns how many times a container of the image has been created
+func (r *Runtime) Starts(imageRef string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.starts[imageRef]
+}
+
+// Pulls returns how many times the image has been pulled
+func (r *Runtime) Pulls(imageRef string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pulls[imageRef]
+}
+
+// Auth returns the credentials the image has been pulled with last time
+func (r *Runtime) Auth(imageRef string) *image.Credentials {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.auths[imageRef]
+}
+
+// NOTE(SergeyCherepiuk): Images become present once they are pulled, the
+// containers are created regardless
+func (r *Runtime) ImagePresent(_ context.Context, ref string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.images[ref], nil
+}
+
+func (r *Runtime) PullImage(_ context.Context, ref string, auth *image.Credentials) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pulls[ref]++
+	r.auths[ref] = auth
+	if err := r.behaviors[ref].PullError; err != nil {
+		return err
+	}
+
+	r.images[ref] = true
+	return nil
+}
+
+// NOTE(SergeyCherepiuk): References of the images are their ids as well
+func (r *Runtime) Images(_ context.Context) ([]image.Info, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	infos := make([]image.Info, 0, len(r.images))
+	for ref := range r.images {
+		infos = append(infos, image.Info{Id: ref, Refs: []string{ref}, Size: r.behaviors[ref].ImageSize})
+	}
+	return infos, nil
+}
+
+func (r *Runtime) RemoveImage(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.images[id] {
+		return ErrImageNotFound
+	}
+	delete(r.images, id)
+	return nil
+}
+
+func (r *Runtime) CreateAndRun(_ context.Context, cont container.Container) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	behavior := r.behaviors[cont.Image.Ref]
+	r.starts[cont.Image.Ref]++
+
+	if behavior.FailOnStart != nil {
+		return "", behavior.FailOnStart
+	}
+
+	cont.Id = uuid.NewString()
+	r.containers[cont.Id] = &fakeContainer{
+		container: cont,
+		behavior:  behavior,
+		startedAt: time.Now(),
+	}
+
+	r.events.Publish(c14n.Event{ContainerId: cont.Id, State: container.State{Status: "running"}})
+	if behavior.ExitAfter > 0 {
+		time.AfterFunc(behavior.ExitAfter, func() { r.exit(cont.Id) })
+	}
+	return cont.Id, nil
+}
+
+// exit reports the scripted exit of the container, unless it has been
+// removed already
+func (r *Runtime) exit(id string) {
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	r.mu.Unlock()
+
+	if ok {
+		state := container.State{Status: "exited", ExitCode: c.behavior.ExitCode}
+		r.events.Publish(c14n.Event{ContainerId: id, State: state})
+	}
+}
+
+func (r *Runtime) StopAndRemove(ctx context.Context, id string) error {
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	r.mu.Unlock()
+
+	if !ok {
+		return ErrContainerNotFound
+	}
+
+	if c.behavior.HangOnStop {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	r.mu.Lock()
+	delete(r.containers, id)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Runtime) Containers(_ context.Context) ([]container.Container, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fleetConts := make([]container.Container, 0, len(r.containers))
+	for _, c := range r.containers {
+		if c.container.Config.Labels[container.TypeLabelKey] == container.TypeLabelValue {
+			fleetConts = append(fleetConts, c.container)
+		}
+	}
+	return fleetConts, nil
+}
+
+func (r *Runtime) ContainerState(_ context.Context, id string) (container.State, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return container.State{}, ErrContainerNotFound
+	}
+
+	exited := c.behavior.ExitAfter > 0 && time.Since(c.startedAt) >= c.behavior.ExitAfter
+	if !exited {
+		return container.State{Status: "running"}, nil
+	}
+	return container.State{Status: "exited", ExitCode: c.behavior.ExitCode}, nil
+}
+
+func (r *Runtime) Logs(_ context.Context, id string, opts c14n.LogsOptions) (io.ReadCloser, error) {
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, ErrContainerNotFound
+	}
+
+	lines := strings.SplitAfter(c.behavior.Output, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if opts.Tail > 0 && opts.Tail < len(lines) {
+		lines = lines[len(lines)-opts.Tail:]
+	}
+	return io.NopCloser(strings.NewReader(strings.Join(lines, ""))), nil
+}
+
+func (r *Runtime) Exec(_ context.Context, id string, _ c14n.ExecOptions, streams c14n.ExecStreams) (int, error) {
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	r.mu.Unlock()
+
+	if !ok {
+		return 0, ErrContainerNotFound
+	}
+
+	if streams.Stdin != nil {
+		if _, err := io.Copy(streams.Output, streams.Stdin); err != nil {
+			return 0, err
+		}
+	}
+	return c.behavior.ExecExitCode, nil
+}
+
+func (r *Runtime) Stats(_ context.Context, id string) (c14n.Stats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return c14n.Stats{}, ErrContainerNotFound
+	}
+	return c.behavior.Stats, nil
+}
+
+func (r *Runtime) Events(ctx context.Context) (<-chan c14n.Event, <-chan error) {
+	return r.events.Subscribe(ctx)
+}
+
+func (r *Runtime) HostAddr(_ context.Context, id string, port uint16) (string, error) {
+	r.mu.Lock()
+	_, ok := r.containers[id]
+	r.mu.Unlock()
+
+	if !ok {
+		return "", ErrContainerNotFound
+	}
+	return c14n.LoopbackAddr(port), nil
+}
@@ -0,0 +1,24 @@
+package c14n
+
+import (
+	"fmt"
+	"net"
+)
+
+// LoopbackAddr is the address of the port for the runtimes that share the
+// network of the host with the containers
+func LoopbackAddr(port uint16) string {
+	return net.JoinHostPort("127.0.0.1", fmt.Sprint(port))
+}
+
+// HostNetwork is implemented by the runtimes that share the network of the
+// host with the containers, hence two of their containers can not expose
+// the same port
+type HostNetwork interface {
+	SharesHostNetwork() bool
+}
+
+func SharesHostNetwork(r Runtime) bool {
+	hn, ok := r.(HostNetwork)
+	return ok && hn.SharesHostNetwork()
+}
@@ -0,0 +1,104 @@
+package c14n
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+const LogsPollInterval = 250 * time.Millisecond
+
+var ErrSinceUnsupported = errors.New("runtime does not keep the timestamps of the logs")
+
+type LogsOptions struct {
+	Follow bool
+	Tail   int // NOTE(SergeyCherepiuk): Zero means all the lines
+	Since  time.Time
+}
+
+// TailFile streams the log file the way the runtimes without their own log
+// storage keep the output. Following stops once the context is done or the
+// file is fully read and the container is not alive anymore
+func TailFile(ctx context.Context, path string, opts LogsOptions, alive func() bool) (io.ReadCloser, error) {
+	if !opts.Since.IsZero() {
+		return nil, ErrSinceUnsupported
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Tail > 0 {
+		if err := seekTail(file, opts.Tail); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	if !opts.Follow {
+		return file, nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer file.Close()
+		pw.CloseWithError(follow(ctx, file, pw, alive))
+	}()
+	return pr, nil
+}
+
+func follow(ctx context.Context, file *os.File, w io.Writer, alive func() bool) error {
+	for {
+		if _, err := io.Copy(w, file); err != nil {
+			return err
+		}
+
+		if !alive() {
+			_, err := io.Copy(w, file) // NOTE(SergeyCherepiuk): Last lines written before the exit
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(LogsPollInterval):
+		}
+	}
+}
+
+// seekTail moves the offset of the file to the beginning of the n-th line
+// from the end
+func seekTail(file *os.File, n int) error {
+	offsets := make([]int64, 0, n+1)
+	scanner := bufio.NewReader(file)
+
+	var offset int64
+	for {
+		line, err := scanner.ReadBytes('\n')
+		if len(line) > 0 {
+			offsets = append(offsets, offset)
+			if len(offsets) > n {
+				offsets = offsets[1:]
+			}
+			offset += int64(len(line))
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	start := offset
+	if len(offsets) > 0 {
+		start = offsets[0]
+	}
+	_, err := file.Seek(start, io.SeekStart)
+	return err
+}
@@ -2,14 +2,36 @@ package c14n
 
 import (
 	"context"
+	"io"
 
 	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
 )
 
 type Runtime interface {
 	Name() string
+
+	// ImagePresent reports whether the image is available on the node, so
+	// the container can be created without pulling it
+	ImagePresent(ctx context.Context, ref string) (bool, error)
+	PullImage(ctx context.Context, ref string, auth *image.Credentials) error
+	Images(context.Context) ([]image.Info, error)
+	RemoveImage(ctx context.Context, id string) error
+
 	CreateAndRun(context.Context, container.Container) (id string, err error)
 	StopAndRemove(ctx context.Context, id string) error
 	Containers(context.Context) ([]container.Container, error)
 	ContainerState(ctx context.Context, id string) (container.State, error)
+	Logs(ctx context.Context, id string, opts LogsOptions) (io.ReadCloser, error)
+	Exec(ctx context.Context, id string, opts ExecOptions, streams ExecStreams) (exitCode int, err error)
+	Stats(ctx context.Context, id string) (Stats, error)
+
+	// HostAddr returns the address the port of the container is reachable
+	// at from the node itself
+	HostAddr(ctx context.Context, id string, port uint16) (string, error)
+
+	// Events streams the changes of the containers' states until the
+	// context is done, the stream ends with an error sent to the second
+	// channel
+	Events(context.Context) (<-chan Event, <-chan error)
 }
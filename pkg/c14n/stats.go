@@ -0,0 +1,29 @@
+package c14n
+
+import "time"
+
+// StatsInterval is how long the CPU usage is sampled for by the runtimes
+// that read it from the cgroups themselves
+const StatsInterval = 500 * time.Millisecond
+
+// Stats is the resource usage of a container. CPU is the number of cores
+// used on average over the sampled interval, the rest are in bytes and the
+// network and block IO are counted since the container has started
+type Stats struct {
+	CPU         float64
+	MemoryUsage uint64
+	MemoryLimit uint64 // NOTE(SergeyCherepiuk): Zero if not limited or unknown
+	NetRx       uint64
+	NetTx       uint64
+	BlockRead   uint64
+	BlockWrite  uint64
+}
+
+// CPUCores converts the CPU time used over the interval into the number of
+// cores, both of the times are in nanoseconds
+func CPUCores(before, after uint64, interval time.Duration) float64 {
+	if after <= before || interval <= 0 {
+		return 0
+	}
+	return float64(after-before) / float64(interval.Nanoseconds())
+}
@@ -9,15 +9,17 @@ import (
 )
 
 type TimeBasedQueue[T any] struct {
-	mu  sync.RWMutex
-	buf map[uuid.UUID]T
-	out chan T
+	mu   sync.RWMutex
+	buf  map[uuid.UUID]T
+	out  chan T
+	done chan struct{}
 }
 
 func NewTimeBasedQueue[T any](internal time.Duration) *TimeBasedQueue[T] {
 	return &TimeBasedQueue[T]{
-		buf: make(map[uuid.UUID]T),
-		out: make(chan T),
+		buf:  make(map[uuid.UUID]T),
+		out:  make(chan T),
+		done: make(chan struct{}),
 	}
 }
 
@@ -32,24 +34,31 @@ func (tbq *TimeBasedQueue[T]) GetAll() []T {
 }
 
 func (tbq *TimeBasedQueue[T]) EnqueueNow(value T) {
-	id := tbq.put(value)
-	go func() {
-		tbq.out <- value
-		tbq.delete(id)
-	}()
+	tbq.EnqueueWithDelay(0, value)
 }
 
 func (tbq *TimeBasedQueue[T]) EnqueueWithDelay(delay time.Duration, value T) {
 	id := tbq.put(value)
 	go func() {
-		time.Sleep(delay)
-		tbq.out <- value
-		tbq.delete(id)
+		defer tbq.delete(id)
+
+		select {
+		case <-tbq.done:
+			return
+		case <-time.After(delay):
+		}
+
+		select {
+		case <-tbq.done:
+		case tbq.out <- value:
+		}
 	}()
 }
 
+// Close drops the values that are not received yet, Out is never closed,
+// so the receivers have to stop on their own
 func (tbq *TimeBasedQueue[T]) Close() {
-	close(tbq.out)
+	close(tbq.done)
 }
 
 func (tbq *TimeBasedQueue[T]) put(value T) uuid.UUID {
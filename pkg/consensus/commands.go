@@ -2,6 +2,7 @@ package consensus
 
 import (
 	"encoding/json"
+	"sync"
 
 	"github.com/SergeyCherepiuk/fleet/pkg/task"
 	"github.com/google/uuid"
@@ -14,36 +15,64 @@ const (
 	RemoveWorker CommandType = "RemoveWorker"
 	SetTask      CommandType = "SetTask"
 	RemoveTask   CommandType = "RemoveTask"
+	Batch        CommandType = "Batch"
+	Noop         CommandType = "Noop"
+
+	SetResource    CommandType = "SetResource"
+	RemoveResource CommandType = "RemoveResource"
 )
 
+// NOTE(SergeyCherepiuk): Index is assigned by the store when the command is
+// committed, commands received from the leader keep their original index
 type Command struct {
-	Index int
-	Type  CommandType
-	Data  []byte
+	Index   int
+	Term    int
+	Type    CommandType
+	Version int
+	Data    []byte
+}
+
+func init() {
+	RegisterCommand(SetWorker, 1, setWorker)
+	RegisterCommand(RemoveWorker, 1, removeWorker)
+	RegisterCommand(SetTask, 1, setTask)
+	RegisterCommand(RemoveTask, 1, removeTask)
+	RegisterCommand(Batch, 1, batch)
+	RegisterCommand(SetResource, 1, setResource)
+	RegisterCommand(RemoveResource, 1, removeResource)
+	RegisterCommand(Noop, 1, func(*State, struct{}) ([]Change, error) { return nil, nil })
+}
+
+func NewSetWorkerCommand(workerId uuid.UUID, worker Worker) *Command {
+	return NewCommand(SetWorker, SetWorkerCommandData{WorkerId: workerId, Worker: worker})
+}
+
+func NewRemoveWorkerCommand(workerId uuid.UUID) *Command {
+	return NewCommand(RemoveWorker, RemoveWorkerCommandData{WorkerId: workerId})
+}
+
+func NewSetTaskCommand(workerId uuid.UUID, task task.Task) *Command {
+	return NewCommand(SetTask, SetTaskCommandData{WorkerId: workerId, Task: task})
 }
 
-func NewSetWorkerCommand(index int, workerId uuid.UUID, worker Worker) *Command {
-	data := SetWorkerCommandData{WorkerId: workerId, Worker: worker}
-	marshaled, _ := json.Marshal(data)
-	return &Command{Index: index, Type: SetWorker, Data: marshaled}
+func NewRemoveTaskCommand(taskId uuid.UUID) *Command {
+	return NewCommand(RemoveTask, RemoveTaskCommandData{TaskId: taskId})
 }
 
-func NewRemoveWorkerCommand(index int, workerId uuid.UUID) *Command {
-	data := RemoveWorkerCommandData{WorkerId: workerId}
-	marshaled, _ := json.Marshal(data)
-	return &Command{Index: index, Type: RemoveWorker, Data: marshaled}
+func NewBatchCommand(cmds ...Command) *Command {
+	return NewConditionalCommand(nil, cmds...)
 }
 
-func NewSetTaskCommand(index int, workerId uuid.UUID, task task.Task) *Command {
-	data := SetTaskCommandData{WorkerId: workerId, Task: task}
-	marshaled, _ := json.Marshal(data)
-	return &Command{Index: index, Type: SetTask, Data: marshaled}
+func NewConditionalCommand(conditions []Condition, cmds ...Command) *Command {
+	return NewCommand(Batch, BatchCommandData{Conditions: conditions, Commands: cmds})
 }
 
-func NewRemoveTaskCommand(index int, taskId uuid.UUID) *Command {
-	data := RemoveTaskCommandData{TaskId: taskId}
-	marshaled, _ := json.Marshal(data)
-	return &Command{Index: index, Type: RemoveTask, Data: marshaled}
+func NewSetResourceCommand(kind, id string, resource json.RawMessage) *Command {
+	return NewCommand(SetResource, SetResourceCommandData{Kind: kind, Id: id, Resource: resource})
+}
+
+func NewRemoveResourceCommand(kind, id string) *Command {
+	return NewCommand(RemoveResource, RemoveResourceCommandData{Kind: kind, Id: id})
 }
 
 type SetWorkerCommandData struct {
@@ -63,3 +92,155 @@ type SetTaskCommandData struct {
 type RemoveTaskCommandData struct {
 	TaskId uuid.UUID
 }
+
+type SetResourceCommandData struct {
+	Kind     string
+	Id       string
+	Resource json.RawMessage
+}
+
+type RemoveResourceCommandData struct {
+	Kind string
+	Id   string
+}
+
+type BatchCommandData struct {
+	Conditions []Condition
+	Commands   []Command
+}
+
+// Condition holds if the task is in the given state and, unless the worker
+// id is nil, assigned to that worker. The zero state requires the task to be
+// absent from the store
+type Condition struct {
+	TaskId   uuid.UUID
+	WorkerId uuid.UUID
+	State    task.State
+}
+
+func setWorker(state *State, data SetWorkerCommandData) ([]Change, error) {
+	state.Workers[data.WorkerId] = Worker{
+		Addr:    data.Worker.Addr,
+		MuTasks: &sync.RWMutex{},
+		Tasks:   make(map[uuid.UUID]task.Task),
+	}
+
+	change := Change{Type: WorkerSet, WorkerId: data.WorkerId}
+	return []Change{change}, nil
+}
+
+func removeWorker(state *State, data RemoveWorkerCommandData) ([]Change, error) {
+	worker, ok := state.Workers[data.WorkerId]
+	if !ok {
+		return nil, ErrWorkerNotFound
+	}
+
+	delete(state.Workers, data.WorkerId)
+
+	changes := []Change{{Type: WorkerRemoved, WorkerId: data.WorkerId}}
+	worker.MuTasks.RLock()
+	for id, t := range worker.Tasks {
+		t := t
+		changes = append(changes, Change{
+			Type:     TaskRemoved,
+			WorkerId: data.WorkerId,
+			TaskId:   id,
+			Task:     &t,
+		})
+	}
+	worker.MuTasks.RUnlock()
+
+	return changes, nil
+}
+
+func setTask(state *State, data SetTaskCommandData) ([]Change, error) {
+	worker, ok := state.Workers[data.WorkerId]
+	if !ok {
+		return nil, ErrWorkerNotFound
+	}
+
+	worker.MuTasks.Lock()
+	worker.Tasks[data.Task.Id] = data.Task
+	worker.MuTasks.Unlock()
+
+	change := Change{
+		Type:     TaskSet,
+		WorkerId: data.WorkerId,
+		TaskId:   data.Task.Id,
+		Task:     &data.Task,
+	}
+	return []Change{change}, nil
+}
+
+func removeTask(state *State, data RemoveTaskCommandData) ([]Change, error) {
+	for id, worker := range state.Workers {
+		worker.MuTasks.Lock()
+		t, ok := worker.Tasks[data.TaskId]
+		delete(worker.Tasks, data.TaskId)
+		worker.MuTasks.Unlock()
+
+		if ok {
+			change := Change{
+				Type:     TaskRemoved,
+				WorkerId: id,
+				TaskId:   data.TaskId,
+				Task:     &t,
+			}
+			return []Change{change}, nil
+		}
+	}
+	return nil, ErrWorkerNotFound
+}
+
+func setResource(state *State, data SetResourceCommandData) ([]Change, error) {
+	change, err := state.SetResource(data.Kind, data.Id, data.Resource)
+	if err != nil {
+		return nil, err
+	}
+	return []Change{change}, nil
+}
+
+func removeResource(state *State, data RemoveResourceCommandData) ([]Change, error) {
+	change, err := state.RemoveResource(data.Kind, data.Id)
+	if err != nil {
+		return nil, err
+	}
+	return []Change{change}, nil
+}
+
+// batch applies the commands to a copy of the state and swaps it in only
+// if the conditions hold and every one of the commands succeeds
+func batch(state *State, data BatchCommandData) ([]Change, error) {
+	for _, condition := range data.Conditions {
+		if !holds(state, condition) {
+			return nil, ErrConditionFailed
+		}
+	}
+
+	staged := copyState(*state)
+	changes := make([]Change, 0, len(data.Commands))
+	for _, cmd := range data.Commands {
+		c, err := apply(&staged, cmd)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+
+	*state = staged
+	return changes, nil
+}
+
+func holds(state *State, condition Condition) bool {
+	for id, worker := range state.Workers {
+		worker.MuTasks.RLock()
+		t, ok := worker.Tasks[condition.TaskId]
+		worker.MuTasks.RUnlock()
+
+		if ok {
+			sameWorker := condition.WorkerId == uuid.Nil || condition.WorkerId == id
+			return sameWorker && t.State == condition.State
+		}
+	}
+	return condition.State == ""
+}
@@ -0,0 +1,98 @@
+package consensus
+
+import (
+	"encoding/json"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/node"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/google/uuid"
+)
+
+// LogEntry is a command with its data decoded, so it can be read by a human
+type LogEntry struct {
+	Index   int
+	Term    int
+	Type    CommandType
+	Version int
+	Data    any
+}
+
+type batchEntryData struct {
+	Conditions []Condition
+	Commands   []LogEntry
+}
+
+func DecodeCommand(cmd Command) LogEntry {
+	entry := LogEntry{
+		Index:   cmd.Index,
+		Term:    cmd.Term,
+		Type:    cmd.Type,
+		Version: cmd.Version,
+	}
+
+	if cmd.Type == Batch {
+		var data BatchCommandData
+		if err := json.Unmarshal(cmd.Data, &data); err == nil {
+			commands := make([]LogEntry, 0, len(data.Commands))
+			for _, c := range data.Commands {
+				commands = append(commands, DecodeCommand(c))
+			}
+			entry.Data = batchEntryData{Conditions: data.Conditions, Commands: commands}
+			return entry
+		}
+	}
+
+	cmd = RedactCommand(cmd)
+	if json.Valid(cmd.Data) {
+		entry.Data = json.RawMessage(cmd.Data)
+	} else if len(cmd.Data) > 0 {
+		entry.Data = cmd.Data
+	}
+	return entry
+}
+
+// Log returns the decoded commands starting from the given index, the ones
+// that are already compacted are skipped
+func Log(s Store, fromIndex int) []LogEntry {
+	n := s.LastIndex() - max(fromIndex, 1) + 1
+	if n <= 0 {
+		return []LogEntry{}
+	}
+
+	cmds := s.GetLastNCommands(n)
+	entries := make([]LogEntry, 0, len(cmds))
+	for _, cmd := range cmds {
+		entries = append(entries, DecodeCommand(cmd))
+	}
+	return entries
+}
+
+type WorkerDump struct {
+	Addr  node.Addr
+	Tasks map[uuid.UUID]task.Task
+}
+
+// Dump is the whole state tree of the store at the given index
+type Dump struct {
+	LastIndex int
+	LastTerm  int
+	Workers   map[uuid.UUID]WorkerDump
+	Resources map[string]map[string]json.RawMessage
+}
+
+func NewDump(s Store) Dump {
+	snapshot := s.Snapshot()
+	RedactResources(snapshot.State.Resources)
+
+	workers := make(map[uuid.UUID]WorkerDump, len(snapshot.State.Workers))
+	for id, worker := range snapshot.State.Workers {
+		workers[id] = WorkerDump{Addr: worker.Addr, Tasks: worker.Tasks}
+	}
+
+	return Dump{
+		LastIndex: snapshot.LastIndex,
+		LastTerm:  snapshot.LastTerm,
+		Workers:   workers,
+		Resources: snapshot.State.Resources,
+	}
+}
@@ -0,0 +1,267 @@
+package consensus
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+const (
+	SnapshotThreshold        = 1024
+	SnapshotTrailingCommands = 128
+
+	walFileName       = "wal"
+	snapshotFileName  = "snapshot"
+	raftWalFileName   = "raft_wal"
+	hardStateFileName = "hard_state"
+)
+
+type durableStore struct {
+	*store
+
+	mu            sync.Mutex
+	dir           string
+	wal           *os.File
+	sinceSnapshot int
+
+	muRaft      sync.Mutex
+	hardState   HardState
+	raftEntries []Command
+	raftWal     *os.File
+}
+
+func NewDurableStore(dir string) (*durableStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	ds := &durableStore{store: NewLocalStore(), dir: dir}
+	if err := ds.recover(); err != nil {
+		return nil, err
+	}
+
+	if err := ds.recoverRaft(); err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+func (ds *durableStore) CommitChange(cmd Command) (int, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if cmd.Index == 0 {
+		cmd.Index = ds.store.LastIndex() + 1
+	}
+
+	if cmd.Index != ds.store.LastIndex()+1 {
+		return ds.store.CommitChange(cmd)
+	}
+
+	if err := appendRecords(ds.wal, cmd); err != nil {
+		return 0, err
+	}
+
+	_, err := ds.store.CommitChange(cmd)
+
+	ds.sinceSnapshot++
+	if ds.sinceSnapshot >= SnapshotThreshold {
+		err = errors.Join(err, ds.snapshot())
+	}
+
+	return 0, err
+}
+
+func (ds *durableStore) Restore(snapshot Snapshot) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.store.Restore(snapshot); err != nil {
+		return err
+	}
+
+	if err := writeSnapshot(ds.path(snapshotFileName), snapshot); err != nil {
+		return err
+	}
+
+	ds.sinceSnapshot = 0
+	return ds.rewriteWal()
+}
+
+func (ds *durableStore) snapshot() error {
+	snapshot := ds.store.Snapshot()
+	if err := writeSnapshot(ds.path(snapshotFileName), snapshot); err != nil {
+		return err
+	}
+
+	ds.store.compact(snapshot.LastIndex - SnapshotTrailingCommands)
+	ds.sinceSnapshot = 0
+	return ds.rewriteWal()
+}
+
+func (ds *durableStore) rewriteWal() error {
+	if ds.wal != nil {
+		ds.wal.Close()
+	}
+
+	// NOTE(SergeyCherepiuk): The last compacted entry goes first, otherwise
+	// its term is lost and the log can not be matched against it on recovery
+	cmds := append([]Command{ds.store.compactedEntry()}, ds.store.GetLastNCommands(ds.store.LogSize())...)
+	if err := writeWal(ds.path(walFileName), cmds); err != nil {
+		return err
+	}
+
+	return ds.openWal()
+}
+
+func (ds *durableStore) recover() error {
+	snapshot, err := readSnapshot(ds.path(snapshotFileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	cmds, offset, err := readWal(ds.path(walFileName))
+	if err != nil {
+		return err
+	}
+
+	if err := ds.store.Restore(snapshot); err != nil {
+		return err
+	}
+
+	history, tail := make([]Command, 0), make([]Command, 0)
+	for _, cmd := range cmds {
+		if cmd.Index <= snapshot.LastIndex {
+			history = append(history, cmd)
+		} else {
+			tail = append(tail, cmd)
+		}
+	}
+
+	if len(history) > 0 && history[len(history)-1].Index == snapshot.LastIndex {
+		ds.store.restoreLog(history)
+	}
+
+	for _, cmd := range tail {
+		ds.store.CommitChange(cmd) // NOTE(SergeyCherepiuk): Error is ignored the same way it was on the first commit
+		ds.sinceSnapshot++
+	}
+
+	if err := os.Truncate(ds.path(walFileName), offset); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return ds.openWal()
+}
+
+func (ds *durableStore) openWal() error {
+	wal, err := os.OpenFile(ds.path(walFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	ds.wal = wal
+	return nil
+}
+
+func (ds *durableStore) path(name string) string {
+	return filepath.Join(ds.dir, name)
+}
+
+func (ds *durableStore) HardState() HardState {
+	ds.muRaft.Lock()
+	defer ds.muRaft.Unlock()
+	return ds.hardState
+}
+
+func (ds *durableStore) SaveHardState(state HardState) error {
+	ds.muRaft.Lock()
+	defer ds.muRaft.Unlock()
+
+	err := writeAtomically(ds.path(hardStateFileName), func(w io.Writer) error {
+		return writeRecord(w, state)
+	})
+	if err != nil {
+		return err
+	}
+
+	ds.hardState = state
+	return nil
+}
+
+func (ds *durableStore) Entries() []Command {
+	ds.muRaft.Lock()
+	defer ds.muRaft.Unlock()
+	return slices.Clone(ds.raftEntries)
+}
+
+func (ds *durableStore) AppendEntries(entries []Command) error {
+	ds.muRaft.Lock()
+	defer ds.muRaft.Unlock()
+
+	return appendRecords(ds.raftWal, entries...)
+}
+
+func (ds *durableStore) ResetEntries(entries []Command) error {
+	ds.muRaft.Lock()
+	defer ds.muRaft.Unlock()
+
+	if ds.raftWal != nil {
+		ds.raftWal.Close()
+	}
+
+	if err := writeWal(ds.path(raftWalFileName), entries); err != nil {
+		return err
+	}
+
+	return ds.openRaftWal()
+}
+
+func (ds *durableStore) recoverRaft() error {
+	file, err := os.Open(ds.path(hardStateFileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		_, err = readRecord(file, &ds.hardState)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	cmds, offset, err := readWal(ds.path(raftWalFileName))
+	if err != nil {
+		return err
+	}
+
+	// NOTE(SergeyCherepiuk): Entries are only ever appended to the file, so a
+	// later entry replaces the earlier ones starting from its index
+	ds.raftEntries = make([]Command, 0, len(cmds))
+	for _, cmd := range cmds {
+		for len(ds.raftEntries) > 0 && ds.raftEntries[len(ds.raftEntries)-1].Index >= cmd.Index {
+			ds.raftEntries = ds.raftEntries[:len(ds.raftEntries)-1]
+		}
+		ds.raftEntries = append(ds.raftEntries, cmd)
+	}
+
+	if err := os.Truncate(ds.path(raftWalFileName), offset); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return ds.openRaftWal()
+}
+
+func (ds *durableStore) openRaftWal() error {
+	wal, err := os.OpenFile(ds.path(raftWalFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	ds.raftWal = wal
+	return nil
+}
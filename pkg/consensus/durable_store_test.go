@@ -0,0 +1,155 @@
+package consensus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func commitN(t *testing.T, s Store, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		cmd := newTestCommand(fmt.Sprint(i), i)
+		cmd.Term = 1
+		if _, err := s.CommitChange(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertResources(t *testing.T, s Store, n int) {
+	t.Helper()
+
+	resources, err := AllResources[int](s, testKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != n {
+		t.Fatalf("store has %d resources instead of %d", len(resources), n)
+	}
+	for i := 0; i < n; i++ {
+		if value := resources[fmt.Sprint(i)]; value != i {
+			t.Fatalf("resource %d is %d", i, value)
+		}
+	}
+}
+
+func TestDurableStoreRecoversCommands(t *testing.T) {
+	dir := t.TempDir()
+	commitN(t, openDurableStore(t, dir), 10)
+
+	ds := openDurableStore(t, dir)
+	assertResources(t, ds, 10)
+	if ds.LastIndex() != 10 || ds.LastTerm() != 1 {
+		t.Fatalf("store is at %d in term %d instead of 10 in term 1", ds.LastIndex(), ds.LastTerm())
+	}
+}
+
+func TestDurableStoreTruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	commitN(t, openDurableStore(t, dir), 3)
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal.Write([]byte{42, 0, 0, 0, 1, 2, 3}) // NOTE(SergeyCherepiuk): Header promises more than there is
+	wal.Close()
+
+	ds := openDurableStore(t, dir)
+	assertResources(t, ds, 3)
+
+	cmd := newTestCommand("3", 3)
+	if _, err := ds.CommitChange(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	assertResources(t, openDurableStore(t, dir), 4)
+}
+
+func TestDurableStoreRecoversFromSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	n := SnapshotThreshold + SnapshotTrailingCommands/2
+	commitN(t, openDurableStore(t, dir), n)
+
+	if _, err := os.Stat(filepath.Join(dir, snapshotFileName)); err != nil {
+		t.Fatalf("snapshot is not taken: %v", err)
+	}
+
+	ds := openDurableStore(t, dir)
+	assertResources(t, ds, n)
+
+	compacted := SnapshotThreshold - SnapshotTrailingCommands
+	if ds.FirstIndex() != compacted+1 {
+		t.Fatalf("log starts at %d instead of %d", ds.FirstIndex(), compacted+1)
+	}
+	if entry := ds.compactedEntry(); entry.Index != compacted || entry.Term != 1 {
+		t.Fatalf("compacted entry is %d in term %d instead of %d in term 1", entry.Index, entry.Term, compacted)
+	}
+	if _, err := ds.GetCommandsAfter(compacted - 1); err == nil {
+		t.Fatal("compacted commands are returned")
+	}
+	if cmds, err := ds.GetCommandsAfter(compacted); err != nil || len(cmds) != n-compacted {
+		t.Fatalf("%d commands after the compacted ones instead of %d: %v", len(cmds), n-compacted, err)
+	}
+}
+
+func TestDurableStoreRecoversRaftState(t *testing.T) {
+	dir := t.TempDir()
+	ds := openDurableStore(t, dir)
+
+	if err := ds.SaveHardState(HardState{Term: 3, VotedFor: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []Command{{Index: 1, Term: 1}, {Index: 2, Term: 1}, {Index: 3, Term: 1}}
+	if err := ds.AppendEntries(entries); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.AppendEntries([]Command{{Index: 2, Term: 3}}); err != nil { // NOTE(SergeyCherepiuk): Conflicting suffix is replaced
+		t.Fatal(err)
+	}
+
+	recovered := openDurableStore(t, dir)
+	if state := recovered.HardState(); state != (HardState{Term: 3, VotedFor: "b"}) {
+		t.Fatalf("hard state is %+v", state)
+	}
+
+	want := []Command{{Index: 1, Term: 1}, {Index: 2, Term: 3}}
+	if got := recovered.Entries(); !slices.EqualFunc(got, want, sameEntry) {
+		t.Fatalf("entries are %+v instead of %+v", got, want)
+	}
+
+	if err := recovered.ResetEntries(want[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if got := openDurableStore(t, dir).Entries(); !slices.EqualFunc(got, want[1:], sameEntry) {
+		t.Fatalf("entries are %+v instead of %+v", got, want[1:])
+	}
+}
+
+func TestReadWalStopsAtCorruptedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), walFileName)
+	cmds := []Command{newTestCommand("0", 0), newTestCommand("1", 1)}
+	if err := writeWal(path, cmds); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	data[len(data)-1] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+
+	read, offset, err := readWal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 {
+		t.Fatalf("%d commands are read instead of 1", len(read))
+	}
+	if offset >= int64(len(data)) {
+		t.Fatalf("offset %d is past the corrupted record", offset)
+	}
+}
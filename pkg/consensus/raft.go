@@ -0,0 +1,757 @@
+package consensus
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+)
+
+const (
+	RaftHeartbeatInterval  = 150 * time.Millisecond
+	RaftElectionTimeoutMin = 1 * time.Second
+	RaftElectionTimeoutMax = 2 * time.Second
+	RaftRequestTimeout     = 500 * time.Millisecond
+	RaftCommitTimeout      = 5 * time.Second
+)
+
+type Role string
+
+const (
+	Follower  Role = "Follower"
+	Candidate Role = "Candidate"
+	Leader    Role = "Leader"
+)
+
+type VoteRequest struct {
+	Term         int
+	CandidateId  string
+	LastLogIndex int
+	LastLogTerm  int
+}
+
+type VoteResponse struct {
+	Term        int
+	VoteGranted bool
+}
+
+type AppendRequest struct {
+	Term         int
+	LeaderId     string
+	PrevLogIndex int
+	PrevLogTerm  int
+	Entries      []Command
+	LeaderCommit int
+}
+
+type AppendResponse struct {
+	Term          int
+	Success       bool
+	ConflictIndex int
+}
+
+type InstallSnapshotRequest struct {
+	Term     int
+	LeaderId string
+	Snapshot Snapshot
+}
+
+type InstallSnapshotResponse struct {
+	Term int
+}
+
+type pendingCommit struct {
+	term int
+	done chan error
+}
+
+// Raft replicates commands across the managers and applies the committed
+// ones to the underlying store, which remains the source of all reads.
+type Raft struct {
+	Store
+
+	storage RaftStorage
+	id      string
+	peers   []string
+
+	mu          sync.Mutex
+	role        Role
+	currentTerm int
+	votedFor    string
+	leader      string
+	log         []Command // NOTE(SergeyCherepiuk): log[0] is a sentinel entry
+	commitIndex int
+	lastApplied int
+	nextIndex   map[string]int
+	matchIndex  map[string]int
+	pending     map[int]pendingCommit
+
+	resetElection chan struct{}
+	apply         chan struct{}
+	replicate     map[string]chan struct{}
+	done          chan struct{}
+	stopOnce      sync.Once
+}
+
+func NewRaft(id string, peers []string, fsm Store) *Raft {
+	lastIndex, lastTerm := fsm.LastIndex(), fsm.LastTerm()
+	replicate := make(map[string]chan struct{}, len(peers))
+	for _, peer := range peers {
+		replicate[peer] = make(chan struct{}, 1)
+	}
+
+	storage, ok := fsm.(RaftStorage)
+	if !ok {
+		storage = &volatileRaftStorage{}
+	}
+
+	r := &Raft{
+		Store:         fsm,
+		storage:       storage,
+		id:            id,
+		peers:         peers,
+		role:          Follower,
+		currentTerm:   storage.HardState().Term,
+		votedFor:      storage.HardState().VotedFor,
+		log:           []Command{{Index: lastIndex, Term: lastTerm}},
+		commitIndex:   lastIndex,
+		lastApplied:   lastIndex,
+		nextIndex:     make(map[string]int),
+		matchIndex:    make(map[string]int),
+		pending:       make(map[int]pendingCommit),
+		resetElection: make(chan struct{}, 1),
+		apply:         make(chan struct{}, 1),
+		replicate:     replicate,
+		done:          make(chan struct{}),
+	}
+
+	// NOTE(SergeyCherepiuk): Entries that were applied before the restart are
+	// already in the store, the rest wait to be committed again
+	for _, cmd := range storage.Entries() {
+		if cmd.Index == r.lastLogIndex()+1 {
+			r.log = append(r.log, cmd)
+		}
+	}
+
+	return r
+}
+
+func (r *Raft) Start() {
+	go r.applyCommitted()
+	go r.watchElectionTimeout()
+
+	if len(r.peers) == 0 {
+		r.startElection()
+	}
+}
+
+// Stop steps the node down for good, it neither replicates, applies nor
+// starts elections afterwards
+func (r *Raft) Stop() {
+	r.stopOnce.Do(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		close(r.done)
+		r.role = Follower
+		r.leader = ""
+	})
+}
+
+func (r *Raft) stopped() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Raft) Id() string {
+	return r.id
+}
+
+func (r *Raft) Leader() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leader
+}
+
+func (r *Raft) IsLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.role == Leader
+}
+
+func (r *Raft) CommitChange(cmd Command) (int, error) {
+	r.mu.Lock()
+	if r.role != Leader || r.stopped() {
+		leader := r.leader
+		r.mu.Unlock()
+
+		if leader == "" {
+			return 0, ErrNoLeader
+		}
+		return 0, forward(leader, cmd)
+	}
+
+	cmd.Index = r.lastLogIndex() + 1
+	cmd.Term = r.currentTerm
+	if err := r.storage.AppendEntries([]Command{cmd}); err != nil {
+		r.mu.Unlock()
+		return 0, err
+	}
+	r.log = append(r.log, cmd)
+
+	done := make(chan error, 1)
+	r.pending[cmd.Index] = pendingCommit{term: cmd.Term, done: done}
+	r.advanceCommitIndex()
+	r.mu.Unlock()
+
+	r.triggerReplication()
+
+	select {
+	case err := <-done:
+		return 0, err
+	case <-time.After(RaftCommitTimeout):
+		r.mu.Lock()
+		delete(r.pending, cmd.Index)
+		r.mu.Unlock()
+		return 0, ErrCommitTimeout
+	}
+}
+
+func (r *Raft) RequestVote(req VoteRequest) VoteResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopped() {
+		return VoteResponse{Term: r.currentTerm, VoteGranted: false}
+	}
+
+	if req.Term > r.currentTerm {
+		if err := r.stepDown(req.Term); err != nil {
+			return VoteResponse{Term: r.currentTerm, VoteGranted: false}
+		}
+	}
+
+	upToDate := req.LastLogTerm > r.lastLogTerm() ||
+		(req.LastLogTerm == r.lastLogTerm() && req.LastLogIndex >= r.lastLogIndex())
+
+	canVote := r.votedFor == "" || r.votedFor == req.CandidateId
+
+	granted := req.Term == r.currentTerm && canVote && upToDate
+	if granted && r.votedFor != req.CandidateId {
+		granted = r.setHardState(r.currentTerm, req.CandidateId) == nil
+	}
+
+	if granted {
+		r.resetElectionTimer()
+	}
+
+	return VoteResponse{Term: r.currentTerm, VoteGranted: granted}
+}
+
+func (r *Raft) AppendEntries(req AppendRequest) AppendResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.Term < r.currentTerm || r.stopped() {
+		return AppendResponse{Term: r.currentTerm, Success: false}
+	}
+
+	if req.Term > r.currentTerm || r.role != Follower {
+		if err := r.stepDown(req.Term); err != nil {
+			return AppendResponse{Term: r.currentTerm, Success: false}
+		}
+	}
+	r.leader = req.LeaderId
+	r.resetElectionTimer()
+
+	if req.PrevLogIndex < r.log[0].Index { // NOTE(SergeyCherepiuk): Compacted entries are committed, hence they match
+		skip := min(r.log[0].Index-req.PrevLogIndex, len(req.Entries))
+		req.Entries = req.Entries[skip:]
+		req.PrevLogIndex, req.PrevLogTerm = r.log[0].Index, r.log[0].Term
+	}
+
+	if req.PrevLogIndex > r.lastLogIndex() {
+		return AppendResponse{
+			Term:          r.currentTerm,
+			Success:       false,
+			ConflictIndex: r.lastLogIndex() + 1,
+		}
+	}
+
+	if term := r.entry(req.PrevLogIndex).Term; term != req.PrevLogTerm {
+		conflictIndex := req.PrevLogIndex
+		for conflictIndex > r.log[0].Index+1 && r.entry(conflictIndex-1).Term == term {
+			conflictIndex--
+		}
+		return AppendResponse{
+			Term:          r.currentTerm,
+			Success:       false,
+			ConflictIndex: conflictIndex,
+		}
+	}
+
+	for i, cmd := range req.Entries {
+		index := req.PrevLogIndex + 1 + i
+		if index <= r.lastLogIndex() && r.entry(index).Term == cmd.Term {
+			continue
+		}
+
+		if err := r.storage.AppendEntries(req.Entries[i:]); err != nil {
+			return AppendResponse{Term: r.currentTerm, Success: false, ConflictIndex: index}
+		}
+
+		r.log = append(r.log[:index-r.log[0].Index], req.Entries[i:]...)
+		break
+	}
+
+	if req.LeaderCommit > r.commitIndex {
+		lastNewIndex := req.PrevLogIndex + len(req.Entries)
+		r.commitIndex = min(req.LeaderCommit, lastNewIndex)
+		r.triggerApply()
+	}
+
+	return AppendResponse{Term: r.currentTerm, Success: true}
+}
+
+func (r *Raft) InstallSnapshot(req InstallSnapshotRequest) InstallSnapshotResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.Term < r.currentTerm || r.stopped() {
+		return InstallSnapshotResponse{Term: r.currentTerm}
+	}
+
+	if req.Term > r.currentTerm || r.role != Follower {
+		if err := r.stepDown(req.Term); err != nil {
+			return InstallSnapshotResponse{Term: r.currentTerm}
+		}
+	}
+	r.leader = req.LeaderId
+	r.resetElectionTimer()
+
+	snapshot := req.Snapshot
+	if snapshot.LastIndex <= r.commitIndex {
+		return InstallSnapshotResponse{Term: r.currentTerm}
+	}
+
+	if err := r.Store.Restore(snapshot); err != nil {
+		return InstallSnapshotResponse{Term: r.currentTerm}
+	}
+
+	if err := r.storage.ResetEntries(nil); err != nil {
+		return InstallSnapshotResponse{Term: r.currentTerm}
+	}
+
+	r.log = []Command{{Index: snapshot.LastIndex, Term: snapshot.LastTerm}}
+	r.commitIndex = snapshot.LastIndex
+	r.lastApplied = snapshot.LastIndex
+
+	return InstallSnapshotResponse{Term: r.currentTerm}
+}
+
+func (r *Raft) watchElectionTimeout() {
+	for {
+		timeout := RaftElectionTimeoutMin +
+			time.Duration(rand.Int63n(int64(RaftElectionTimeoutMax-RaftElectionTimeoutMin)))
+
+		select {
+		case <-r.done:
+			return
+		case <-r.resetElection:
+			continue
+		case <-time.After(timeout):
+		}
+
+		if !r.IsLeader() {
+			r.startElection()
+		}
+	}
+}
+
+func (r *Raft) startElection() {
+	r.mu.Lock()
+	if r.stopped() {
+		r.mu.Unlock()
+		return
+	}
+
+	if err := r.setHardState(r.currentTerm+1, r.id); err != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.role = Candidate
+	r.leader = ""
+
+	term := r.currentTerm
+	req := VoteRequest{
+		Term:         term,
+		CandidateId:  r.id,
+		LastLogIndex: r.lastLogIndex(),
+		LastLogTerm:  r.lastLogTerm(),
+	}
+
+	votes := 1
+	if r.hasQuorum(votes) {
+		r.becomeLeader()
+	}
+	r.mu.Unlock()
+
+	for _, peer := range r.peers {
+		go func(peer string) {
+			var resp VoteResponse
+			if err := call(peer, "/raft/vote", req, &resp); err != nil {
+				return
+			}
+
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			if resp.Term > r.currentTerm {
+				r.stepDown(resp.Term)
+				return
+			}
+
+			if r.role != Candidate || r.currentTerm != term || !resp.VoteGranted {
+				return
+			}
+
+			votes++
+			if r.hasQuorum(votes) {
+				r.becomeLeader()
+			}
+		}(peer)
+	}
+}
+
+func (r *Raft) becomeLeader() {
+	r.role = Leader
+	r.leader = r.id
+
+	for _, peer := range r.peers {
+		r.nextIndex[peer] = r.lastLogIndex() + 1
+		r.matchIndex[peer] = 0
+	}
+
+	// NOTE(SergeyCherepiuk): Entries from previous terms can only be committed
+	// indirectly, so the new leader appends an entry of its own term right away
+	noop := Command{Index: r.lastLogIndex() + 1, Term: r.currentTerm, Type: Noop}
+	if err := r.storage.AppendEntries([]Command{noop}); err != nil {
+		r.role = Follower
+		r.leader = ""
+		return
+	}
+	r.log = append(r.log, noop)
+	r.advanceCommitIndex()
+
+	for _, peer := range r.peers {
+		go r.replicateTo(peer, r.currentTerm)
+	}
+}
+
+func (r *Raft) stepDown(term int) error {
+	r.role = Follower
+	if term > r.currentTerm {
+		return r.setHardState(term, "")
+	}
+	return nil
+}
+
+// setHardState persists the term and the vote before they take effect,
+// so that a restarted node never votes twice in the same term
+func (r *Raft) setHardState(term int, votedFor string) error {
+	if err := r.storage.SaveHardState(HardState{Term: term, VotedFor: votedFor}); err != nil {
+		return err
+	}
+
+	r.currentTerm, r.votedFor = term, votedFor
+	return nil
+}
+
+func (r *Raft) replicateTo(peer string, term int) {
+	ticker := time.NewTicker(RaftHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		if !r.sendAppendEntries(peer, term) {
+			return
+		}
+
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		case <-r.replicate[peer]:
+		}
+	}
+}
+
+func (r *Raft) sendAppendEntries(peer string, term int) bool {
+	r.mu.Lock()
+	if r.role != Leader || r.currentTerm != term {
+		r.mu.Unlock()
+		return false
+	}
+
+	next := r.nextIndex[peer]
+	if next <= r.log[0].Index {
+		r.mu.Unlock()
+		return r.sendSnapshot(peer, term)
+	}
+
+	entries := make([]Command, r.lastLogIndex()-next+1)
+	copy(entries, r.log[next-r.log[0].Index:])
+
+	req := AppendRequest{
+		Term:         term,
+		LeaderId:     r.id,
+		PrevLogIndex: next - 1,
+		PrevLogTerm:  r.entry(next - 1).Term,
+		Entries:      entries,
+		LeaderCommit: r.commitIndex,
+	}
+	r.mu.Unlock()
+
+	var resp AppendResponse
+	if err := call(peer, "/raft/append", req, &resp); err != nil {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if resp.Term > r.currentTerm {
+		r.stepDown(resp.Term)
+		return false
+	}
+
+	if r.role != Leader || r.currentTerm != term {
+		return false
+	}
+
+	if resp.Success {
+		r.matchIndex[peer] = max(r.matchIndex[peer], req.PrevLogIndex+len(entries))
+		r.nextIndex[peer] = r.matchIndex[peer] + 1
+		r.advanceCommitIndex()
+	} else {
+		r.nextIndex[peer] = max(r.log[0].Index+1, min(resp.ConflictIndex, next-1))
+		r.triggerReplicationTo(peer)
+	}
+
+	return true
+}
+
+func (r *Raft) sendSnapshot(peer string, term int) bool {
+	req := InstallSnapshotRequest{
+		Term:     term,
+		LeaderId: r.id,
+		Snapshot: r.Store.Snapshot(),
+	}
+
+	var resp InstallSnapshotResponse
+	if err := call(peer, "/raft/snapshot", req, &resp); err != nil {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if resp.Term > r.currentTerm {
+		r.stepDown(resp.Term)
+		return false
+	}
+
+	if r.role != Leader || r.currentTerm != term {
+		return false
+	}
+
+	r.matchIndex[peer] = max(r.matchIndex[peer], req.Snapshot.LastIndex)
+	r.nextIndex[peer] = r.matchIndex[peer] + 1
+	r.advanceCommitIndex()
+	return true
+}
+
+func (r *Raft) advanceCommitIndex() {
+	for n := r.lastLogIndex(); n > r.commitIndex; n-- {
+		if r.entry(n).Term != r.currentTerm {
+			break
+		}
+
+		replicas := 1
+		for _, peer := range r.peers {
+			if r.matchIndex[peer] >= n {
+				replicas++
+			}
+		}
+
+		if r.hasQuorum(replicas) {
+			r.commitIndex = n
+			r.triggerApply()
+			return
+		}
+	}
+}
+
+func (r *Raft) applyCommitted() {
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.apply:
+		}
+
+		for {
+			r.mu.Lock()
+			if r.lastApplied >= r.commitIndex {
+				r.compactLog()
+				r.mu.Unlock()
+				break
+			}
+
+			r.lastApplied++
+			cmd := r.entry(r.lastApplied)
+			pending, ok := r.pending[cmd.Index]
+			delete(r.pending, cmd.Index)
+			r.mu.Unlock()
+
+			_, err := r.Store.CommitChange(cmd)
+
+			if r.Store.LastIndex() < cmd.Index {
+				r.retryApply(cmd.Index, pending, ok)
+				break
+			}
+
+			if !ok {
+				continue
+			}
+
+			if pending.term != cmd.Term {
+				err = ErrNotLeader
+			}
+			pending.done <- err
+		}
+	}
+}
+
+// retryApply rolls lastApplied back when the store failed to persist the
+// entry, skipping it would make the store diverge from the rest of the log
+func (r *Raft) retryApply(index int, pending pendingCommit, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lastApplied != index {
+		return
+	}
+
+	r.lastApplied = index - 1
+	if ok {
+		r.pending[index] = pending
+	}
+	time.AfterFunc(RaftHeartbeatInterval, r.triggerApply)
+}
+
+// compactLog drops the entries that the store has already compacted,
+// peers that need them are sent a snapshot instead
+func (r *Raft) compactLog() {
+	index := min(r.lastApplied, r.Store.FirstIndex()-1)
+	if index <= r.log[0].Index {
+		return
+	}
+
+	r.log = append(make([]Command, 0, r.lastLogIndex()-index+1), r.log[index-r.log[0].Index:]...)
+
+	// NOTE(SergeyCherepiuk): Failing to rewrite only leaves the stale entries
+	// on disk, they are skipped on recovery anyway
+	r.storage.ResetEntries(r.log[r.lastApplied-r.log[0].Index+1:])
+}
+
+func (r *Raft) hasQuorum(votes int) bool {
+	return votes > (len(r.peers)+1)/2
+}
+
+func (r *Raft) entry(index int) Command {
+	return r.log[index-r.log[0].Index]
+}
+
+func (r *Raft) lastLogIndex() int {
+	return r.log[len(r.log)-1].Index
+}
+
+func (r *Raft) lastLogTerm() int {
+	return r.log[len(r.log)-1].Term
+}
+
+func (r *Raft) resetElectionTimer() {
+	select {
+	case r.resetElection <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Raft) triggerApply() {
+	select {
+	case r.apply <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Raft) triggerReplication() {
+	for _, peer := range r.peers {
+		r.triggerReplicationTo(peer)
+	}
+}
+
+func (r *Raft) triggerReplicationTo(peer string) {
+	select {
+	case r.replicate[peer] <- struct{}{}:
+	default:
+	}
+}
+
+func call(peer, endpoint string, req, resp any) error {
+	r, err := httpclient.PostWithTimeout(peer, endpoint, req, RaftRequestTimeout)
+	if err != nil {
+		return err
+	}
+
+	if r.StatusCode != http.StatusOK {
+		return errors.New(httpinternal.ErrorMessage(r.Body))
+	}
+
+	return httpinternal.Body(r, resp)
+}
+
+func forward(leader string, cmd Command) error {
+	resp, err := httpclient.PostWithTimeout(leader, "/raft/forward", cmd, RaftCommitTimeout)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return knownError(httpinternal.ErrorMessage(resp.Body))
+	}
+
+	return nil
+}
+
+func knownError(message string) error {
+	known := []error{
+		ErrLogOutOfSync, ErrWorkerNotFound, ErrTaskNotFound, ErrUnknownCommand,
+		ErrNotLeader, ErrNoLeader, ErrCommitTimeout, ErrConditionFailed,
+		ErrResourceNotFound, ErrUnsupportedVersion,
+	}
+
+	for _, err := range known {
+		if err.Error() == message {
+			return err
+		}
+	}
+	return errors.New(message)
+}
@@ -0,0 +1,50 @@
+package consensus
+
+// HardState is the part of the raft state that has to survive a restart
+// before any vote or append is acknowledged
+type HardState struct {
+	Term     int
+	VotedFor string
+}
+
+// RaftStorage persists the hard state and the entries that raft appends to
+// its log, entries that are applied to the store are kept by the store itself
+type RaftStorage interface {
+	HardState() HardState
+	SaveHardState(state HardState) error
+
+	// Entries returns the entries that were appended before the restart
+	Entries() []Command
+	// AppendEntries appends entries to the log, replacing every entry at the
+	// index of entries[0] and beyond
+	AppendEntries(entries []Command) error
+	// ResetEntries replaces the whole log with entries
+	ResetEntries(entries []Command) error
+}
+
+// NOTE(SergeyCherepiuk): Stores that keep nothing on disk have nothing to
+// recover either, so raft falls back to keeping its state in memory only
+type volatileRaftStorage struct {
+	state HardState
+}
+
+func (s *volatileRaftStorage) HardState() HardState {
+	return s.state
+}
+
+func (s *volatileRaftStorage) SaveHardState(state HardState) error {
+	s.state = state
+	return nil
+}
+
+func (s *volatileRaftStorage) Entries() []Command {
+	return nil
+}
+
+func (s *volatileRaftStorage) AppendEntries([]Command) error {
+	return nil
+}
+
+func (s *volatileRaftStorage) ResetEntries([]Command) error {
+	return nil
+}
@@ -0,0 +1,309 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	testTimeout = 10 * time.Second
+	testKind    = "test"
+)
+
+func newTestCommand(id string, value int) Command {
+	data, _ := json.Marshal(value)
+	return *NewSetResourceCommand(testKind, id, data)
+}
+
+func openDurableStore(t *testing.T, dir string) *durableStore {
+	t.Helper()
+
+	ds, err := NewDurableStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ds.wal.Close()
+		ds.raftWal.Close()
+	})
+	return ds
+}
+
+type testNode struct {
+	raft   *Raft
+	dir    string
+	server *httptest.Server
+}
+
+// NOTE(SergeyCherepiuk): Mirrors the raft endpoints of the manager, so the
+// nodes talk to each other the same way the managers do
+func (n *testNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var resp any
+	switch r.URL.Path {
+	case "/raft/vote":
+		var req VoteRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		resp = n.raft.RequestVote(req)
+	case "/raft/append":
+		var req AppendRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		resp = n.raft.AppendEntries(req)
+	case "/raft/snapshot":
+		var req InstallSnapshotRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		resp = n.raft.InstallSnapshot(req)
+	case "/raft/forward":
+		var cmd Command
+		json.NewDecoder(r.Body).Decode(&cmd)
+		if _, err := n.raft.CommitChange(cmd); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"Message": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		return
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (n *testNode) stop() {
+	n.raft.Stop()
+	n.server.CloseClientConnections()
+	n.server.Close()
+}
+
+func startCluster(t *testing.T, size int) []*testNode {
+	t.Helper()
+
+	nodes := make([]*testNode, size)
+	addrs := make([]string, size)
+	for i := range nodes {
+		nodes[i] = &testNode{dir: t.TempDir()}
+		nodes[i].server = httptest.NewUnstartedServer(nodes[i])
+		addrs[i] = nodes[i].server.Listener.Addr().String()
+	}
+
+	for i, n := range nodes {
+		peers := slices.Delete(slices.Clone(addrs), i, i+1)
+		n.raft = NewRaft(addrs[i], peers, openDurableStore(t, n.dir))
+		n.server.Start()
+		n.raft.Start()
+		t.Cleanup(n.stop)
+	}
+	return nodes
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met in time")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func waitForLeader(t *testing.T, nodes []*testNode) *testNode {
+	t.Helper()
+
+	var leader *testNode
+	waitFor(t, func() bool {
+		leaders := 0
+		for _, n := range nodes {
+			if n.raft.IsLeader() {
+				leader = n
+				leaders++
+			}
+		}
+		return leaders == 1
+	})
+	return leader
+}
+
+func (r *Raft) term() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.currentTerm
+}
+
+func hasResource(s Store, id string) bool {
+	_, err := s.GetResource(testKind, id)
+	return err == nil
+}
+
+func TestRaftReplicatesAndSurvivesLeaderLoss(t *testing.T) {
+	nodes := startCluster(t, 3)
+	leader := waitForLeader(t, nodes)
+
+	var follower *testNode
+	for _, n := range nodes {
+		if n != leader {
+			follower = n
+		}
+	}
+
+	// NOTE(SergeyCherepiuk): Followers forward the commands to the leader,
+	// once they have heard from it
+	waitFor(t, func() bool { return follower.raft.Leader() == leader.raft.Id() })
+	if _, err := follower.raft.CommitChange(newTestCommand("first", 1)); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		for _, n := range nodes {
+			if !hasResource(n.raft, "first") {
+				return false
+			}
+		}
+		return true
+	})
+
+	term := leader.raft.term()
+	leader.stop()
+
+	remaining := slices.DeleteFunc(slices.Clone(nodes), func(n *testNode) bool { return n == leader })
+	next := waitForLeader(t, remaining)
+	if next.raft.term() <= term {
+		t.Fatalf("new leader is elected in term %d, not after %d", next.raft.term(), term)
+	}
+
+	if _, err := next.raft.CommitChange(newTestCommand("second", 2)); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		return hasResource(remaining[0].raft, "second") && hasResource(remaining[1].raft, "second")
+	})
+}
+
+func TestRaftRestartsFromDisk(t *testing.T) {
+	dir := t.TempDir()
+
+	r := NewRaft("self", nil, openDurableStore(t, dir))
+	r.Start()
+	waitFor(t, r.IsLeader)
+	if _, err := r.CommitChange(newTestCommand("0", 0)); err != nil {
+		t.Fatal(err)
+	}
+	term := r.term()
+	r.Stop()
+
+	restarted := NewRaft("self", nil, openDurableStore(t, dir))
+	if restarted.term() != term {
+		t.Fatalf("term %d is restored instead of %d", restarted.term(), term)
+	}
+	if !hasResource(restarted, "0") {
+		t.Fatal("committed command is lost")
+	}
+
+	restarted.Start()
+	defer restarted.Stop()
+	waitFor(t, restarted.IsLeader)
+	if restarted.term() <= term {
+		t.Fatalf("term %d is reused", restarted.term())
+	}
+}
+
+func TestRaftVotesOncePerTermAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	peers := []string{"a", "b"}
+
+	r := NewRaft("self", peers, openDurableStore(t, dir))
+	if resp := r.RequestVote(VoteRequest{Term: 5, CandidateId: "a"}); !resp.VoteGranted {
+		t.Fatal("vote is not granted to the first candidate")
+	}
+
+	restarted := NewRaft("self", peers, openDurableStore(t, dir))
+	if resp := restarted.RequestVote(VoteRequest{Term: 5, CandidateId: "b"}); resp.VoteGranted {
+		t.Fatal("vote is granted twice in the same term")
+	}
+	if resp := restarted.RequestVote(VoteRequest{Term: 5, CandidateId: "a"}); !resp.VoteGranted {
+		t.Fatal("vote is not granted again to the same candidate")
+	}
+	if resp := restarted.RequestVote(VoteRequest{Term: 4, CandidateId: "b"}); resp.VoteGranted || resp.Term != 5 {
+		t.Fatalf("stale candidate is answered with %+v", resp)
+	}
+}
+
+func TestRaftVotesOnlyForUpToDateCandidates(t *testing.T) {
+	r := NewRaft("self", []string{"a", "b"}, openDurableStore(t, t.TempDir()))
+
+	entries := []Command{{Index: 1, Term: 1, Type: Noop}, {Index: 2, Term: 2, Type: Noop}}
+	if resp := r.AppendEntries(AppendRequest{Term: 2, LeaderId: "a", Entries: entries}); !resp.Success {
+		t.Fatal("entries are not appended")
+	}
+
+	tests := []struct {
+		lastIndex, lastTerm int
+		granted             bool
+	}{
+		{lastIndex: 5, lastTerm: 1, granted: false},
+		{lastIndex: 1, lastTerm: 2, granted: false},
+		{lastIndex: 2, lastTerm: 2, granted: true},
+	}
+
+	for i, test := range tests {
+		req := VoteRequest{Term: 3 + i, CandidateId: "b", LastLogIndex: test.lastIndex, LastLogTerm: test.lastTerm}
+		if resp := r.RequestVote(req); resp.VoteGranted != test.granted {
+			t.Errorf("vote for the log at %d in term %d is granted: %v", test.lastIndex, test.lastTerm, resp.VoteGranted)
+		}
+	}
+}
+
+func TestRaftReplacesConflictingEntries(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRaft("self", []string{"a", "b"}, openDurableStore(t, dir))
+
+	entries := []Command{{Index: 1, Term: 1, Type: Noop}, {Index: 2, Term: 1, Type: Noop}, {Index: 3, Term: 1, Type: Noop}}
+	if resp := r.AppendEntries(AppendRequest{Term: 1, LeaderId: "a", Entries: entries}); !resp.Success {
+		t.Fatal("entries are not appended")
+	}
+
+	mismatch := AppendRequest{Term: 2, LeaderId: "b", PrevLogIndex: 3, PrevLogTerm: 2}
+	if resp := r.AppendEntries(mismatch); resp.Success || resp.ConflictIndex != 1 {
+		t.Fatalf("mismatching entry is answered with %+v", resp)
+	}
+
+	replacing := AppendRequest{
+		Term:         2,
+		LeaderId:     "b",
+		PrevLogIndex: 1,
+		PrevLogTerm:  1,
+		Entries:      []Command{{Index: 2, Term: 2, Type: Noop}},
+	}
+	if resp := r.AppendEntries(replacing); !resp.Success {
+		t.Fatal("conflicting entries are not replaced")
+	}
+
+	want := []Command{{Index: 1, Term: 1}, {Index: 2, Term: 2}}
+	if got := r.log[1:]; !slices.EqualFunc(got, want, sameEntry) {
+		t.Fatalf("log is %+v instead of %+v", got, want)
+	}
+
+	restarted := NewRaft("self", []string{"a", "b"}, openDurableStore(t, dir))
+	if got := restarted.log[1:]; !slices.EqualFunc(got, want, sameEntry) {
+		t.Fatalf("log is restored as %+v instead of %+v", got, want)
+	}
+	if restarted.term() != 2 {
+		t.Fatalf("term %d is restored instead of 2", restarted.term())
+	}
+}
+
+func TestRaftRejectsCommitsOnFollowers(t *testing.T) {
+	r := NewRaft("self", []string{"a", "b"}, openDurableStore(t, t.TempDir()))
+	if _, err := r.CommitChange(newTestCommand("0", 0)); !errors.Is(err, ErrNoLeader) {
+		t.Fatalf("expected %v, got %v", ErrNoLeader, err)
+	}
+}
+
+func sameEntry(a, b Command) bool {
+	return a.Index == b.Index && a.Term == b.Term
+}
@@ -0,0 +1,92 @@
+package consensus
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Redaction strips the secret parts of a resource, so it can be shown or
+// handed to the nodes that don't need them
+type Redaction func(resource json.RawMessage) json.RawMessage
+
+var (
+	muRedactions sync.RWMutex
+	redactions   = make(map[string]Redaction)
+)
+
+// RegisterRedaction makes the resources of the kind redacted wherever they
+// leave the managers: in the decoded log, in the dump, in the watch events and
+// in the commands and snapshots replicated to workers. It panics if the kind
+// is registered twice.
+func RegisterRedaction(kind string, redact Redaction) {
+	muRedactions.Lock()
+	defer muRedactions.Unlock()
+
+	if _, ok := redactions[kind]; ok {
+		panic(fmt.Sprintf("consensus: redaction of %q is registered twice", kind))
+	}
+	redactions[kind] = redact
+}
+
+func redaction(kind string) (Redaction, bool) {
+	muRedactions.RLock()
+	defer muRedactions.RUnlock()
+
+	redact, ok := redactions[kind]
+	return redact, ok
+}
+
+// RedactCommand returns the command with the resources it sets redacted,
+// index and term are left as they are
+func RedactCommand(cmd Command) Command {
+	switch cmd.Type {
+	case SetResource:
+		var data SetResourceCommandData
+		if err := json.Unmarshal(cmd.Data, &data); err != nil {
+			return cmd
+		}
+
+		redact, ok := redaction(data.Kind)
+		if !ok {
+			return cmd
+		}
+
+		data.Resource = redact(data.Resource)
+		cmd.Data, _ = json.Marshal(data)
+	case Batch:
+		var data BatchCommandData
+		if err := json.Unmarshal(cmd.Data, &data); err != nil {
+			return cmd
+		}
+
+		for i, c := range data.Commands {
+			data.Commands[i] = RedactCommand(c)
+		}
+		cmd.Data, _ = json.Marshal(data)
+	}
+	return cmd
+}
+
+func RedactCommands(cmds []Command) []Command {
+	redacted := make([]Command, 0, len(cmds))
+	for _, cmd := range cmds {
+		redacted = append(redacted, RedactCommand(cmd))
+	}
+	return redacted
+}
+
+// RedactResources redacts the resources in place, the map must not be shared
+// with the state of the store
+func RedactResources(resources map[string]map[string]json.RawMessage) {
+	for kind, byId := range resources {
+		redact, ok := redaction(kind)
+		if !ok {
+			continue
+		}
+
+		for id, resource := range byId {
+			byId[id] = redact(resource)
+		}
+	}
+}
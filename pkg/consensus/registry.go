@@ -0,0 +1,100 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrUnsupportedVersion = errors.New("command version is not supported")
+
+// Upgrade converts the data of a command from one version to the next one
+type Upgrade func(data []byte) ([]byte, error)
+
+type commandSpec struct {
+	version  int
+	apply    func(state *State, data []byte) ([]Change, error)
+	upgrades []Upgrade
+}
+
+var (
+	muRegistry sync.RWMutex
+	registry   = make(map[CommandType]commandSpec)
+)
+
+// RegisterCommand makes the command type known to every store. Version
+// starts at 1 and upgrades[i] converts the data of version i+1 to i+2,
+// so the log entries written by older versions can still be applied.
+// It panics if the type is registered twice or the upgrades are missing.
+func RegisterCommand[T any](
+	cmdType CommandType,
+	version int,
+	apply func(state *State, data T) ([]Change, error),
+	upgrades ...Upgrade,
+) {
+	if version != len(upgrades)+1 {
+		panic(fmt.Sprintf("consensus: command %q of version %d needs %d upgrades", cmdType, version, version-1))
+	}
+
+	muRegistry.Lock()
+	defer muRegistry.Unlock()
+
+	if _, ok := registry[cmdType]; ok {
+		panic(fmt.Sprintf("consensus: command %q is registered twice", cmdType))
+	}
+
+	registry[cmdType] = commandSpec{
+		version:  version,
+		upgrades: upgrades,
+		apply: func(state *State, data []byte) ([]Change, error) {
+			var unmarshaled T
+			if len(data) > 0 {
+				if err := json.Unmarshal(data, &unmarshaled); err != nil {
+					return nil, err
+				}
+			}
+			return apply(state, unmarshaled)
+		},
+	}
+}
+
+func NewCommand[T any](cmdType CommandType, data T) *Command {
+	marshaled, _ := json.Marshal(data)
+	return &Command{Type: cmdType, Version: commandVersion(cmdType), Data: marshaled}
+}
+
+func commandVersion(cmdType CommandType) int {
+	muRegistry.RLock()
+	defer muRegistry.RUnlock()
+
+	if spec, ok := registry[cmdType]; ok {
+		return spec.version
+	}
+	return 1
+}
+
+func apply(state *State, cmd Command) ([]Change, error) {
+	muRegistry.RLock()
+	spec, ok := registry[cmd.Type]
+	muRegistry.RUnlock()
+
+	if !ok {
+		return nil, ErrUnknownCommand
+	}
+
+	version := max(cmd.Version, 1) // NOTE(SergeyCherepiuk): Commands written before versioning have none
+	if version > spec.version {
+		return nil, ErrUnsupportedVersion
+	}
+
+	data := cmd.Data
+	for ; version < spec.version; version++ {
+		var err error
+		if data, err = spec.upgrades[version-1](data); err != nil {
+			return nil, err
+		}
+	}
+
+	return spec.apply(state, data)
+}
@@ -0,0 +1,71 @@
+package consensus
+
+type Snapshot struct {
+	LastIndex int
+	LastTerm  int
+	State     State
+}
+
+func (s *store) Snapshot() Snapshot {
+	s.muCommit.Lock()
+	defer s.muCommit.Unlock()
+
+	s.muState.RLock()
+	defer s.muState.RUnlock()
+
+	return Snapshot{
+		LastIndex: s.LastIndex(),
+		LastTerm:  s.LastTerm(),
+		State:     copyState(s.state),
+	}
+}
+
+func (s *store) Restore(snapshot Snapshot) error {
+	s.muCommit.Lock()
+	defer s.muCommit.Unlock()
+
+	state := copyState(snapshot.State)
+
+	s.muState.Lock()
+	s.state = state
+	s.muState.Unlock()
+
+	s.muLog.Lock()
+	s.log = make([]Command, 0)
+	s.compactedIndex = snapshot.LastIndex
+	s.compactedTerm = snapshot.LastTerm
+	s.muLog.Unlock()
+
+	s.watchHub.reset()
+	return nil
+}
+
+// restoreLog takes the last compacted entry followed by the log itself
+func (s *store) restoreLog(cmds []Command) {
+	s.muLog.Lock()
+	defer s.muLog.Unlock()
+
+	s.log = append(make([]Command, 0, len(cmds)-1), cmds[1:]...)
+	s.compactedIndex = cmds[0].Index
+	s.compactedTerm = cmds[0].Term
+}
+
+func (s *store) compactedEntry() Command {
+	s.muLog.RLock()
+	defer s.muLog.RUnlock()
+	return Command{Index: s.compactedIndex, Term: s.compactedTerm, Type: Noop}
+}
+
+func (s *store) compact(index int) {
+	s.muLog.Lock()
+	defer s.muLog.Unlock()
+
+	n := min(index-s.compactedIndex, len(s.log))
+	if n <= 0 {
+		return
+	}
+
+	s.compactedIndex = s.log[n-1].Index
+	s.compactedTerm = s.log[n-1].Term
+	s.log = append(make([]Command, 0, len(s.log)-n), s.log[n:]...)
+}
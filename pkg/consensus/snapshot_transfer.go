@@ -0,0 +1,120 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"sync"
+)
+
+var (
+	ErrSnapshotOutOfOrder = errors.New("snapshot chunk is out of order")
+	ErrSnapshotCorrupted  = errors.New("snapshot is corrupted")
+	ErrSnapshotMismatch   = errors.New("snapshot does not match its index and term")
+)
+
+type SnapshotChunk struct {
+	LastIndex int
+	LastTerm  int
+	Offset    int
+	Data      []byte
+	Done      bool
+	Checksum  uint32
+	Tail      []Command
+}
+
+// SplitSnapshot encodes the snapshot and cuts it into chunks of at most
+// size bytes, the last chunk carries the checksum and the commands that
+// were committed after the snapshot had been taken.
+func SplitSnapshot(snapshot Snapshot, tail []Command, size int) ([]SnapshotChunk, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]SnapshotChunk, 0, len(data)/size+1)
+	for offset := 0; offset < len(data) || offset == 0; offset += size {
+		end := min(offset+size, len(data))
+		chunks = append(chunks, SnapshotChunk{
+			LastIndex: snapshot.LastIndex,
+			LastTerm:  snapshot.LastTerm,
+			Offset:    offset,
+			Data:      data[offset:end],
+		})
+	}
+
+	last := &chunks[len(chunks)-1]
+	last.Done = true
+	last.Checksum = crc32.ChecksumIEEE(data)
+	last.Tail = tail
+	return chunks, nil
+}
+
+type SnapshotReceiver struct {
+	mu        sync.Mutex
+	lastIndex int
+	lastTerm  int
+	buf       bytes.Buffer
+}
+
+func NewSnapshotReceiver() *SnapshotReceiver {
+	return &SnapshotReceiver{}
+}
+
+// Receive buffers the chunk and, once the final one arrives, validates the
+// transfer and installs the snapshot followed by its tail into the store
+func (sr *SnapshotReceiver) Receive(store Store, chunk SnapshotChunk) (done bool, err error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if chunk.Offset == 0 {
+		sr.lastIndex, sr.lastTerm = chunk.LastIndex, chunk.LastTerm
+		sr.buf.Reset()
+	}
+
+	transferMismatch := chunk.LastIndex != sr.lastIndex || chunk.LastTerm != sr.lastTerm
+	if transferMismatch || chunk.Offset != sr.buf.Len() {
+		return false, ErrSnapshotOutOfOrder
+	}
+
+	sr.buf.Write(chunk.Data)
+	if !chunk.Done {
+		return false, nil
+	}
+
+	defer sr.buf.Reset()
+
+	if crc32.ChecksumIEEE(sr.buf.Bytes()) != chunk.Checksum {
+		return false, ErrSnapshotCorrupted
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(sr.buf.Bytes(), &snapshot); err != nil {
+		return false, errors.Join(ErrSnapshotCorrupted, err)
+	}
+
+	if snapshot.LastIndex != chunk.LastIndex || snapshot.LastTerm != chunk.LastTerm {
+		return false, ErrSnapshotMismatch
+	}
+
+	for i, cmd := range chunk.Tail {
+		if cmd.Index != snapshot.LastIndex+1+i {
+			return false, ErrSnapshotMismatch
+		}
+	}
+
+	if snapshot.LastIndex > store.LastIndex() {
+		if err := store.Restore(snapshot); err != nil {
+			return false, err
+		}
+	}
+
+	for _, cmd := range chunk.Tail {
+		if _, err := store.CommitChange(cmd); errors.Is(err, ErrLogOutOfSync) {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,104 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/google/uuid"
+	"golang.org/x/exp/maps"
+)
+
+var ErrResourceNotFound = errors.New("resource is not found")
+
+// State is what the commands are applied to. Workers and their tasks are
+// built in, everything else lives in Resources, grouped by kind and id
+type State struct {
+	Workers   map[uuid.UUID]Worker
+	Resources map[string]map[string]json.RawMessage
+}
+
+func NewState() State {
+	return State{
+		Workers:   make(map[uuid.UUID]Worker),
+		Resources: make(map[string]map[string]json.RawMessage),
+	}
+}
+
+func (s *State) Resource(kind, id string) (json.RawMessage, error) {
+	if resource, ok := s.Resources[kind][id]; ok {
+		return resource, nil
+	}
+	return nil, ErrResourceNotFound
+}
+
+func (s *State) SetResource(kind, id string, resource any) (Change, error) {
+	marshaled, err := json.Marshal(resource)
+	if err != nil {
+		return Change{}, err
+	}
+
+	if s.Resources[kind] == nil {
+		s.Resources[kind] = make(map[string]json.RawMessage)
+	}
+	s.Resources[kind][id] = marshaled
+
+	return Change{Type: ResourceSet, Kind: kind, ResourceId: id}, nil
+}
+
+func (s *State) RemoveResource(kind, id string) (Change, error) {
+	if _, ok := s.Resources[kind][id]; !ok {
+		return Change{}, ErrResourceNotFound
+	}
+
+	delete(s.Resources[kind], id)
+	return Change{Type: ResourceRemoved, Kind: kind, ResourceId: id}, nil
+}
+
+func copyState(state State) State {
+	c := NewState()
+	for id, worker := range state.Workers {
+		if worker.MuTasks != nil {
+			worker.MuTasks.RLock()
+		}
+
+		tasks := make(map[uuid.UUID]task.Task, len(worker.Tasks))
+		maps.Copy(tasks, worker.Tasks)
+
+		if worker.MuTasks != nil {
+			worker.MuTasks.RUnlock()
+		}
+
+		c.Workers[id] = Worker{Addr: worker.Addr, MuTasks: &sync.RWMutex{}, Tasks: tasks}
+	}
+
+	for kind, resources := range state.Resources {
+		c.Resources[kind] = maps.Clone(resources)
+	}
+	return c
+}
+
+func GetResource[T any](s Store, kind, id string) (T, error) {
+	var resource T
+	data, err := s.GetResource(kind, id)
+	if err != nil {
+		return resource, err
+	}
+
+	err = json.Unmarshal(data, &resource)
+	return resource, err
+}
+
+func AllResources[T any](s Store, kind string) (map[string]T, error) {
+	all := s.AllResources(kind)
+	resources := make(map[string]T, len(all))
+	for id, data := range all {
+		var resource T
+		if err := json.Unmarshal(data, &resource); err != nil {
+			return nil, err
+		}
+		resources[id] = resource
+	}
+	return resources, nil
+}
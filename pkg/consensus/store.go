@@ -8,6 +8,7 @@ import (
 	"github.com/SergeyCherepiuk/fleet/pkg/node"
 	"github.com/SergeyCherepiuk/fleet/pkg/task"
 	"github.com/google/uuid"
+	"golang.org/x/exp/maps"
 )
 
 type Store interface {
@@ -16,33 +17,55 @@ type Store interface {
 	GetWorker(worketId uuid.UUID) (Worker, error)
 	GetWorkerByTaskId(taskId uuid.UUID) (uuid.UUID, Worker, error)
 	GetLastNCommands(n int) []Command
+	GetCommandsAfter(index int) ([]Command, error)
 
 	LogSize() int
 	WorkersNumber() int
 
+	FirstIndex() int
 	LastIndex() int
+	LastTerm() int
 	CommitChange(cmd Command) (off int, err error)
+
+	Snapshot() Snapshot
+	Restore(snapshot Snapshot) error
+
+	GetResource(kind, id string) (json.RawMessage, error)
+	AllResources(kind string) map[string]json.RawMessage
+
+	Watch(fromIndex int, filter WatchFilter) (*Watcher, error)
 }
 
 var (
-	ErrLogOutOfSync   = errors.New("log is out of sync")
-	ErrWorkerNotFound = errors.New("worker is not found")
-	ErrTaskNotFound   = errors.New("task is not found")
-	ErrUnknownCommand = errors.New("unknown command")
+	ErrLogOutOfSync    = errors.New("log is out of sync")
+	ErrWorkerNotFound  = errors.New("worker is not found")
+	ErrTaskNotFound    = errors.New("task is not found")
+	ErrUnknownCommand  = errors.New("unknown command")
+	ErrNotLeader       = errors.New("node is not a leader")
+	ErrNoLeader        = errors.New("leader is unknown")
+	ErrCommitTimeout   = errors.New("command is not committed in time")
+	ErrConditionFailed = errors.New("condition of the command does not hold")
 )
 
 type store struct {
+	muCommit sync.Mutex
+
 	muState sync.RWMutex
-	state   map[uuid.UUID]Worker
+	state   State
+
+	muLog          sync.RWMutex
+	log            []Command
+	compactedIndex int
+	compactedTerm  int
 
-	muLog sync.RWMutex
-	log   []Command
+	watchHub *watchHub
 }
 
 func NewLocalStore() *store {
 	return &store{
-		state: make(map[uuid.UUID]Worker),
-		log:   make([]Command, 0),
+		state:    NewState(),
+		log:      make([]Command, 0),
+		watchHub: newWatchHub(),
 	}
 }
 
@@ -56,8 +79,8 @@ func (s *store) AllWorkers() map[uuid.UUID]Worker {
 	s.muState.RLock()
 	defer s.muState.RUnlock()
 
-	c := make(map[uuid.UUID]Worker, len(s.state))
-	for k, v := range s.state {
+	c := make(map[uuid.UUID]Worker, len(s.state.Workers))
+	for k, v := range s.state.Workers {
 		c[k] = v
 	}
 	return c
@@ -67,7 +90,7 @@ func (s *store) GetTask(tid uuid.UUID) (task.Task, error) {
 	s.muState.RLock()
 	defer s.muState.RUnlock()
 
-	for _, worker := range s.state {
+	for _, worker := range s.state.Workers {
 		if task, ok := worker.Tasks[tid]; ok {
 			return task, nil
 		}
@@ -79,7 +102,7 @@ func (s *store) GetWorker(wid uuid.UUID) (Worker, error) {
 	s.muState.RLock()
 	defer s.muState.RUnlock()
 
-	if worker, ok := s.state[wid]; ok {
+	if worker, ok := s.state.Workers[wid]; ok {
 		return worker, nil
 	}
 	return Worker{}, ErrWorkerNotFound
@@ -89,7 +112,7 @@ func (s *store) GetWorkerByTaskId(tid uuid.UUID) (uuid.UUID, Worker, error) {
 	s.muState.RLock()
 	defer s.muState.RUnlock()
 
-	for id, worker := range s.state {
+	for id, worker := range s.state.Workers {
 		if _, ok := worker.Tasks[tid]; ok {
 			return id, worker, nil
 		}
@@ -97,6 +120,18 @@ func (s *store) GetWorkerByTaskId(tid uuid.UUID) (uuid.UUID, Worker, error) {
 	return uuid.Nil, Worker{}, ErrWorkerNotFound
 }
 
+func (s *store) GetResource(kind, id string) (json.RawMessage, error) {
+	s.muState.RLock()
+	defer s.muState.RUnlock()
+	return s.state.Resource(kind, id)
+}
+
+func (s *store) AllResources(kind string) map[string]json.RawMessage {
+	s.muState.RLock()
+	defer s.muState.RUnlock()
+	return maps.Clone(s.state.Resources[kind])
+}
+
 func (s *store) GetLastNCommands(n int) []Command {
 	s.muLog.RLock()
 	defer s.muLog.RUnlock()
@@ -107,6 +142,22 @@ func (s *store) GetLastNCommands(n int) []Command {
 	return c
 }
 
+// GetCommandsAfter returns every command past the index, read at once so
+// that the result is contiguous even while new commands keep coming
+func (s *store) GetCommandsAfter(index int) ([]Command, error) {
+	s.muLog.RLock()
+	defer s.muLog.RUnlock()
+
+	if index < s.compactedIndex {
+		return nil, ErrLogOutOfSync
+	}
+
+	n := max(len(s.log)-(index-s.compactedIndex), 0)
+	c := make([]Command, n)
+	copy(c, s.log[len(s.log)-n:])
+	return c, nil
+}
+
 func (s *store) LogSize() int {
 	s.muLog.RLock()
 	defer s.muLog.RUnlock()
@@ -116,122 +167,64 @@ func (s *store) LogSize() int {
 func (s *store) WorkersNumber() int {
 	s.muState.RLock()
 	defer s.muState.RUnlock()
-	return len(s.state)
+	return len(s.state.Workers)
 }
 
-func (s *store) LastIndex() int {
-	if s.LogSize() == 0 {
-		return 0
-	}
-
+func (s *store) FirstIndex() int {
 	s.muLog.RLock()
 	defer s.muLog.RUnlock()
-	return s.log[len(s.log)-1].Index
+	return s.compactedIndex + 1
 }
 
-func (s *store) CommitChange(cmd Command) (int, error) {
-	lastIndex := s.LastIndex()
-	diff := cmd.Index - 1 - lastIndex
-
-	if diff > 0 {
-		return diff, ErrLogOutOfSync
-	}
-
-	var err error
-	switch cmd.Type {
-	case SetWorker:
-		err = s.setWorker(cmd.Data)
-	case RemoveWorker:
-		err = s.removeWorker(cmd.Data)
-	case SetTask:
-		err = s.setTask(cmd.Data)
-	case RemoveTask:
-		err = s.removeTask(cmd.Data)
-	default:
-		err = ErrUnknownCommand
-	}
+func (s *store) LastIndex() int {
+	s.muLog.RLock()
+	defer s.muLog.RUnlock()
 
-	if err != nil {
-		return 0, err
+	if len(s.log) == 0 {
+		return s.compactedIndex
 	}
-
-	s.muLog.Lock()
-	defer s.muLog.Unlock()
-	s.log = append(s.log, cmd)
-	return 0, nil
+	return s.log[len(s.log)-1].Index
 }
 
-func (s *store) setWorker(data []byte) error {
-	var unmarshaled SetWorkerCommandData
-	if err := json.Unmarshal(data, &unmarshaled); err != nil {
-		return err
-	}
-
-	s.muState.Lock()
-	defer s.muState.Unlock()
+func (s *store) LastTerm() int {
+	s.muLog.RLock()
+	defer s.muLog.RUnlock()
 
-	s.state[unmarshaled.WorkerId] = Worker{
-		Addr:    unmarshaled.Worker.Addr,
-		MuTasks: &sync.RWMutex{},
-		Tasks:   make(map[uuid.UUID]task.Task),
+	if len(s.log) == 0 {
+		return s.compactedTerm
 	}
-	return nil
+	return s.log[len(s.log)-1].Term
 }
 
-func (s *store) removeWorker(data []byte) error {
-	var unmarshaled RemoveWorkerCommandData
-	if err := json.Unmarshal(data, &unmarshaled); err != nil {
-		return err
-	}
-
-	s.muState.Lock()
-	defer s.muState.Unlock()
-
-	if _, ok := s.state[unmarshaled.WorkerId]; !ok {
-		return ErrWorkerNotFound
-	}
-
-	delete(s.state, unmarshaled.WorkerId)
-	return nil
-}
+func (s *store) CommitChange(cmd Command) (int, error) {
+	s.muCommit.Lock()
+	defer s.muCommit.Unlock()
 
-func (s *store) setTask(data []byte) error {
-	var unmarshaled SetTaskCommandData
-	if err := json.Unmarshal(data, &unmarshaled); err != nil {
-		return err
+	lastIndex := s.LastIndex()
+	if cmd.Index == 0 {
+		cmd.Index = lastIndex + 1
 	}
 
-	s.muState.RLock()
-	defer s.muState.RUnlock()
+	diff := cmd.Index - 1 - lastIndex
 
-	worker, ok := s.state[unmarshaled.WorkerId]
-	if !ok {
-		return ErrWorkerNotFound
+	if diff > 0 {
+		return diff, ErrLogOutOfSync
 	}
 
-	worker.MuTasks.Lock()
-	s.state[unmarshaled.WorkerId].Tasks[unmarshaled.Task.Id] = unmarshaled.Task
-	worker.MuTasks.Unlock()
-
-	return nil
-}
-
-func (s *store) removeTask(data []byte) error {
-	var unmarshaled RemoveTaskCommandData
-	if err := json.Unmarshal(data, &unmarshaled); err != nil {
-		return err
+	if diff < 0 { // NOTE(SergeyCherepiuk): Command is already applied
+		return 0, nil
 	}
 
-	id, worker, err := s.GetWorkerByTaskId(unmarshaled.TaskId)
-	if err != nil {
-		return err
-	}
+	s.muState.Lock()
+	changes, err := apply(&s.state, cmd)
+	s.muState.Unlock()
 
-	s.muState.RLock()
-	worker.MuTasks.Lock()
-	delete(s.state[id].Tasks, unmarshaled.TaskId)
-	worker.MuTasks.Unlock()
-	s.muState.RUnlock()
+	// NOTE(SergeyCherepiuk): Command is logged even if it failed to apply,
+	// otherwise replicas that apply the same log would drift apart
+	s.muLog.Lock()
+	s.log = append(s.log, cmd)
+	s.muLog.Unlock()
 
-	return nil
+	s.watchHub.publish(WatchEvent{Command: cmd, Changes: changes})
+	return 0, err
 }
@@ -0,0 +1,169 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+const recordHeaderSize = 8
+
+var ErrCorruptedRecord = errors.New("record is corrupted")
+
+// Records are framed as [payload length][payload crc32][json payload],
+// both header fields being little-endian uint32.
+func writeRecord(w io.Writer, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	record = append(record, payload...)
+
+	_, err = w.Write(record)
+	return err
+}
+
+func readRecord(r io.Reader, v any) (int, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[0:4])
+	checksum := binary.LittleEndian.Uint32(header[4:8])
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return 0, ErrCorruptedRecord
+	}
+
+	if err := json.Unmarshal(payload, v); err != nil {
+		return 0, errors.Join(ErrCorruptedRecord, err)
+	}
+
+	return recordHeaderSize + int(size), nil
+}
+
+// readWal returns every intact command of the log along with the offset
+// right after the last of them, so that a torn tail can be truncated.
+func readWal(path string) ([]Command, int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make([]Command, 0), 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var (
+		cmds   = make([]Command, 0)
+		offset int64
+	)
+
+	for {
+		var cmd Command
+		n, err := readRecord(file, &cmd)
+		if err != nil {
+			break
+		}
+
+		cmds = append(cmds, cmd)
+		offset += int64(n)
+	}
+
+	return cmds, offset, nil
+}
+
+// appendRecords writes the commands at the end of the log and syncs it. A
+// failed write is truncated back, otherwise the partial record would hide
+// every record appended after it on recovery
+func appendRecords(wal *os.File, cmds ...Command) error {
+	info, err := wal.Stat()
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range cmds {
+		if err = writeRecord(wal, cmd); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = wal.Sync()
+	}
+
+	if err != nil {
+		// NOTE(SergeyCherepiuk): The log that can't be truncated is closed, so
+		// nothing is appended after the partial record
+		if terr := wal.Truncate(info.Size()); terr != nil {
+			wal.Close()
+			return errors.Join(err, terr)
+		}
+	}
+	return err
+}
+
+func writeWal(path string, cmds []Command) error {
+	return writeAtomically(path, func(w io.Writer) error {
+		for _, cmd := range cmds {
+			if err := writeRecord(w, cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func readSnapshot(path string) (Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer file.Close()
+
+	var snapshot Snapshot
+	_, err = readRecord(file, &snapshot)
+	return snapshot, err
+}
+
+func writeSnapshot(path string, snapshot Snapshot) error {
+	return writeAtomically(path, func(w io.Writer) error {
+		return writeRecord(w, snapshot)
+	})
+}
+
+func writeAtomically(path string, write func(io.Writer) error) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
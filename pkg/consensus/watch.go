@@ -0,0 +1,182 @@
+package consensus
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/google/uuid"
+)
+
+const (
+	WatchHistorySize = 1024
+	WatchBufferSize  = 256
+)
+
+var ErrWatchCompacted = errors.New("requested index is no longer available for watching")
+
+type ChangeType string
+
+const (
+	WorkerSet     ChangeType = "WorkerSet"
+	WorkerRemoved ChangeType = "WorkerRemoved"
+	TaskSet       ChangeType = "TaskSet"
+	TaskRemoved   ChangeType = "TaskRemoved"
+
+	ResourceSet     ChangeType = "ResourceSet"
+	ResourceRemoved ChangeType = "ResourceRemoved"
+)
+
+// Change is a decoded modification of the state, Task is nil for changes
+// of the workers and holds the last known task for removed ones. Kind and
+// ResourceId are set for changes of the registered resources only
+type Change struct {
+	Type       ChangeType
+	WorkerId   uuid.UUID
+	TaskId     uuid.UUID
+	Task       *task.Task
+	Kind       string
+	ResourceId string
+}
+
+type WatchEvent struct {
+	Command Command
+	Changes []Change
+}
+
+type WatchFilter struct {
+	WorkerId uuid.UUID
+	TaskId   uuid.UUID
+}
+
+func (f WatchFilter) match(event WatchEvent) (WatchEvent, bool) {
+	if f.WorkerId == uuid.Nil && f.TaskId == uuid.Nil {
+		return event, true
+	}
+
+	changes := make([]Change, 0, len(event.Changes))
+	for _, change := range event.Changes {
+		workerMatches := f.WorkerId == uuid.Nil || f.WorkerId == change.WorkerId
+		taskMatches := f.TaskId == uuid.Nil || f.TaskId == change.TaskId
+		if workerMatches && taskMatches {
+			changes = append(changes, change)
+		}
+	}
+
+	event.Changes = changes
+	return event, len(changes) > 0
+}
+
+type Watcher struct {
+	filter WatchFilter
+	events chan WatchEvent
+	once   sync.Once
+	hub    *watchHub
+}
+
+// Events is closed once the watcher is closed or it falls too far behind,
+// the consumer is then expected to re-read the state and watch again
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+func (w *Watcher) Close() {
+	w.hub.remove(w)
+}
+
+type watchHub struct {
+	mu       sync.Mutex
+	history  []WatchEvent
+	watchers map[*Watcher]struct{}
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{
+		history:  make([]WatchEvent, 0, WatchHistorySize),
+		watchers: make(map[*Watcher]struct{}),
+	}
+}
+
+func (h *watchHub) watch(fromIndex, lastIndex int, filter WatchFilter) (*Watcher, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	backlog := make([]WatchEvent, 0)
+	if fromIndex <= lastIndex {
+		if len(h.history) == 0 || h.history[0].Command.Index > fromIndex {
+			return nil, ErrWatchCompacted
+		}
+
+		for _, event := range h.history[fromIndex-h.history[0].Command.Index:] {
+			if event, ok := filter.match(event); ok {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	w := &Watcher{
+		filter: filter,
+		events: make(chan WatchEvent, len(backlog)+WatchBufferSize),
+		hub:    h,
+	}
+	for _, event := range backlog {
+		w.events <- event
+	}
+
+	h.watchers[w] = struct{}{}
+	return w, nil
+}
+
+func (h *watchHub) publish(event WatchEvent) {
+	event.Command = RedactCommand(event.Command)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.history) == WatchHistorySize {
+		h.history = append(h.history[:0], h.history[1:]...)
+	}
+	h.history = append(h.history, event)
+
+	for w := range h.watchers {
+		event, ok := w.filter.match(event)
+		if !ok {
+			continue
+		}
+
+		select {
+		case w.events <- event:
+		default:
+			h.close(w)
+		}
+	}
+}
+
+// reset drops the history and closes every watcher, since the state they
+// have been following is replaced as a whole (e.g. by a snapshot)
+func (h *watchHub) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.history = h.history[:0]
+	for w := range h.watchers {
+		h.close(w)
+	}
+}
+
+func (h *watchHub) remove(w *Watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.close(w)
+}
+
+func (h *watchHub) close(w *Watcher) {
+	delete(h.watchers, w)
+	w.once.Do(func() { close(w.events) })
+}
+
+func (s *store) Watch(fromIndex int, filter WatchFilter) (*Watcher, error) {
+	s.muCommit.Lock()
+	defer s.muCommit.Unlock()
+	return s.watchHub.watch(fromIndex, s.LastIndex(), filter)
+}
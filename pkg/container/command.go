@@ -0,0 +1,17 @@
+package container
+
+import "slices"
+
+// ProcessArgs resolves the command line of the container the same way
+// docker does: overriding the entrypoint drops the default command of the
+// image, args are appended to the command
+func (c Config) ProcessArgs(imageEntrypoint, imageCommand []string) (entrypoint, command []string) {
+	entrypoint, command = imageEntrypoint, imageCommand
+	if c.Entrypoint != nil {
+		entrypoint, command = c.Entrypoint, nil
+	}
+	if c.Command != nil {
+		command = c.Command
+	}
+	return entrypoint, append(slices.Clone(command), c.Args...)
+}
@@ -1,6 +1,8 @@
 package container
 
 import (
+	"time"
+
 	"github.com/SergeyCherepiuk/fleet/pkg/image"
 )
 
@@ -17,6 +19,26 @@ type Config struct {
 	Labels            Labels
 	RestartPolicy     RestartPolicy
 	RequiredResources RequiredResources
+	Mounts            []Mount
+
+	// Entrypoint and Command override the ones of the image, if set
+	Entrypoint []string
+	Command    []string
+	Args       []string
+	WorkingDir string
+	User       string // NOTE(SergeyCherepiuk): Either "user" or "user:group"
+	Hostname   string
+
+	// StopSignal and StopTimeout are used to stop the container gracefully
+	// before it is killed, runtime's defaults are used if not set
+	StopSignal  string
+	StopTimeout time.Duration
+
+	// HealthCheck failing makes the container unhealthy, so it is restarted
+	// according to the restart policy. ReadinessCheck only marks the
+	// container as not ready
+	HealthCheck    *HealthCheck
+	ReadinessCheck *HealthCheck
 }
 
 type Labels map[string]string
@@ -65,6 +87,17 @@ func New(image image.Image, config Config) *Container {
 			Labels:            config.Labels.With(DefaultLabels),
 			RestartPolicy:     config.RestartPolicy,
 			RequiredResources: config.RequiredResources,
+			Mounts:            config.Mounts,
+			Entrypoint:        config.Entrypoint,
+			Command:           config.Command,
+			Args:              config.Args,
+			WorkingDir:        config.WorkingDir,
+			User:              config.User,
+			Hostname:          config.Hostname,
+			StopSignal:        config.StopSignal,
+			StopTimeout:       config.StopTimeout,
+			HealthCheck:       config.HealthCheck,
+			ReadinessCheck:    config.ReadinessCheck,
 		},
 	}
 }
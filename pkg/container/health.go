@@ -0,0 +1,35 @@
+package container
+
+import "time"
+
+type HealthCheckType string
+
+const (
+	ExecCheck HealthCheckType = "exec"
+	HTTPCheck HealthCheckType = "http"
+	TCPCheck  HealthCheckType = "tcp"
+)
+
+// NOTE(SergeyCherepiuk): Defaults are the same as the ones of docker's
+// HEALTHCHECK instruction
+const (
+	DefaultCheckInterval = 30 * time.Second
+	DefaultCheckTimeout  = 30 * time.Second
+	DefaultCheckRetries  = 3
+	DefaultCheckPath     = "/"
+)
+
+// HealthCheck is probed by the worker for as long as the container runs.
+// Failures within the start period are not counted, unless the check has
+// succeeded already
+type HealthCheck struct {
+	Type    HealthCheckType
+	Command []string // NOTE(SergeyCherepiuk): Exec checks only
+	Path    string   // NOTE(SergeyCherepiuk): HTTP checks only
+	Port    uint16   // NOTE(SergeyCherepiuk): HTTP and TCP checks only
+
+	Interval    time.Duration
+	Timeout     time.Duration
+	Retries     int
+	StartPeriod time.Duration `yaml:"startPeriod"`
+}
@@ -0,0 +1,37 @@
+package container
+
+type MountType string
+
+const (
+	VolumeMount MountType = "volume"
+	BindMount   MountType = "bind"
+	TmpfsMount  MountType = "tmpfs"
+)
+
+// Mount attaches storage to the container. Source is the name of a volume
+// or a path on the node for a bind mount, tmpfs mounts have no source
+type Mount struct {
+	Type     MountType
+	Source   string
+	Target   string
+	ReadOnly bool `yaml:"readOnly"`
+
+	// Ephemeral volumes are anonymous, they are removed along with the
+	// container and don't pin the task to the worker
+	Ephemeral bool
+}
+
+// Persistent reports whether the data of the mount stays on the worker
+// once the container is removed
+func (m Mount) Persistent() bool {
+	return m.Type == VolumeMount && !m.Ephemeral
+}
+
+func (c Config) HasPersistentMounts() bool {
+	for _, m := range c.Mounts {
+		if m.Persistent() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package containerd
+
+import (
+	"context"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/errdefs"
+)
+
+func (r *Runtime) ContainerState(ctx context.Context, id string) (container.State, error) {
+	c, err := r.Client.LoadContainer(ctx, id)
+	if err != nil {
+		return container.State{}, err
+	}
+
+	t, err := c.Task(ctx, nil)
+	if errdefs.IsNotFound(err) {
+		return container.State{Status: "created"}, nil
+	}
+	if err != nil {
+		return container.State{}, err
+	}
+
+	status, err := t.Status(ctx)
+	if err != nil {
+		return container.State{}, err
+	}
+
+	state := container.State{
+		Status:   toDockerStatus(status.Status),
+		ExitCode: int(status.ExitStatus),
+	}
+	return state, nil
+}
+
+// NOTE(SergeyCherepiuk): Statuses are named after the docker ones, since
+// the worker maps them to the states of the tasks
+func toDockerStatus(status containerd.ProcessStatus) string {
+	switch status {
+	case containerd.Stopped:
+		return "exited"
+	case containerd.Pausing:
+		return "paused"
+	default:
+		return string(status)
+	}
+}
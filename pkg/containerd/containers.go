@@ -0,0 +1,48 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/containerd/containerd/containers"
+)
+
+func (r *Runtime) Containers(ctx context.Context) ([]container.Container, error) {
+	containerdConts, err := r.Client.Containers(ctx, labelFilter())
+	if err != nil {
+		return nil, err
+	}
+
+	fleetConts := make([]container.Container, 0, len(containerdConts))
+	for _, c := range containerdConts {
+		info, err := c.Info(ctx)
+		if err != nil {
+			continue // NOTE(SergeyCherepiuk): Container has been removed meanwhile
+		}
+		fleetConts = append(fleetConts, r.toFleetContainer(ctx, info))
+	}
+	return fleetConts, nil
+}
+
+func labelFilter() string {
+	return fmt.Sprintf("labels.%q==%s", container.TypeLabelKey, container.TypeLabelValue)
+}
+
+func (r *Runtime) toFleetContainer(ctx context.Context, info containers.Container) container.Container {
+	img := image.Image{Ref: info.Image}
+	if i, err := r.Client.GetImage(ctx, info.Image); err == nil {
+		img.Id = i.Target().Digest.String()
+	}
+
+	return container.Container{
+		Id:    info.ID,
+		Image: img,
+		Config: container.Config{
+			Labels: info.Labels,
+			// NOTE(SergeyCherepiuk): Envs, ports, restart policy and required
+			// resources are only in the OCI spec, hence ignored
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package containerd
+
+import (
+	"context"
+	"os"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/oci"
+	"github.com/google/uuid"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+const CPUPeriod = 100_000
+
+func (r *Runtime) CreateAndRun(ctx context.Context, cont container.Container) (string, error) {
+	image, err := r.Client.GetImage(ctx, cont.Image.Ref)
+	if err != nil {
+		return "", err
+	}
+
+	// NOTE(SergeyCherepiuk): Images pulled by other clients might have not
+	// been unpacked into the snapshotter yet
+	if unpacked, err := image.IsUnpacked(ctx, ""); err != nil || !unpacked {
+		if err := image.Unpack(ctx, ""); err != nil {
+			return "", err
+		}
+	}
+
+	id := uuid.NewString()
+	mounts, err := mountSpecs(id, cont.Config.Mounts)
+	if err != nil {
+		return "", err
+	}
+
+	imageSpec, err := image.Spec(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	opts := specOpts(image, cont.Config, mounts)
+	opts = append(opts, processOpts(imageSpec.Config, cont.Config)...)
+
+	c, err := r.Client.NewContainer(
+		ctx, id,
+		containerd.WithImage(image),
+		containerd.WithNewSnapshot(id, image),
+		containerd.WithContainerLabels(cont.Config.Labels),
+		containerd.WithNewSpec(opts...),
+	)
+	if err != nil {
+		os.RemoveAll(ephemeralPath(id))
+		return "", err
+	}
+
+	if err := runTask(ctx, c); err != nil {
+		os.RemoveAll(ephemeralPath(id))
+		return "", err
+	}
+	return id, nil
+}
+
+// NOTE(SergeyCherepiuk): Task that failed to start might still be created by
+// the shim, the container can't be deleted until the task is killed and deleted
+func runTask(ctx context.Context, c containerd.Container) error {
+	t, err := c.NewTask(ctx, cio.LogFile(logPath(c.ID())))
+	if err != nil {
+		c.Delete(ctx, containerd.WithSnapshotCleanup)
+		return err
+	}
+
+	if err := t.Start(ctx); err != nil {
+		t.Delete(ctx, containerd.WithProcessKill)
+		c.Delete(ctx, containerd.WithSnapshotCleanup)
+		return err
+	}
+	return nil
+}
+
+// NOTE(SergeyCherepiuk): Containers share the network of the host, since
+// there is no port mapping without CNI, so exposed ports are reachable as is.
+// The managers never place two tasks exposing the same port on one worker
+func specOpts(image containerd.Image, config container.Config, mounts []specs.Mount) []oci.SpecOpts {
+	opts := []oci.SpecOpts{
+		oci.WithImageConfig(image),
+		oci.WithEnv(config.Env),
+		oci.WithHostNamespace(specs.NetworkNamespace),
+		oci.WithHostHostsFile,
+		oci.WithHostResolvconf,
+		oci.WithMounts(mounts),
+	}
+
+	resources := config.RequiredResources
+	if resources.Memory > 0 {
+		opts = append(opts, oci.WithMemoryLimit(resources.Memory))
+	}
+	if resources.CPU > 0 {
+		opts = append(opts, oci.WithCPUCFS(int64(resources.CPU*CPUPeriod), CPUPeriod))
+	}
+	return opts
+}
+
+// processOpts override the process defined by the image, they must follow
+// the options that apply the config of the image
+func processOpts(imageConfig ocispec.ImageConfig, config container.Config) []oci.SpecOpts {
+	entrypoint, command := config.ProcessArgs(imageConfig.Entrypoint, imageConfig.Cmd)
+	opts := []oci.SpecOpts{
+		oci.WithProcessArgs(append(entrypoint, command...)...),
+		oci.WithAnnotations(stopAnnotations(config)),
+	}
+
+	if config.WorkingDir != "" {
+		opts = append(opts, oci.WithProcessCwd(config.WorkingDir))
+	}
+	if config.User != "" {
+		opts = append(opts, oci.WithUser(config.User))
+	}
+	if config.Hostname != "" {
+		opts = append(opts, oci.WithHostname(config.Hostname))
+	}
+	return opts
+}
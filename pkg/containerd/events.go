@@ -0,0 +1,68 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/typeurl/v2"
+)
+
+func (r *Runtime) Events(ctx context.Context) (<-chan c14n.Event, <-chan error) {
+	envelopes, errs := r.Client.Subscribe(
+		ctx,
+		fmt.Sprintf(`topic=="/tasks/start",namespace==%q`, Namespace),
+		fmt.Sprintf(`topic=="/tasks/exit",namespace==%q`, Namespace),
+	)
+
+	out := make(chan c14n.Event)
+	outErrs := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case envelope := <-envelopes:
+				event, ok := toEvent(envelope.Event)
+				if !ok {
+					continue
+				}
+
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					outErrs <- ctx.Err()
+					return
+				}
+			case err := <-errs:
+				outErrs <- err
+				return
+			}
+		}
+	}()
+
+	return out, outErrs
+}
+
+func toEvent(any typeurl.Any) (c14n.Event, bool) {
+	decoded, err := typeurl.UnmarshalAny(any)
+	if err != nil {
+		return c14n.Event{}, false
+	}
+
+	switch e := decoded.(type) {
+	case *apievents.TaskStart:
+		state := container.State{Status: "running"}
+		return c14n.Event{ContainerId: e.ContainerID, State: state}, true
+	case *apievents.TaskExit:
+		// NOTE(SergeyCherepiuk): Exits of the exec'ed processes are reported
+		// under the same container, only the init process counts
+		if e.ID != e.ContainerID {
+			return c14n.Event{}, false
+		}
+		state := container.State{Status: "exited", ExitCode: int(e.ExitStatus)}
+		return c14n.Event{ContainerId: e.ContainerID, State: state}, true
+	default:
+		return c14n.Event{}, false
+	}
+}
@@ -0,0 +1,65 @@
+package containerd
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/containerd/containerd/cio"
+	"github.com/google/uuid"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func (r *Runtime) Exec(ctx context.Context, id string, opts c14n.ExecOptions, streams c14n.ExecStreams) (int, error) {
+	c, err := r.Client.LoadContainer(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	t, err := c.Task(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	spec, err := c.Spec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// NOTE(SergeyCherepiuk): Process inherits everything (user, cwd, etc.)
+	// from the main process of the container
+	process := *spec.Process
+	process.Args = opts.Cmd
+	process.Env = append(process.Env, opts.Env...)
+	process.Terminal = opts.Tty
+	if opts.Tty && opts.Width > 0 && opts.Height > 0 {
+		process.ConsoleSize = &specs.Box{Width: opts.Width, Height: opts.Height}
+	}
+
+	cioOpts := []cio.Opt{cio.WithStreams(streams.Stdin, streams.Output, streams.Output)}
+	if opts.Tty {
+		cioOpts = append(cioOpts, cio.WithTerminal)
+	}
+
+	p, err := t.Exec(ctx, uuid.NewString(), &process, cio.NewCreator(cioOpts...))
+	if err != nil {
+		return 0, err
+	}
+	defer p.Delete(context.WithoutCancel(ctx))
+
+	exited, err := p.Wait(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.Start(ctx); err != nil {
+		return 0, err
+	}
+
+	status := <-exited
+	if ctx.Err() != nil { // NOTE(SergeyCherepiuk): Only waiting is cancelled, the process is still running
+		p.Kill(context.WithoutCancel(ctx), syscall.SIGKILL)
+	}
+	p.IO().Wait()
+	return int(status.ExitCode()), status.Error()
+}
@@ -0,0 +1,15 @@
+package containerd
+
+import (
+	"context"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+)
+
+func (r *Runtime) HostAddr(_ context.Context, _ string, port uint16) (string, error) {
+	return c14n.LoopbackAddr(port), nil
+}
+
+func (r *Runtime) SharesHostNetwork() bool {
+	return true
+}
@@ -0,0 +1,92 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes/docker"
+)
+
+func (r *Runtime) ImagePresent(ctx context.Context, ref string) (bool, error) {
+	_, err := r.Client.GetImage(ctx, ref)
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *Runtime) PullImage(ctx context.Context, ref string, auth *image.Credentials) error {
+	opts := []containerd.RemoteOpt{containerd.WithPullUnpack}
+	if auth != nil {
+		opts = append(opts, containerd.WithResolver(docker.NewResolver(resolverOptions(*auth))))
+	}
+
+	_, err := r.Client.Pull(ctx, ref, opts...)
+	return err
+}
+
+// resolverOptions hand the credentials out to the hosts they are meant for only
+func resolverOptions(auth image.Credentials) docker.ResolverOptions {
+	creds := func(host string) (string, string, error) {
+		if !auth.Matches(host) {
+			return "", "", nil
+		}
+		return auth.Username, auth.Password, nil
+	}
+
+	authorizer := docker.NewDockerAuthorizer(docker.WithAuthCreds(creds))
+	return docker.ResolverOptions{
+		Hosts: docker.ConfigureDefaultRegistries(docker.WithAuthorizer(authorizer)),
+	}
+}
+
+// NOTE(SergeyCherepiuk): containerd keeps an image per name, the names
+// pointing to the same content are listed as one image
+func (r *Runtime) Images(ctx context.Context) ([]image.Info, error) {
+	imgs, err := r.Client.ListImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]image.Info, 0, len(imgs))
+	indexes := make(map[string]int, len(imgs))
+	for _, img := range imgs {
+		digest := img.Target().Digest.String()
+		if i, ok := indexes[digest]; ok {
+			infos[i].Refs = append(infos[i].Refs, img.Name())
+			continue
+		}
+
+		size, err := img.Size(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		indexes[digest] = len(infos)
+		infos = append(infos, image.Info{Id: digest, Refs: []string{img.Name()}, Size: uint64(max(size, 0))})
+	}
+	return infos, nil
+}
+
+func (r *Runtime) RemoveImage(ctx context.Context, id string) error {
+	imgs, err := r.Client.ListImages(ctx, fmt.Sprintf("target.digest==%s", id))
+	if err != nil {
+		return err
+	}
+	if len(imgs) == 0 {
+		return errdefs.ErrNotFound
+	}
+
+	// NOTE(SergeyCherepiuk): Content is garbage collected by containerd
+	// once the last of the names is deleted
+	for _, img := range imgs {
+		if err := r.Client.ImageService().Delete(ctx, img.Name(), images.SynchronousDelete()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package containerd
+
+import (
+	"context"
+	"io"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+)
+
+func (r *Runtime) Logs(ctx context.Context, id string, opts c14n.LogsOptions) (io.ReadCloser, error) {
+	alive := func() bool {
+		state, err := r.ContainerState(ctx, id)
+		return err == nil && state.Status == "running"
+	}
+	return c14n.TailFile(ctx, logPath(id), opts, alive)
+}
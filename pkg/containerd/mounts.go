@@ -0,0 +1,60 @@
+package containerd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// NOTE(SergeyCherepiuk): containerd has no volumes of its own, they are
+// directories on the node bind mounted into the containers
+var VolumeDir = "/var/lib/fleet/containerd/volumes"
+
+const ephemeralDir = ".ephemeral"
+
+func ephemeralPath(id string) string {
+	return filepath.Join(VolumeDir, ephemeralDir, id)
+}
+
+func mountSpecs(id string, mounts []container.Mount) ([]specs.Mount, error) {
+	specMounts := make([]specs.Mount, 0, len(mounts))
+	for i, m := range mounts {
+		access := "rw"
+		if m.ReadOnly {
+			access = "ro"
+		}
+
+		if m.Type == container.TmpfsMount {
+			specMounts = append(specMounts, specs.Mount{
+				Type:        "tmpfs",
+				Source:      "tmpfs",
+				Destination: m.Target,
+				Options:     []string{"nosuid", "nodev", "mode=1777", access},
+			})
+			continue
+		}
+
+		source := m.Source
+		if m.Type == container.VolumeMount {
+			source = filepath.Join(VolumeDir, m.Source)
+			if m.Ephemeral {
+				source = filepath.Join(ephemeralPath(id), fmt.Sprint(i))
+			}
+
+			if err := os.MkdirAll(source, 0755); err != nil {
+				return nil, err
+			}
+		}
+
+		specMounts = append(specMounts, specs.Mount{
+			Type:        "bind",
+			Source:      source,
+			Destination: m.Target,
+			Options:     []string{"rbind", access},
+		})
+	}
+	return specMounts, nil
+}
@@ -0,0 +1,41 @@
+package containerd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/containerd"
+)
+
+const (
+	DefaultAddress = "/run/containerd/containerd.sock"
+	Namespace      = "fleet"
+)
+
+// NOTE(SergeyCherepiuk): containerd does not keep the output of the tasks,
+// it is written into a file per container instead
+var LogDir = filepath.Join(os.TempDir(), "fleet-containerd")
+
+type Runtime struct {
+	Client *containerd.Client
+}
+
+func New(address string) (*Runtime, error) {
+	if err := os.MkdirAll(LogDir, 0700); err != nil {
+		return nil, err
+	}
+
+	client, err := containerd.New(address, containerd.WithDefaultNamespace(Namespace))
+	if err != nil {
+		return nil, err
+	}
+	return &Runtime{Client: client}, nil
+}
+
+func logPath(id string) string {
+	return filepath.Join(LogDir, id+".log")
+}
+
+func (r *Runtime) Name() string {
+	return "containerd"
+}
@@ -0,0 +1,434 @@
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	tasktypes "github.com/containerd/containerd/api/types/task"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type fakeTask struct {
+	pid      uint32
+	status   tasktypes.Status
+	exitCode uint32
+	exited   chan struct{}
+	signals  []syscall.Signal
+	ignored  []syscall.Signal
+}
+
+func (t *fakeTask) exit(code uint32) {
+	if t.status != tasktypes.Status_STOPPED {
+		t.status, t.exitCode = tasktypes.Status_STOPPED, code
+		close(t.exited)
+	}
+}
+
+// NOTE(SergeyCherepiuk): Serves just enough of the containerd API for the
+// runtime to manage the containers and their tasks, images and snapshots are
+// left unimplemented
+type fakeContainerd struct {
+	mu         sync.Mutex
+	containers map[string]*containersapi.Container
+	tasks      map[string]*fakeTask
+	filters    []string
+	startErr   error
+}
+
+type containersServer struct {
+	containersapi.UnimplementedContainersServer
+	*fakeContainerd
+}
+
+type tasksServer struct {
+	tasksapi.UnimplementedTasksServer
+	*fakeContainerd
+}
+
+type namespacesServer struct {
+	namespacesapi.UnimplementedNamespacesServer
+}
+
+func newFakeRuntime(t *testing.T) (*Runtime, *fakeContainerd) {
+	t.Helper()
+
+	logDir, volumeDir := LogDir, VolumeDir
+	LogDir, VolumeDir = t.TempDir(), t.TempDir()
+	t.Cleanup(func() { LogDir, VolumeDir = logDir, volumeDir })
+
+	address := filepath.Join(t.TempDir(), "containerd.sock")
+	l, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeContainerd{
+		containers: make(map[string]*containersapi.Container),
+		tasks:      make(map[string]*fakeTask),
+	}
+	server := grpc.NewServer(grpc.UnaryInterceptor(requireNamespace))
+	containersapi.RegisterContainersServer(server, containersServer{fakeContainerd: fake})
+	tasksapi.RegisterTasksServer(server, tasksServer{fakeContainerd: fake})
+	namespacesapi.RegisterNamespacesServer(server, namespacesServer{})
+	go server.Serve(l)
+	t.Cleanup(server.Stop)
+
+	r, err := New(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Client.Close() })
+	return r, fake
+}
+
+func requireNamespace(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if ns, _ := namespaces.Namespace(ctx); ns != Namespace {
+		return nil, status.Errorf(codes.FailedPrecondition, "namespace %q is used instead of %q", ns, Namespace)
+	}
+	return handler(ctx, req)
+}
+
+func (f *fakeContainerd) addContainer(id string, labels, annotations map[string]string) {
+	spec, _ := json.Marshal(specs.Spec{Annotations: annotations})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.containers[id] = &containersapi.Container{
+		ID:     id,
+		Labels: labels,
+		Spec:   &anypb.Any{TypeUrl: "types.containerd.io/opencontainers/runtime-spec/1/Spec", Value: spec},
+	}
+}
+
+func (f *fakeContainerd) addTask(id string, status tasktypes.Status, ignored ...syscall.Signal) *fakeTask {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTask{pid: 42, status: status, exited: make(chan struct{}), ignored: ignored}
+	if status == tasktypes.Status_STOPPED {
+		close(t.exited)
+	}
+	f.tasks[id] = t
+	return t
+}
+
+func (f *fakeContainerd) exists(id string) (container, task bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, container = f.containers[id]
+	_, task = f.tasks[id]
+	return container, task
+}
+
+func (f *fakeContainerd) signals(t *fakeTask) []syscall.Signal {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(t.signals)
+}
+
+func (f *fakeContainerd) task(id string) (*fakeTask, error) {
+	t, ok := f.tasks[id]
+	if !ok {
+		return nil, errdefs.ToGRPCf(errdefs.ErrNotFound, "task %s", id)
+	}
+	return t, nil
+}
+
+func (f containersServer) Get(ctx context.Context, req *containersapi.GetContainerRequest) (*containersapi.GetContainerResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[req.ID]
+	if !ok {
+		return nil, errdefs.ToGRPCf(errdefs.ErrNotFound, "container %s", req.ID)
+	}
+	return &containersapi.GetContainerResponse{Container: c}, nil
+}
+
+func (f containersServer) List(ctx context.Context, req *containersapi.ListContainersRequest) (*containersapi.ListContainersResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.filters = append(f.filters, req.Filters...)
+	resp := &containersapi.ListContainersResponse{}
+	for _, c := range f.containers {
+		if c.Labels[container.TypeLabelKey] == container.TypeLabelValue {
+			resp.Containers = append(resp.Containers, c)
+		}
+	}
+	return resp, nil
+}
+
+func (f containersServer) Delete(ctx context.Context, req *containersapi.DeleteContainerRequest) (*emptypb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.containers[req.ID]; !ok {
+		return nil, errdefs.ToGRPCf(errdefs.ErrNotFound, "container %s", req.ID)
+	}
+	delete(f.containers, req.ID)
+	return &emptypb.Empty{}, nil
+}
+
+func (f tasksServer) Create(ctx context.Context, req *tasksapi.CreateTaskRequest) (*tasksapi.CreateTaskResponse, error) {
+	f.mu.Lock()
+	if _, ok := f.containers[req.ContainerID]; !ok {
+		f.mu.Unlock()
+		return nil, errdefs.ToGRPCf(errdefs.ErrNotFound, "container %s", req.ContainerID)
+	}
+	f.mu.Unlock()
+
+	t := f.addTask(req.ContainerID, tasktypes.Status_CREATED)
+	return &tasksapi.CreateTaskResponse{ContainerID: req.ContainerID, Pid: t.pid}, nil
+}
+
+func (f tasksServer) Start(ctx context.Context, req *tasksapi.StartRequest) (*tasksapi.StartResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.task(req.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+	if f.startErr != nil {
+		return nil, f.startErr
+	}
+	t.status = tasktypes.Status_RUNNING
+	return &tasksapi.StartResponse{Pid: t.pid}, nil
+}
+
+func (f tasksServer) Get(ctx context.Context, req *tasksapi.GetRequest) (*tasksapi.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.task(req.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+	process := &tasktypes.Process{
+		ContainerID: req.ContainerID,
+		ID:          req.ContainerID,
+		Pid:         t.pid,
+		Status:      t.status,
+		ExitStatus:  t.exitCode,
+	}
+	return &tasksapi.GetResponse{Process: process}, nil
+}
+
+func (f tasksServer) Kill(ctx context.Context, req *tasksapi.KillRequest) (*emptypb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.task(req.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+
+	signal := syscall.Signal(req.Signal)
+	t.signals = append(t.signals, signal)
+	if !slices.Contains(t.ignored, signal) {
+		t.exit(128 + req.Signal)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (f tasksServer) Wait(ctx context.Context, req *tasksapi.WaitRequest) (*tasksapi.WaitResponse, error) {
+	f.mu.Lock()
+	t, err := f.task(req.ContainerID)
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-t.exited:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &tasksapi.WaitResponse{ExitStatus: t.exitCode}, nil
+}
+
+func (f tasksServer) Delete(ctx context.Context, req *tasksapi.DeleteTaskRequest) (*tasksapi.DeleteResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.task(req.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+	if t.status == tasktypes.Status_RUNNING {
+		return nil, errdefs.ToGRPCf(errdefs.ErrFailedPrecondition, "task %s is running", req.ContainerID)
+	}
+	delete(f.tasks, req.ContainerID)
+	return &tasksapi.DeleteResponse{ExitStatus: t.exitCode}, nil
+}
+
+func (namespacesServer) Get(ctx context.Context, req *namespacesapi.GetNamespaceRequest) (*namespacesapi.GetNamespaceResponse, error) {
+	return &namespacesapi.GetNamespaceResponse{Namespace: &namespacesapi.Namespace{Name: req.Name}}, nil
+}
+
+func TestRunTaskCleansUpAfterFailedStart(t *testing.T) {
+	r, fake := newFakeRuntime(t)
+	fake.addContainer("failing", nil, nil)
+	fake.startErr = status.Error(codes.Internal, "exec format error")
+
+	ctx := context.Background()
+	c, err := r.Client.LoadContainer(ctx, "failing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runTask(ctx, c); err == nil {
+		t.Fatal("task is started")
+	}
+	if container, task := fake.exists("failing"); container || task {
+		t.Fatalf("container (%v) or task (%v) is left behind", container, task)
+	}
+}
+
+func TestRunTaskStartsTask(t *testing.T) {
+	r, fake := newFakeRuntime(t)
+	fake.addContainer("started", nil, nil)
+
+	ctx := context.Background()
+	c, err := r.Client.LoadContainer(ctx, "started")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runTask(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := r.ContainerState(ctx, "started"); err != nil || state.Status != "running" {
+		t.Fatalf("container is %+v: %v", state, err)
+	}
+}
+
+func TestContainerState(t *testing.T) {
+	r, fake := newFakeRuntime(t)
+
+	fake.addContainer("created", nil, nil)
+	fake.addContainer("running", nil, nil)
+	fake.addTask("running", tasktypes.Status_RUNNING)
+	fake.addContainer("exited", nil, nil)
+	fake.addTask("exited", tasktypes.Status_STOPPED).exitCode = 3
+
+	tests := []struct {
+		id    string
+		state container.State
+	}{
+		{id: "created", state: container.State{Status: "created"}},
+		{id: "running", state: container.State{Status: "running"}},
+		{id: "exited", state: container.State{Status: "exited", ExitCode: 3}},
+	}
+
+	for _, test := range tests {
+		state, err := r.ContainerState(context.Background(), test.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state != test.state {
+			t.Errorf("state of %q is %+v instead of %+v", test.id, state, test.state)
+		}
+	}
+
+	if _, err := r.ContainerState(context.Background(), "missing"); !errdefs.IsNotFound(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestStopAndRemove(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		ignored     []syscall.Signal
+		signals     []syscall.Signal
+	}{
+		{
+			name:    "default signal",
+			signals: []syscall.Signal{syscall.SIGTERM},
+		},
+		{
+			name:        "stop signal",
+			annotations: map[string]string{StopSignalAnnotation: "SIGINT"},
+			signals:     []syscall.Signal{syscall.SIGINT},
+		},
+		{
+			name:        "killed after stop timeout",
+			annotations: map[string]string{StopTimeoutAnnotation: "10ms"},
+			ignored:     []syscall.Signal{syscall.SIGTERM},
+			signals:     []syscall.Signal{syscall.SIGTERM, syscall.SIGKILL},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, fake := newFakeRuntime(t)
+			fake.addContainer("stopped", nil, test.annotations)
+			task := fake.addTask("stopped", tasktypes.Status_RUNNING, test.ignored...)
+
+			if err := r.StopAndRemove(context.Background(), "stopped"); err != nil {
+				t.Fatal(err)
+			}
+			if signals := fake.signals(task); !slices.Equal(signals, test.signals) {
+				t.Fatalf("task is sent %v instead of %v", signals, test.signals)
+			}
+			if container, task := fake.exists("stopped"); container || task {
+				t.Fatalf("container (%v) or task (%v) is left behind", container, task)
+			}
+		})
+	}
+}
+
+func TestStopAndRemoveWithoutTask(t *testing.T) {
+	r, fake := newFakeRuntime(t)
+	fake.addContainer("created", nil, nil)
+
+	if err := r.StopAndRemove(context.Background(), "created"); err != nil {
+		t.Fatal(err)
+	}
+	if container, _ := fake.exists("created"); container {
+		t.Fatal("container is left behind")
+	}
+}
+
+func TestContainersListsOnlyFleetContainers(t *testing.T) {
+	r, fake := newFakeRuntime(t)
+	fake.addContainer("fleet", map[string]string{container.TypeLabelKey: container.TypeLabelValue}, nil)
+	fake.addContainer("other", nil, nil)
+
+	conts, err := r.Containers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conts) != 1 || conts[0].Id != "fleet" {
+		t.Fatalf("containers are %+v", conts)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if !slices.Equal(fake.filters, []string{labelFilter()}) {
+		t.Fatalf("containers are filtered by %v instead of %q", fake.filters, labelFilter())
+	}
+}
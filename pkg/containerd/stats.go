@@ -0,0 +1,123 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	v1 "github.com/containerd/cgroups/v3/cgroup1/stats"
+	v2 "github.com/containerd/cgroups/v3/cgroup2/stats"
+	"github.com/containerd/containerd"
+	"github.com/containerd/typeurl/v2"
+)
+
+// UnlimitedMemory is the limit cgroups report when the memory is not limited,
+// rounded down to the page size
+const UnlimitedMemory = math.MaxInt64 &^ (4096 - 1)
+
+var ErrUnknownMetrics = errors.New("metrics of the task are of unknown type")
+
+// NOTE(SergeyCherepiuk): Containers share the network of the host, so the
+// network usage can't be told apart and is not reported
+func (r *Runtime) Stats(ctx context.Context, id string) (c14n.Stats, error) {
+	c, err := r.Client.LoadContainer(ctx, id)
+	if err != nil {
+		return c14n.Stats{}, err
+	}
+
+	t, err := c.Task(ctx, nil)
+	if err != nil {
+		return c14n.Stats{}, err
+	}
+
+	_, before, err := sample(ctx, t)
+	if err != nil {
+		return c14n.Stats{}, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return c14n.Stats{}, ctx.Err()
+	case <-time.After(c14n.StatsInterval):
+	}
+
+	stats, after, err := sample(ctx, t)
+	if err != nil {
+		return c14n.Stats{}, err
+	}
+
+	stats.CPU = c14n.CPUCores(before, after, c14n.StatsInterval)
+	if stats.MemoryLimit >= UnlimitedMemory {
+		stats.MemoryLimit = 0
+	}
+	return stats, nil
+}
+
+// sample reads the metrics of the task along with the CPU time it has used
+// so far in nanoseconds
+func sample(ctx context.Context, t containerd.Task) (c14n.Stats, uint64, error) {
+	metric, err := t.Metrics(ctx)
+	if err != nil {
+		return c14n.Stats{}, 0, err
+	}
+
+	data, err := typeurl.UnmarshalAny(metric.Data)
+	if err != nil {
+		return c14n.Stats{}, 0, err
+	}
+
+	switch m := data.(type) {
+	case *v1.Metrics:
+		stats, cpuTime := v1Stats(m)
+		return stats, cpuTime, nil
+	case *v2.Metrics:
+		stats, cpuTime := v2Stats(m)
+		return stats, cpuTime, nil
+	default:
+		return c14n.Stats{}, 0, ErrUnknownMetrics
+	}
+}
+
+func v1Stats(m *v1.Metrics) (stats c14n.Stats, cpuTime uint64) {
+	if m.CPU != nil && m.CPU.Usage != nil {
+		cpuTime = m.CPU.Usage.Total
+	}
+
+	if m.Memory != nil && m.Memory.Usage != nil {
+		stats.MemoryUsage = m.Memory.Usage.Usage - min(m.Memory.TotalInactiveFile, m.Memory.Usage.Usage)
+		stats.MemoryLimit = m.Memory.Usage.Limit
+	}
+
+	if m.Blkio != nil {
+		for _, entry := range m.Blkio.IoServiceBytesRecursive {
+			switch entry.Op {
+			case "Read":
+				stats.BlockRead += entry.Value
+			case "Write":
+				stats.BlockWrite += entry.Value
+			}
+		}
+	}
+	return stats, cpuTime
+}
+
+func v2Stats(m *v2.Metrics) (stats c14n.Stats, cpuTime uint64) {
+	if m.CPU != nil {
+		cpuTime = m.CPU.UsageUsec * uint64(time.Microsecond)
+	}
+
+	if m.Memory != nil {
+		stats.MemoryUsage = m.Memory.Usage - min(m.Memory.InactiveFile, m.Memory.Usage)
+		stats.MemoryLimit = m.Memory.UsageLimit
+	}
+
+	if m.Io != nil {
+		for _, entry := range m.Io.Usage {
+			stats.BlockRead += entry.Rbytes
+			stats.BlockWrite += entry.Wbytes
+		}
+	}
+	return stats, cpuTime
+}
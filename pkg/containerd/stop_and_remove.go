@@ -0,0 +1,89 @@
+package containerd
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/moby/sys/signal"
+)
+
+const StopTimeout = 10 * time.Second
+
+func (r *Runtime) StopAndRemove(ctx context.Context, id string) error {
+	c, err := r.Client.LoadContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := stopTask(ctx, c); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	os.Remove(logPath(id))
+	os.RemoveAll(ephemeralPath(id))
+	return c.Delete(ctx, containerd.WithSnapshotCleanup)
+}
+
+// NOTE(SergeyCherepiuk): containerd does not know how to stop the task
+// gracefully, the settings are kept in the annotations of its spec instead
+const (
+	StopSignalAnnotation  = "com.fleet.stop-signal"
+	StopTimeoutAnnotation = "com.fleet.stop-timeout"
+)
+
+func stopAnnotations(config container.Config) map[string]string {
+	annotations := make(map[string]string)
+	if config.StopSignal != "" {
+		annotations[StopSignalAnnotation] = config.StopSignal
+	}
+	if config.StopTimeout > 0 {
+		annotations[StopTimeoutAnnotation] = config.StopTimeout.String()
+	}
+	return annotations
+}
+
+func stopSettings(ctx context.Context, c containerd.Container) (syscall.Signal, time.Duration) {
+	sig, timeout := syscall.SIGTERM, StopTimeout
+
+	spec, err := c.Spec(ctx)
+	if err != nil {
+		return sig, timeout
+	}
+
+	if parsed, err := signal.ParseSignal(spec.Annotations[StopSignalAnnotation]); err == nil {
+		sig = parsed
+	}
+	if parsed, err := time.ParseDuration(spec.Annotations[StopTimeoutAnnotation]); err == nil {
+		timeout = parsed
+	}
+	return sig, timeout
+}
+
+func stopTask(ctx context.Context, c containerd.Container) error {
+	t, err := c.Task(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	exited, err := t.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	sig, timeout := stopSettings(ctx, c)
+	t.Kill(ctx, sig)
+	select {
+	case <-exited:
+	case <-time.After(timeout):
+		t.Kill(ctx, syscall.SIGKILL)
+		<-exited
+	}
+
+	_, err = t.Delete(ctx)
+	return err
+}
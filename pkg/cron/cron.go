@@ -0,0 +1,107 @@
+// Package cron describes the tasks that are run on a schedule, written as a
+// standard cron expression.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	_ "time/tzdata" // NOTE(SergeyCherepiuk): Managers may run without the zoneinfo
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/google/uuid"
+)
+
+// Kind is the kind of the resources the crons are kept in the store under,
+// by their names
+const Kind = "crons"
+
+const (
+	DefaultSuccessfulHistoryLimit = 3
+	DefaultFailedHistoryLimit     = 1
+)
+
+// ConcurrencyPolicy tells what to do when the time has come to run the task
+// while the previous one is still running
+type ConcurrencyPolicy string
+
+const (
+	Allow   ConcurrencyPolicy = "allow"
+	Forbid  ConcurrencyPolicy = "forbid"
+	Replace ConcurrencyPolicy = "replace"
+)
+
+type Cron struct {
+	Id                     uuid.UUID
+	Name                   string
+	Schedule               string
+	Timezone               string // NOTE(SergeyCherepiuk): Empty is UTC
+	ConcurrencyPolicy      ConcurrencyPolicy
+	SuccessfulHistoryLimit int
+	FailedHistoryLimit     int
+	Suspended              bool
+	Container              container.Container
+
+	// Tasks are the ones that are still running and the finished ones that
+	// are kept as the history, in order
+	Tasks  []uuid.UUID
+	Active int
+
+	CreatedAt        time.Time
+	LastScheduledAt  time.Time
+	LastSuccessfulAt time.Time
+}
+
+func New(name, schedule, timezone string, policy ConcurrencyPolicy, successfulHistoryLimit, failedHistoryLimit int, container container.Container) *Cron {
+	return &Cron{
+		Id:                     uuid.New(),
+		Name:                   name,
+		Schedule:               schedule,
+		Timezone:               timezone,
+		ConcurrencyPolicy:      policy,
+		SuccessfulHistoryLimit: successfulHistoryLimit,
+		FailedHistoryLimit:     failedHistoryLimit,
+		Container:              container,
+		Tasks:                  make([]uuid.UUID, 0),
+	}
+}
+
+// Validate checks the schedule, timezone and the concurrency policy of the
+// cron
+func (c Cron) Validate() error {
+	if _, err := ParseSchedule(c.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+
+	if c.ConcurrencyPolicy != Allow && c.ConcurrencyPolicy != Forbid && c.ConcurrencyPolicy != Replace {
+		return fmt.Errorf(
+			"unknown concurrency policy, available options: %q, %q, %q",
+			Allow, Forbid, Replace,
+		)
+	}
+
+	if c.SuccessfulHistoryLimit < 0 || c.FailedHistoryLimit < 0 {
+		return errors.New("history limits must not be negative")
+	}
+
+	return nil
+}
+
+// Next returns the first time after t the task of the cron is due
+func (c Cron) Next(t time.Time) (time.Time, error) {
+	schedule, err := ParseSchedule(c.Schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return schedule.Next(t, loc)
+}
@@ -0,0 +1,229 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NOTE(SergeyCherepiuk): Next gives up on the schedules that never match,
+// e.g. "0 0 30 2 *"
+const searchYears = 5
+
+var ErrNoNextTime = errors.New("schedule never matches")
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+type bits uint64
+
+func (b bits) has(i int) bool {
+	return b&(1<<uint(i)) != 0
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    []string // NOTE(SergeyCherepiuk): Names are numbered from min
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: monthNames}
+	dowField    = field{name: "day of week", min: 0, max: 7, names: dayNames}
+)
+
+// Schedule is a parsed standard cron expression: minute, hour, day of month,
+// month and day of week
+type Schedule struct {
+	minute, hour, dom, month, dow bits
+
+	// NOTE(SergeyCherepiuk): If both days are restricted, either of them
+	// has to match, the same as in the original cron
+	domAny, dowAny bool
+}
+
+func ParseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := macros[expr]; ok {
+		expr = macro
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return Schedule{}, fmt.Errorf("expected 5 fields, got %d", len(parts))
+	}
+
+	var (
+		s   Schedule
+		err error
+	)
+	fields := []struct {
+		bits  *bits
+		field field
+	}{
+		{&s.minute, minuteField},
+		{&s.hour, hourField},
+		{&s.dom, domField},
+		{&s.month, monthField},
+		{&s.dow, dowField},
+	}
+	for i, f := range fields {
+		if *f.bits, err = parseField(parts[i], f.field); err != nil {
+			return Schedule{}, err
+		}
+	}
+
+	if s.dow.has(7) { // NOTE(SergeyCherepiuk): Both 0 and 7 are Sunday
+		s.dow |= 1
+	}
+	s.domAny, s.dowAny = strings.HasPrefix(parts[2], "*"), strings.HasPrefix(parts[4], "*")
+	return s, nil
+}
+
+func parseField(expr string, f field) (bits, error) {
+	var b bits
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepExpr); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step of the %s: %q", f.name, part)
+			}
+		}
+
+		var low, high int
+		switch lowExpr, highExpr, isRange := strings.Cut(rangeExpr, "-"); {
+		case rangeExpr == "*":
+			low, high = f.min, f.max
+		case isRange:
+			var err error
+			if low, err = f.value(lowExpr); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(highExpr); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if low, err = f.value(rangeExpr); err != nil {
+				return 0, err
+			}
+			high = low
+			if hasStep {
+				high = f.max
+			}
+		}
+
+		if low > high {
+			return 0, fmt.Errorf("invalid range of the %s: %q", f.name, part)
+		}
+
+		for i := low; i <= high; i += step {
+			b |= 1 << uint(i)
+		}
+	}
+	return b, nil
+}
+
+func (f field) value(expr string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(expr, name) {
+			return f.min + i, nil
+		}
+	}
+
+	value, err := strconv.Atoi(expr)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("%s must be in range from %d to %d, got %q", f.name, f.min, f.max, expr)
+	}
+	return value, nil
+}
+
+// Next returns the first time after t the schedule matches, in the location.
+// The schedule is matched against the wall clock: a minute repeated when the
+// clocks fall back matches once, the minutes skipped when they spring forward
+// match at the moment of the jump.
+func (s Schedule) Next(t time.Time, loc *time.Location) (time.Time, error) {
+	// NOTE(SergeyCherepiuk): Wall clock is walked in UTC, where every day has
+	// all of its minutes exactly once
+	wall := wallClock(t.In(loc)).Add(time.Minute)
+
+	limit := wall.AddDate(searchYears, 0, 0)
+	for wall.Before(limit) {
+		switch {
+		case !s.month.has(int(wall.Month())):
+			wall = time.Date(wall.Year(), wall.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(wall):
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !s.hour.has(wall.Hour()):
+			wall = wall.Add(time.Duration(60-wall.Minute()) * time.Minute)
+		case !s.minute.has(wall.Minute()):
+			wall = wall.Add(time.Minute)
+		default:
+			if next := inLocation(wall, loc); next.After(t) {
+				return next, nil
+			}
+			wall = wall.Add(time.Minute) // NOTE(SergeyCherepiuk): Repeated minute has matched already
+		}
+	}
+	return time.Time{}, ErrNoNextTime
+}
+
+// inLocation returns the earliest moment the wall clock shows the time in the
+// location, or the moment the clocks jump over it
+func inLocation(wall time.Time, loc *time.Location) time.Time {
+	t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, loc)
+	start, end := t.ZoneBounds()
+	if normalized := wallClock(t); !normalized.Equal(wall) {
+		// NOTE(SergeyCherepiuk): Time in the gap is normalized into either
+		// side of it, depending on the offset it is read with
+		if normalized.Before(wall) {
+			return end
+		}
+		return start
+	}
+
+	if start.IsZero() {
+		return t
+	}
+
+	_, before := start.Add(-time.Nanosecond).Zone()
+	_, after := t.Zone()
+	if earlier := t.Add(time.Duration(after-before) * time.Second); earlier.Before(start) && wallClock(earlier).Equal(wall) {
+		return earlier
+	}
+	return t
+}
+
+// wallClock is the time shown by the clock in the location of t, as if the
+// clock were in UTC
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom, dow := s.dom.has(t.Day()), s.dow.has(int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
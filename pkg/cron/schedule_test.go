@@ -0,0 +1,184 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from string
+		next string
+	}{
+		{name: "hourly", expr: "@hourly", from: "2024-05-15T10:15:00Z", next: "2024-05-15T11:00:00Z"},
+		{name: "daily", expr: "@daily", from: "2024-05-15T10:15:00Z", next: "2024-05-16T00:00:00Z"},
+		{name: "midnight", expr: "@midnight", from: "2024-05-15T00:00:00Z", next: "2024-05-16T00:00:00Z"},
+		{name: "weekly", expr: "@weekly", from: "2024-05-15T10:15:00Z", next: "2024-05-19T00:00:00Z"},
+		{name: "monthly", expr: "@monthly", from: "2024-05-15T10:15:00Z", next: "2024-06-01T00:00:00Z"},
+		{name: "yearly", expr: "@yearly", from: "2024-05-15T10:15:00Z", next: "2025-01-01T00:00:00Z"},
+		{name: "seconds are dropped", expr: "* * * * *", from: "2024-05-15T10:15:42Z", next: "2024-05-15T10:16:00Z"},
+		{name: "month names", expr: "0 0 1 jan,JUL *", from: "2024-05-15T10:15:00Z", next: "2024-07-01T00:00:00Z"},
+		{name: "day names", expr: "0 9 * * mon-fri", from: "2024-05-17T10:00:00Z", next: "2024-05-20T09:00:00Z"},
+		{name: "sunday as seven", expr: "0 0 * * 7", from: "2024-05-15T10:15:00Z", next: "2024-05-19T00:00:00Z"},
+		{name: "step", expr: "*/15 * * * *", from: "2024-05-15T10:16:00Z", next: "2024-05-15T10:30:00Z"},
+		{name: "step from value", expr: "5/20 * * * *", from: "2024-05-15T10:16:00Z", next: "2024-05-15T10:25:00Z"},
+		{name: "step of range", expr: "0 8-18/4 * * *", from: "2024-05-15T12:30:00Z", next: "2024-05-15T16:00:00Z"},
+		{name: "list", expr: "0,45 10 * * *", from: "2024-05-15T10:15:00Z", next: "2024-05-15T10:45:00Z"},
+		{name: "day of month or week, month first", expr: "0 0 11 * fri", from: "2024-09-10T00:00:00Z", next: "2024-09-11T00:00:00Z"},
+		{name: "day of month or week, week first", expr: "0 0 20 * fri", from: "2024-09-10T00:00:00Z", next: "2024-09-13T00:00:00Z"},
+		{name: "day of month with any day of week", expr: "0 0 20 * *", from: "2024-09-10T00:00:00Z", next: "2024-09-20T00:00:00Z"},
+		{name: "day of week with any day of month", expr: "0 0 * * fri", from: "2024-09-10T00:00:00Z", next: "2024-09-13T00:00:00Z"},
+		{name: "stepped day of month and day of week", expr: "0 0 */2 * thu", from: "2024-09-10T00:00:00Z", next: "2024-09-19T00:00:00Z"},
+		{name: "leap day", expr: "0 0 29 2 *", from: "2024-03-01T00:00:00Z", next: "2028-02-29T00:00:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			next, err := schedule.Next(mustParse(t, test.from), time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := mustParse(t, test.next); !next.Equal(want) {
+				t.Fatalf("next of %q after %s is %s instead of %s", test.expr, test.from, next, want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextAcrossDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// NOTE(SergeyCherepiuk): In 2024 the clocks in New York sprang forward
+	// from 02:00 EST to 03:00 EDT on March 10 and fell back from 02:00 EDT to
+	// 01:00 EST on November 3
+	tests := []struct {
+		name string
+		expr string
+		from string
+		next []string
+	}{
+		{
+			name: "skipped minute fires at the jump",
+			expr: "30 2 * * *",
+			from: "2024-03-10T00:00:00-05:00",
+			next: []string{"2024-03-10T03:00:00-04:00", "2024-03-11T02:30:00-04:00"},
+		},
+		{
+			name: "hourly over the jump",
+			expr: "0 * * * *",
+			from: "2024-03-10T01:30:00-05:00",
+			next: []string{"2024-03-10T03:00:00-04:00", "2024-03-10T04:00:00-04:00"},
+		},
+		{
+			name: "minutes after the jump",
+			expr: "15 3 * * *",
+			from: "2024-03-10T01:30:00-05:00",
+			next: []string{"2024-03-10T03:15:00-04:00", "2024-03-11T03:15:00-04:00"},
+		},
+		{
+			name: "repeated minute fires once",
+			expr: "30 1 * * *",
+			from: "2024-11-03T00:00:00-04:00",
+			next: []string{"2024-11-03T01:30:00-04:00", "2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name: "repeated minute from the second pass",
+			expr: "30 1 * * *",
+			from: "2024-11-03T01:10:00-05:00",
+			next: []string{"2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name: "hourly over the repeated hour",
+			expr: "0 * * * *",
+			from: "2024-11-03T00:30:00-04:00",
+			next: []string{"2024-11-03T01:00:00-04:00", "2024-11-03T02:00:00-05:00"},
+		},
+		{
+			name: "every minute over the repeated hour",
+			expr: "* * * * *",
+			from: "2024-11-03T01:59:00-04:00",
+			next: []string{"2024-11-03T02:00:00-05:00"},
+		},
+		{
+			name: "daily keeps the wall clock",
+			expr: "0 9 * * *",
+			from: "2024-11-02T09:00:00-04:00",
+			next: []string{"2024-11-03T09:00:00-05:00", "2024-11-04T09:00:00-05:00"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			from := mustParse(t, test.from)
+			for _, value := range test.next {
+				next, err := schedule.Next(from, loc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := mustParse(t, value); !next.Equal(want) {
+					t.Fatalf("next of %q after %s is %s instead of %s", test.expr, from.In(loc), next, want.In(loc))
+				}
+				from = next
+			}
+		})
+	}
+}
+
+func TestScheduleNeverMatches(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := schedule.Next(mustParse(t, "2024-01-01T00:00:00Z"), time.UTC); !errors.Is(err, ErrNoNextTime) {
+		t.Fatalf("expected %v, got %v", ErrNoNextTime, err)
+	}
+}
+
+func TestParseScheduleRejectsInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@often",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"* * * * sunday",
+	} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("%q is parsed", expr)
+		}
+	}
+}
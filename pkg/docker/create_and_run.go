@@ -3,23 +3,17 @@ package docker
 import (
 	"context"
 	"fmt"
-	"io"
 	"math"
-	"os"
 
 	"github.com/SergeyCherepiuk/fleet/pkg/container"
-	"github.com/SergeyCherepiuk/fleet/pkg/image"
 	"github.com/docker/docker/api/types"
 	apicontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/go-connections/nat"
 	"github.com/google/uuid"
 )
 
 func (r *Runtime) CreateAndRun(ctx context.Context, container container.Container) (string, error) {
-	if err := r.pullImage(ctx, container.Image); err != nil {
-		return "", err
-	}
-
 	id, err := r.createContainer(ctx, container)
 	if err != nil {
 		return "", err
@@ -28,26 +22,38 @@ func (r *Runtime) CreateAndRun(ctx context.Context, container container.Containe
 	return id, r.Client.ContainerStart(ctx, id, types.ContainerStartOptions{})
 }
 
-func (r *Runtime) pullImage(ctx context.Context, image image.Image) error {
-	reader, err := r.Client.ImagePull(ctx, image.Ref, types.ImagePullOptions{})
+func (r *Runtime) createContainer(ctx context.Context, cont container.Container) (string, error) {
+	image, _, err := r.Client.ImageInspectWithRaw(ctx, cont.Image.Ref)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	io.Copy(os.Stderr, reader)
-	reader.Close()
-	return nil
-}
+	var imageEntrypoint, imageCmd []string
+	if image.Config != nil {
+		imageEntrypoint, imageCmd = image.Config.Entrypoint, image.Config.Cmd
+	}
+	entrypoint, cmd := cont.Config.ProcessArgs(imageEntrypoint, imageCmd)
 
-func (r *Runtime) createContainer(ctx context.Context, cont container.Container) (string, error) {
 	config := apicontainer.Config{
 		Image:        cont.Image.Ref,
+		Entrypoint:   entrypoint,
+		Cmd:          cmd,
 		Env:          cont.Config.Env,
 		Labels:       cont.Config.Labels,
 		ExposedPorts: portSet(cont.Config.ExposedPorts),
+		WorkingDir:   cont.Config.WorkingDir,
+		User:         cont.Config.User,
+		Hostname:     cont.Config.Hostname,
+		StopSignal:   cont.Config.StopSignal,
 	}
+	if cont.Config.StopTimeout > 0 {
+		seconds := int(math.Ceil(cont.Config.StopTimeout.Seconds()))
+		config.StopTimeout = &seconds
+	}
+
 	hostConfig := apicontainer.HostConfig{
 		PortBindings: portMap(cont.Config.ExposedPorts),
+		Mounts:       mounts(cont.Config.Mounts),
 		Resources: apicontainer.Resources{
 			Memory:   int64(cont.Config.RequiredResources.Memory),
 			NanoCPUs: int64(cont.Config.RequiredResources.CPU * math.Pow(10, 9)),
@@ -63,6 +69,24 @@ func (r *Runtime) createContainer(ctx context.Context, cont container.Container)
 	return resp.ID, nil
 }
 
+func mounts(fleetMounts []container.Mount) []mount.Mount {
+	dockerMounts := make([]mount.Mount, len(fleetMounts))
+	for i, m := range fleetMounts {
+		source := m.Source
+		if m.Ephemeral {
+			source = "" // NOTE(SergeyCherepiuk): Anonymous volumes are removed along with the container
+		}
+
+		dockerMounts[i] = mount.Mount{
+			Type:     mount.Type(m.Type),
+			Source:   source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		}
+	}
+	return dockerMounts
+}
+
 func portSet(ports []uint16) nat.PortSet {
 	portSet := nat.PortSet{}
 	for _, p := range ports {
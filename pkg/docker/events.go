@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+)
+
+func (r *Runtime) Events(ctx context.Context) (<-chan c14n.Event, <-chan error) {
+	filter := labelFilter()
+	filter.Add("type", string(events.ContainerEventType))
+	filter.Add("event", "start")
+	filter.Add("event", "die")
+
+	messages, errs := r.Client.Events(ctx, types.EventsOptions{Filters: filter})
+
+	out := make(chan c14n.Event)
+	outErrs := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case message := <-messages:
+				select {
+				case out <- toEvent(message):
+				case <-ctx.Done():
+					outErrs <- ctx.Err()
+					return
+				}
+			case err := <-errs:
+				outErrs <- err
+				return
+			}
+		}
+	}()
+
+	return out, outErrs
+}
+
+func toEvent(message events.Message) c14n.Event {
+	event := c14n.Event{ContainerId: message.Actor.ID}
+	if message.Action == "start" {
+		event.State = container.State{Status: "running"}
+		return event
+	}
+
+	exitCode, _ := strconv.Atoi(message.Actor.Attributes["exitCode"])
+	event.State = container.State{Status: "exited", ExitCode: exitCode}
+	return event
+}
@@ -0,0 +1,62 @@
+package docker
+
+import (
+	"context"
+	"io"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func (r *Runtime) Exec(ctx context.Context, id string, opts c14n.ExecOptions, streams c14n.ExecStreams) (int, error) {
+	config := types.ExecConfig{
+		Cmd:          opts.Cmd,
+		Env:          opts.Env,
+		Tty:          opts.Tty,
+		AttachStdin:  streams.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	if opts.Tty && opts.Width > 0 && opts.Height > 0 {
+		config.ConsoleSize = &[2]uint{opts.Height, opts.Width}
+	}
+
+	exec, err := r.Client.ContainerExecCreate(ctx, id, config)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := r.Client.ContainerExecAttach(ctx, exec.ID, types.ExecStartCheck{Tty: opts.Tty})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Close()
+
+	// NOTE(SergeyCherepiuk): Docker can not kill an exec, closing the attached
+	// streams at least unblocks the copy and hangs up on the process
+	stop := context.AfterFunc(ctx, resp.Close)
+	defer stop()
+
+	if streams.Stdin != nil {
+		go func() {
+			io.Copy(resp.Conn, streams.Stdin)
+			resp.CloseWrite()
+		}()
+	}
+
+	if opts.Tty {
+		_, err = io.Copy(streams.Output, resp.Reader)
+	} else {
+		_, err = stdcopy.StdCopy(streams.Output, streams.Output, resp.Reader)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	inspect, err := r.Client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
@@ -0,0 +1,31 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/docker/go-connections/nat"
+)
+
+func (r *Runtime) HostAddr(ctx context.Context, id string, port uint16) (string, error) {
+	json, err := r.Client.ContainerInspect(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if json.NetworkSettings == nil {
+		return "", fmt.Errorf("port %d is not published", port)
+	}
+
+	bindings := json.NetworkSettings.Ports[nat.Port(fmt.Sprintf("%d/tcp", port))]
+	if len(bindings) == 0 {
+		return "", fmt.Errorf("port %d is not published", port)
+	}
+
+	host := bindings[0].HostIP
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, bindings[0].HostPort), nil
+}
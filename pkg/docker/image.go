@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"slices"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+func (r *Runtime) ImagePresent(ctx context.Context, ref string) (bool, error) {
+	_, _, err := r.Client.ImageInspectWithRaw(ctx, ref)
+	if client.IsErrNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *Runtime) PullImage(ctx context.Context, ref string, auth *image.Credentials) error {
+	var opts types.ImagePullOptions
+	if auth != nil {
+		encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			ServerAddress: auth.Server,
+		})
+		if err != nil {
+			return err
+		}
+		opts.RegistryAuth = encoded
+	}
+
+	reader, err := r.Client.ImagePull(ctx, ref, opts)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// NOTE(SergeyCherepiuk): Pull completes once the progress is read, the
+	// errors that occur midway are reported in the progress as well
+	return jsonmessage.DisplayJSONMessagesStream(reader, io.Discard, 0, false, nil)
+}
+
+func (r *Runtime) Images(ctx context.Context) ([]image.Info, error) {
+	summaries, err := r.Client.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]image.Info, 0, len(summaries))
+	for _, s := range summaries {
+		refs := slices.DeleteFunc(slices.Clone(s.RepoTags), func(tag string) bool {
+			return tag == "<none>:<none>"
+		})
+		infos = append(infos, image.Info{Id: s.ID, Refs: refs, Size: uint64(max(s.Size, 0))})
+	}
+	return infos, nil
+}
+
+// NOTE(SergeyCherepiuk): Tags are removed one by one, docker refuses to
+// remove the image referenced by several of them without forcing it
+func (r *Runtime) RemoveImage(ctx context.Context, id string) error {
+	inspect, _, err := r.Client.ImageInspectWithRaw(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	targets := inspect.RepoTags
+	if len(targets) == 0 {
+		targets = []string{id}
+	}
+
+	for _, target := range targets {
+		opts := types.ImageRemoveOptions{PruneChildren: true}
+		if _, err := r.Client.ImageRemove(ctx, target, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func (r *Runtime) Logs(ctx context.Context, id string, opts c14n.LogsOptions) (io.ReadCloser, error) {
+	logsOpts := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     opts.Follow,
+		Tail:       "all",
+	}
+	if opts.Tail > 0 {
+		logsOpts.Tail = fmt.Sprint(opts.Tail)
+	}
+	if !opts.Since.IsZero() {
+		logsOpts.Since = fmt.Sprint(opts.Since.Unix())
+	}
+
+	logs, err := r.Client.ContainerLogs(ctx, id, logsOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE(SergeyCherepiuk): Containers are created without TTY, so stdout
+	// and stderr come multiplexed into one stream
+	pr, pw := io.Pipe()
+	go func() {
+		defer logs.Close()
+		_, err := stdcopy.StdCopy(pw, pw, logs)
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
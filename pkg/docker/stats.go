@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/docker/docker/api/types"
+)
+
+// NOTE(SergeyCherepiuk): Docker samples the CPU usage twice when the stats
+// are not streamed, the previous sample is sent along with the current one
+func (r *Runtime) Stats(ctx context.Context, id string) (c14n.Stats, error) {
+	resp, err := r.Client.ContainerStats(ctx, id, false)
+	if err != nil {
+		return c14n.Stats{}, err
+	}
+	defer resp.Body.Close()
+
+	var s types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return c14n.Stats{}, err
+	}
+
+	stats := c14n.Stats{
+		CPU:         cpuCores(s.CPUStats, s.PreCPUStats),
+		MemoryUsage: memoryUsage(s.MemoryStats),
+		MemoryLimit: s.MemoryStats.Limit,
+	}
+
+	for _, network := range s.Networks {
+		stats.NetRx += network.RxBytes
+		stats.NetTx += network.TxBytes
+	}
+
+	for _, entry := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+
+	return stats, nil
+}
+
+// cpuCores follows the way docker stats calculates the usage, as a share of
+// the CPU time of the whole system
+func cpuCores(current, previous types.CPUStats) float64 {
+	if current.CPUUsage.TotalUsage <= previous.CPUUsage.TotalUsage ||
+		current.SystemUsage <= previous.SystemUsage {
+		return 0
+	}
+
+	cores := current.OnlineCPUs
+	if cores == 0 {
+		cores = uint32(len(current.CPUUsage.PercpuUsage))
+	}
+
+	cpuDelta := float64(current.CPUUsage.TotalUsage - previous.CPUUsage.TotalUsage)
+	systemDelta := float64(current.SystemUsage - previous.SystemUsage)
+	return cpuDelta / systemDelta * float64(cores)
+}
+
+// NOTE(SergeyCherepiuk): Page cache that can be reclaimed is not counted,
+// the same way docker stats does it
+func memoryUsage(stats types.MemoryStats) uint64 {
+	inactive, ok := stats.Stats["total_inactive_file"] // NOTE(SergeyCherepiuk): cgroup v1
+	if !ok {
+		inactive = stats.Stats["inactive_file"]
+	}
+
+	if inactive > stats.Usage {
+		return stats.Usage
+	}
+	return stats.Usage - inactive
+}
@@ -9,6 +9,9 @@ import (
 
 func (r *Runtime) StopAndRemove(ctx context.Context, id string) error {
 	r.Client.ContainerStop(ctx, id, apicontainer.StopOptions{})
+
+	// NOTE(SergeyCherepiuk): Only the anonymous (ephemeral) volumes are
+	// removed, the named ones are kept for the next containers of the task
 	removeOpts := types.ContainerRemoveOptions{RemoveVolumes: true}
 	return r.Client.ContainerRemove(ctx, id, removeOpts)
 }
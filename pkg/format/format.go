@@ -81,3 +81,18 @@ func maxLength(elements []string) int {
 	}
 	return max
 }
+
+// Bytes formats the size in the binary units, e.g. "1.5 GiB"
+func Bytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit && exp < 5; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
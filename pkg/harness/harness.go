@@ -0,0 +1,276 @@
+// Package harness boots a whole cluster, managers and workers, inside of
+// one process on loopback ports, so it can be driven from Go tests without
+// any container engine.
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n/fake"
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/manager"
+	"github.com/SergeyCherepiuk/fleet/pkg/node"
+	"github.com/SergeyCherepiuk/fleet/pkg/scheduler"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/SergeyCherepiuk/fleet/pkg/worker"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	WaitInterval = 50 * time.Millisecond
+	StartTimeout = 10 * time.Second
+	AnyLocalPort = "127.0.0.1:0"
+)
+
+var (
+	ErrTimeout  = errors.New("condition is not met in time")
+	ErrNoLeader = errors.New("no manager has been elected as a leader")
+)
+
+type Options struct {
+	Managers int // NOTE(SergeyCherepiuk): At least one manager is started
+	Workers  int
+
+	// Runtime returns the runtime of the i-th worker, a separate fake
+	// runtime is given to every worker if not provided
+	Runtime func(i int) c14n.Runtime
+
+	// Scheduler returns the scheduler of the i-th manager, round robin is
+	// used if not provided
+	Scheduler func(i int) scheduler.Scheduler
+}
+
+type Manager struct {
+	*manager.Manager
+	Addr   string
+	server *echo.Echo
+}
+
+type Worker struct {
+	*worker.Worker
+	Addr    string
+	Runtime c14n.Runtime
+	server  *echo.Echo
+}
+
+type Cluster struct {
+	Managers []*Manager
+	Workers  []*Worker
+}
+
+func Start(opts Options) (*Cluster, error) {
+	opts.Managers = max(opts.Managers, 1)
+	if opts.Runtime == nil {
+		opts.Runtime = func(int) c14n.Runtime { return fake.New() }
+	}
+	if opts.Scheduler == nil {
+		opts.Scheduler = func(int) scheduler.Scheduler { return scheduler.NewRoundRobin() }
+	}
+
+	cluster := Cluster{}
+	if err := cluster.startManagers(opts); err != nil {
+		cluster.Close()
+		return nil, err
+	}
+
+	if _, err := cluster.WaitForLeader(StartTimeout); err != nil {
+		cluster.Close()
+		return nil, err
+	}
+
+	for i := 0; i < opts.Workers; i++ {
+		if _, err := cluster.AddWorker(opts.Runtime(i)); err != nil {
+			cluster.Close()
+			return nil, err
+		}
+	}
+
+	return &cluster, nil
+}
+
+func (c *Cluster) startManagers(opts Options) error {
+	listeners := make([]net.Listener, opts.Managers)
+	addrs := make([]string, opts.Managers)
+	for i := range listeners {
+		ln, err := net.Listen("tcp", AnyLocalPort)
+		if err != nil {
+			return err
+		}
+		listeners[i], addrs[i] = ln, ln.Addr().String()
+	}
+
+	for i, ln := range listeners {
+		peers := make([]string, 0, len(addrs)-1)
+		for j, addr := range addrs {
+			if j != i {
+				peers = append(peers, addr)
+			}
+		}
+
+		n := node.Node{Addr: loopbackAddr(ln)}
+		m := manager.New(n, opts.Scheduler(i), consensus.NewLocalStore(), peers)
+
+		c.Managers = append(c.Managers, &Manager{
+			Manager: m,
+			Addr:    addrs[i],
+			server:  serve(manager.NewServer(m), ln),
+		})
+	}
+	return nil
+}
+
+// AddWorker starts one more worker and waits until the leader has it
+// registered
+func (c *Cluster) AddWorker(runtime c14n.Runtime) (*Worker, error) {
+	ln, err := net.Listen("tcp", AnyLocalPort)
+	if err != nil {
+		return nil, err
+	}
+
+	n := node.Node{Addr: loopbackAddr(ln)}
+	w := worker.New(n, runtime, c.managerAddrs())
+	server := serve(worker.NewServer(w), ln)
+	w.Start()
+
+	hw := Worker{Worker: w, Addr: ln.Addr().String(), Runtime: runtime, server: server}
+	c.Workers = append(c.Workers, &hw)
+
+	err = c.WaitFor(StartTimeout, func() bool {
+		leader := c.Leader()
+		if leader == nil {
+			return false
+		}
+		_, err := leader.Store.GetWorker(w.Id)
+		return err == nil
+	})
+	return &hw, err
+}
+
+// Leader returns the manager that currently considers itself the leader
+func (c *Cluster) Leader() *Manager {
+	for _, m := range c.Managers {
+		if m.IsLeader() {
+			return m
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) WaitForLeader(timeout time.Duration) (*Manager, error) {
+	var leader *Manager
+	err := c.WaitFor(timeout, func() bool {
+		leader = c.Leader()
+		return leader != nil
+	})
+	if err != nil {
+		return nil, ErrNoLeader
+	}
+	return leader, nil
+}
+
+// Run submits the tasks through the API of the leader, the same way the
+// CLI does
+func (c *Cluster) Run(tasks ...task.Task) error {
+	leader := c.Leader()
+	if leader == nil {
+		return ErrNoLeader
+	}
+
+	resp, err := httpclient.Post(leader.Addr, "/task/run", tasks)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+	return nil
+}
+
+// Task returns the task as the leader's store has it
+func (c *Cluster) Task(id uuid.UUID) (task.Task, error) {
+	leader := c.Leader()
+	if leader == nil {
+		return task.Task{}, ErrNoLeader
+	}
+	return leader.Store.GetTask(id)
+}
+
+func (c *Cluster) WaitForTask(id uuid.UUID, state task.State, timeout time.Duration) (task.Task, error) {
+	var t task.Task
+	err := c.WaitFor(timeout, func() bool {
+		var err error
+		t, err = c.Task(id)
+		return err == nil && t.State == state
+	})
+	if err != nil {
+		return t, fmt.Errorf("task %s is %q instead of %q: %w", id, t.State, state, err)
+	}
+	return t, nil
+}
+
+func (c *Cluster) WaitFor(timeout time.Duration, condition func() bool) error {
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			return ErrTimeout
+		}
+		time.Sleep(WaitInterval)
+	}
+	return nil
+}
+
+// StopWorker cuts the worker off the cluster, its tasks are left as they
+// are, so the managers see it as a lost heartbeat
+func (c *Cluster) StopWorker(i int) error {
+	w := c.Workers[i]
+	w.Stop()
+	return w.server.Close()
+}
+
+// StopManager takes the manager down the way a crash would, the rest of
+// the managers elect a new leader if it was the one
+func (c *Cluster) StopManager(i int) error {
+	m := c.Managers[i]
+	m.Stop()
+	return m.server.Close()
+}
+
+func (c *Cluster) Close() {
+	for _, w := range c.Workers {
+		w.Stop()
+		w.server.Close()
+	}
+	for _, m := range c.Managers {
+		m.Stop()
+		m.server.Close()
+	}
+}
+
+func (c *Cluster) managerAddrs() []string {
+	addrs := make([]string, len(c.Managers))
+	for i, m := range c.Managers {
+		addrs[i] = m.Addr
+	}
+	return addrs
+}
+
+func serve(e *echo.Echo, ln net.Listener) *echo.Echo {
+	e.HidePort = true
+	e.Listener = ln
+	go e.Start("")
+	return e
+}
+
+func loopbackAddr(ln net.Listener) node.Addr {
+	tcp := ln.Addr().(*net.TCPAddr)
+	return node.Addr{Addr: tcp.IP, Port: uint16(tcp.Port)}
+}
@@ -0,0 +1,408 @@
+package harness
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n/fake"
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/SergeyCherepiuk/fleet/pkg/job"
+	"github.com/SergeyCherepiuk/fleet/pkg/manager"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+)
+
+const testTimeout = 10 * time.Second
+
+func newTask(ref string, config container.Config) task.Task {
+	config.Labels = container.Labels{}
+	return *task.New(*container.New(image.Image{Ref: ref}, config))
+}
+
+func start(t *testing.T, opts Options) *Cluster {
+	t.Helper()
+
+	cluster, err := Start(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+	return cluster
+}
+
+func sharedRuntime(runtime *fake.Runtime) func(int) c14n.Runtime {
+	return func(int) c14n.Runtime { return runtime }
+}
+
+func TestTasksAreSpreadAcrossWorkers(t *testing.T) {
+	cluster := start(t, Options{Workers: 3})
+
+	tasks := make([]task.Task, 6)
+	for i := range tasks {
+		tasks[i] = newTask("web", container.Config{})
+	}
+	if err := cluster.Run(tasks...); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tk := range tasks {
+		if _, err := cluster.WaitForTask(tk.Id, task.Running, testTimeout); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for id := range cluster.Leader().Store.AllWorkers() {
+		if n := len(cluster.Leader().WorkerTasks(id)); n != 2 {
+			t.Fatalf("worker %s runs %d tasks instead of 2", id, n)
+		}
+	}
+}
+
+func TestRestartPolicy(t *testing.T) {
+	tests := []struct {
+		policy   container.RestartPolicy
+		behavior fake.Behavior
+		restarts bool
+	}{
+		{policy: container.Always, behavior: fake.Exit(100 * time.Millisecond), restarts: true},
+		{policy: container.Always, behavior: fake.Crash(100 * time.Millisecond), restarts: true},
+		{policy: container.OnFailure, behavior: fake.Crash(100 * time.Millisecond), restarts: true},
+		{policy: container.OnFailure, behavior: fake.Exit(100 * time.Millisecond), restarts: false},
+		{policy: container.Never, behavior: fake.Crash(100 * time.Millisecond), restarts: false},
+	}
+
+	runtime := fake.New()
+	cluster := start(t, Options{Workers: 1, Runtime: sharedRuntime(runtime)})
+
+	for i, test := range tests {
+		ref := fmt.Sprintf("app-%d", i)
+		runtime.Script(ref, test.behavior)
+		if err := cluster.Run(newTask(ref, container.Config{RestartPolicy: test.policy})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	time.Sleep(2 * time.Second)
+
+	for i, test := range tests {
+		starts := runtime.Starts(fmt.Sprintf("app-%d", i))
+		if restarted := starts > 1; restarted != test.restarts {
+			t.Errorf("%q policy with exit code %d: started %d times", test.policy, test.behavior.ExitCode, starts)
+		}
+	}
+}
+
+func TestTasksAreRescheduledOnHeartbeatLoss(t *testing.T) {
+	cluster := start(t, Options{Workers: 2})
+
+	tasks := []task.Task{newTask("web", container.Config{}), newTask("web", container.Config{})}
+	if err := cluster.Run(tasks...); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tk := range tasks {
+		if _, err := cluster.WaitForTask(tk.Id, task.Running, testTimeout); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lost := cluster.Workers[0]
+	if err := cluster.StopWorker(0); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cluster.WaitFor(testTimeout, func() bool {
+		_, err := cluster.Leader().Store.GetWorker(lost.Id)
+		return err != nil
+	})
+	if err != nil {
+		t.Fatalf("lost worker is still registered: %v", err)
+	}
+
+	for _, tk := range tasks {
+		if _, err := cluster.WaitForTask(tk.Id, task.Running, testTimeout); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := len(cluster.Leader().WorkerTasks(cluster.Workers[1].Id)); n != len(tasks) {
+		t.Fatalf("remaining worker runs %d tasks instead of %d", n, len(tasks))
+	}
+}
+
+func TestPendingTasksSurviveLeaderFailover(t *testing.T) {
+	cluster := start(t, Options{Managers: 3})
+
+	tk := newTask("web", container.Config{})
+	if err := cluster.Run(tk); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cluster.WaitFor(testTimeout, func() bool {
+		for _, m := range cluster.Managers {
+			if len(m.Store.AllResources(manager.EventKind)) == 0 {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("pending task is not replicated: %v", err)
+	}
+
+	for i, m := range cluster.Managers {
+		if m.IsLeader() {
+			cluster.StopManager(i)
+		}
+	}
+
+	if _, err := cluster.WaitForLeader(testTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cluster.AddWorker(fake.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cluster.WaitForTask(tk.Id, task.Running, testTimeout); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPortConflictOnHostNetwork(t *testing.T) {
+	cluster := start(t, Options{
+		Workers: 2,
+		Runtime: func(int) c14n.Runtime {
+			runtime := fake.New()
+			runtime.HostNetwork = true
+			return runtime
+		},
+	})
+
+	config := container.Config{ExposedPorts: []uint16{8080}}
+	tasks := []task.Task{newTask("web", config), newTask("web", config), newTask("web", config)}
+	if err := cluster.Run(tasks...); err != nil {
+		t.Fatal(err)
+	}
+
+	placed := make(map[string]bool)
+	err := cluster.WaitFor(testTimeout, func() bool {
+		clear(placed)
+		leader := cluster.Leader()
+		for id, w := range leader.Store.AllWorkers() {
+			for _, tk := range leader.WorkerTasks(id) {
+				if tk.State == task.Running {
+					placed[w.Addr.String()] = true
+				}
+			}
+		}
+		return len(placed) == 2
+	})
+	if err != nil {
+		t.Fatalf("tasks are running on %d workers instead of 2: %v", len(placed), err)
+	}
+
+	time.Sleep(time.Second)
+	if n := len(cluster.Leader().Tasks()); n != 2 {
+		t.Fatalf("%d tasks are placed despite the port conflict instead of 2", n)
+	}
+}
+
+func TestPortsAreNotCheckedOffHostNetwork(t *testing.T) {
+	cluster := start(t, Options{Workers: 1})
+
+	config := container.Config{ExposedPorts: []uint16{8080}}
+	tasks := []task.Task{newTask("web", config), newTask("web", config)}
+	if err := cluster.Run(tasks...); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tk := range tasks {
+		if _, err := cluster.WaitForTask(tk.Id, task.Running, testTimeout); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCredentialsAreHandedOnlyToTheWorker(t *testing.T) {
+	runtime := fake.New()
+	cluster := start(t, Options{Workers: 1, Runtime: sharedRuntime(runtime)})
+
+	leader, err := cluster.WaitForLeader(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watcher, err := leader.Store.Watch(leader.Store.LastIndex()+1, consensus.WatchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	creds := image.Credentials{Server: "registry.local", Username: "fleet", Password: "secret"}
+	if err := leader.SetCredentials("private", creds); err != nil {
+		t.Fatal(err)
+	}
+
+	tk := newTask("registry.local/app", container.Config{})
+	tk.Container.Image.Credentials = "private"
+	if err := cluster.Run(tk); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cluster.WaitForTask(tk.Id, task.Running, testTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if auth := runtime.Auth(tk.Container.Image.Ref); auth == nil || *auth != creds {
+		t.Fatalf("image is pulled with %+v instead of %+v", auth, creds)
+	}
+
+	dump, _ := json.Marshal(consensus.NewDump(leader.Store))
+	log, _ := json.Marshal(consensus.Log(leader.Store, 0))
+	if bytes.Contains(dump, []byte(creds.Password)) || bytes.Contains(log, []byte(creds.Password)) {
+		t.Fatal("password is revealed by the store")
+	}
+
+	if _, ok := leader.Backup().Resources[image.CredentialsKind]; ok {
+		t.Fatal("credentials are backed up")
+	}
+
+	watched := false
+	for len(watcher.Events()) > 0 {
+		event := <-watcher.Events()
+		for _, change := range event.Changes {
+			watched = watched || change.Kind == image.CredentialsKind
+		}
+		data, _ := json.Marshal(event)
+		if bytes.Contains(data, []byte(creds.Password)) || bytes.Contains(event.Command.Data, []byte(creds.Password)) {
+			t.Fatal("password is revealed to the watchers")
+		}
+	}
+	if !watched {
+		t.Fatal("credentials are not watched")
+	}
+}
+
+func TestJobRunsOnlyItsOwnTasks(t *testing.T) {
+	runtime := fake.New()
+	runtime.Script("batch", fake.Exit(100*time.Millisecond))
+	cluster := start(t, Options{Workers: 2, Runtime: sharedRuntime(runtime)})
+
+	leader, err := cluster.WaitForLeader(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j := job.New(*container.New(image.Image{Ref: "batch"}, container.Config{Labels: container.Labels{}}), 3, 2, 0, 0)
+	if err := leader.RunJobs([]job.Job{*j}); err != nil {
+		t.Fatal(err)
+	}
+
+	stranger := newTask("batch", container.Config{})
+	stranger.JobId = j.Id
+	if err := cluster.Run(stranger); err != nil {
+		t.Fatal(err)
+	}
+
+	err = cluster.WaitFor(testTimeout, func() bool {
+		jobs, err := cluster.Leader().Jobs()
+		return err == nil && len(jobs) == 1 && jobs[0].Status == job.Complete
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := runtime.Starts("batch"); n != 3 {
+		t.Fatalf("%d tasks are started instead of 3", n)
+	}
+	if _, err := cluster.Task(stranger.Id); err == nil {
+		t.Fatal("task unknown to the job is run")
+	}
+}
+
+func TestRestoredTasksArePinnedAgain(t *testing.T) {
+	volume := container.Config{Mounts: []container.Mount{{Type: container.VolumeMount, Source: "data", Target: "/data"}}}
+
+	original := start(t, Options{Workers: 1})
+	tk := newTask("db", volume)
+	if err := original.Run(tk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := original.WaitForTask(tk.Id, task.Running, testTimeout); err != nil {
+		t.Fatal(err)
+	}
+	backup := original.Leader().Backup()
+	original.Close()
+
+	restored := start(t, Options{Workers: 1})
+	leader, err := restored.WaitForLeader(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := leader.Restore(backup); err != nil {
+		t.Fatal(err)
+	}
+
+	running, err := restored.WaitForTask(tk.Id, task.Running, testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if running.PinnedTo == nil || running.PinnedTo.String() != restored.Workers[0].Addr {
+		t.Fatalf("task is pinned to %v instead of %s", running.PinnedTo, restored.Workers[0].Addr)
+	}
+}
+
+func TestPinnedTaskFollowsRestartedWorker(t *testing.T) {
+	volume := container.Config{Mounts: []container.Mount{{Type: container.VolumeMount, Source: "data", Target: "/data"}}}
+
+	runtime := fake.New()
+	cluster := start(t, Options{Workers: 1, Runtime: sharedRuntime(runtime)})
+
+	tk := newTask("db", volume)
+	if err := cluster.Run(tk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cluster.WaitForTask(tk.Id, task.Running, testTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	lost := cluster.Workers[0]
+	if err := cluster.StopWorker(0); err != nil {
+		t.Fatal(err)
+	}
+	err := cluster.WaitFor(testTimeout, func() bool {
+		_, err := cluster.Leader().Store.GetWorker(lost.Id)
+		return err != nil
+	})
+	if err != nil {
+		t.Fatalf("lost worker is still registered: %v", err)
+	}
+
+	// NOTE(SergeyCherepiuk): Restarted worker is on the same host, but
+	// listens on another port
+	restarted, err := cluster.AddWorker(runtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cluster.WaitFor(testTimeout, func() bool {
+		tasks := cluster.Leader().WorkerTasks(restarted.Id)
+		return slices.ContainsFunc(tasks, func(placed task.Task) bool { return placed.Id == tk.Id })
+	})
+	if err != nil {
+		t.Fatalf("task is not placed on the restarted worker: %v", err)
+	}
+	if _, err := cluster.WaitForTask(tk.Id, task.Running, testTimeout); err != nil {
+		t.Fatal(err)
+	}
+	if n := runtime.Starts("db"); n != 2 {
+		t.Fatalf("task is started %d times instead of 2", n)
+	}
+}
@@ -2,23 +2,41 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 func Get(addr, endpoint string) (*http.Response, error) {
-	url, err := url.JoinPath("http://", addr, endpoint)
+	return GetWithContext(context.Background(), addr, endpoint)
+}
+
+// GetWithContext cancels the request along with the context, e.g. once the
+// client the response is streamed to has gone
+func GetWithContext(ctx context.Context, addr, endpoint string) (*http.Response, error) {
+	url, err := joinUrl(addr, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	client := http.Client{}
-	return client.Get(url)
+	return client.Do(req)
 }
 
 func Post(addr, endpoint string, payload any) (*http.Response, error) {
-	url, err := url.JoinPath("http://", addr, endpoint)
+	return PostWithTimeout(addr, endpoint, payload, 0)
+}
+
+func PostWithTimeout(addr, endpoint string, payload any, timeout time.Duration) (*http.Response, error) {
+	url, err := joinUrl(addr, endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -28,24 +46,30 @@ func Post(addr, endpoint string, payload any) (*http.Response, error) {
 		return nil, err
 	}
 
-	client := http.Client{}
+	client := http.Client{Timeout: timeout}
 	return client.Post(url, "application/json", bytes.NewReader(body))
 }
 
+// PostWithHeader sends the header along with the payload, e.g. the secrets
+// that must not be part of it
+func PostWithHeader(addr, endpoint string, payload any, header http.Header) (*http.Response, error) {
+	return named("POST", addr, endpoint, payload, header)
+}
+
 func Put(addr, endpoint string, payload any) (*http.Response, error) {
-	return named("PUT", addr, endpoint, payload)
+	return named("PUT", addr, endpoint, payload, nil)
 }
 
 func Patch(addr, endpoint string, payload any) (*http.Response, error) {
-	return named("PATCH", addr, endpoint, payload)
+	return named("PATCH", addr, endpoint, payload, nil)
 }
 
 func Delete(addr, endpoint string, payload any) (*http.Response, error) {
-	return named("DELETE", addr, endpoint, payload)
+	return named("DELETE", addr, endpoint, payload, nil)
 }
 
-func named(method, addr, endpoint string, payload any) (*http.Response, error) {
-	url, err := url.JoinPath("http://", addr, endpoint)
+func named(method, addr, endpoint string, payload any, header http.Header) (*http.Response, error) {
+	url, err := joinUrl(addr, endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +83,21 @@ func named(method, addr, endpoint string, payload any) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
 
 	client := http.Client{}
 	return client.Do(req)
 }
+
+// joinUrl keeps the query of the endpoint intact, url.JoinPath would escape it
+func joinUrl(addr, endpoint string) (string, error) {
+	path, query, found := strings.Cut(endpoint, "?")
+	joined, err := url.JoinPath("http://", addr, path)
+	if err != nil || !found {
+		return joined, err
+	}
+	return joined + "?" + query, nil
+}
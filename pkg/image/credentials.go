@@ -0,0 +1,53 @@
+package image
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// CredentialsKind is the kind of the resources the registry credentials are
+// kept in the store under, by their names
+const CredentialsKind = "registry-credentials"
+
+// AuthHeader carries the credentials to the worker running the task, the
+// same way docker passes them to its daemon
+const AuthHeader = "X-Registry-Auth"
+
+// NOTE(SergeyCherepiuk): Managers keep the credentials as is, the password is
+// redacted from everything that leaves them and handed only to the worker
+// that pulls the image
+type Credentials struct {
+	Server   string
+	Username string
+	Password string
+}
+
+// Matches reports whether the credentials are meant for the registry host,
+// credentials without the server match any of them
+func (c Credentials) Matches(host string) bool {
+	if c.Server == "" {
+		return true
+	}
+
+	server := strings.TrimPrefix(strings.TrimPrefix(c.Server, "https://"), "http://")
+	server = strings.TrimSuffix(server, "/")
+	return server == host ||
+		server == "docker.io" && host == "registry-1.docker.io" ||
+		server == "index.docker.io" && host == "registry-1.docker.io"
+}
+
+func EncodeAuth(c Credentials) string {
+	data, _ := json.Marshal(c)
+	return base64.URLEncoding.EncodeToString(data)
+}
+
+func DecodeAuth(auth string) (Credentials, error) {
+	var c Credentials
+	data, err := base64.URLEncoding.DecodeString(auth)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
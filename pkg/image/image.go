@@ -1,7 +1,41 @@
 package image
 
+import "github.com/distribution/reference"
+
 // NOTE(SergeyCherepiuk): Only pulled images are supported for now
 type Image struct {
-	Id  string `yaml:"-"`
-	Ref string // registry/tag:version
+	Id         string `yaml:"-"`
+	Ref        string // registry/tag:version
+	PullPolicy PullPolicy
+
+	// Credentials is the name of the registry credentials stored in the
+	// cluster, the image is pulled anonymously if it is empty
+	Credentials string
+}
+
+// NOTE(SergeyCherepiuk): Empty policy is the same as IfNotPresent
+type PullPolicy string
+
+const (
+	PullAlways       PullPolicy = "always"
+	PullIfNotPresent PullPolicy = "ifNotPresent"
+	PullNever        PullPolicy = "never"
+)
+
+// Info describes one of the images present on the node
+type Info struct {
+	Id   string
+	Refs []string
+	Size uint64
+}
+
+// Normalize brings the reference to its full form, so "nginx" and
+// "docker.io/library/nginx:latest" are the same image. References that
+// are not the ones of a registry, e.g. paths, are returned as is
+func Normalize(ref string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ref
+	}
+	return reference.TagNameOnly(named).String()
 }
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/google/uuid"
+)
+
+// Kind is the kind of the resources the jobs are kept in the store under,
+// by their ids
+const Kind = "jobs"
+
+const (
+	DefaultCompletions  = 1
+	DefaultParallelism  = 1
+	DefaultBackoffLimit = 6
+)
+
+type Status string
+
+const (
+	Active   Status = "Active"
+	Complete Status = "Complete"
+	Failed   Status = "Failed"
+)
+
+// Reasons of the job's failure
+const (
+	BackoffLimitExceeded = "BackoffLimitExceeded"
+	DeadlineExceeded     = "DeadlineExceeded"
+)
+
+// Job runs the tasks from the same container until Completions of them
+// finish successfully, at most Parallelism at a time. Failed tasks are
+// replaced by the new ones until more than BackoffLimit of them fail
+type Job struct {
+	Id             uuid.UUID
+	Container      container.Container
+	Completions    int
+	Parallelism    int
+	BackoffLimit   int
+	ActiveDeadline time.Duration // NOTE(SergeyCherepiuk): Zero is no deadline
+
+	Status    Status
+	Reason    string
+	Tasks     []uuid.UUID // NOTE(SergeyCherepiuk): Every task run by the job, in order
+	Active    int
+	Succeeded int
+	Failed    int
+
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+func New(container container.Container, completions, parallelism, backoffLimit int, activeDeadline time.Duration) *Job {
+	return &Job{
+		Id:             uuid.New(),
+		Container:      container,
+		Completions:    completions,
+		Parallelism:    parallelism,
+		BackoffLimit:   backoffLimit,
+		ActiveDeadline: activeDeadline,
+		Status:         Active,
+		Tasks:          make([]uuid.UUID, 0),
+	}
+}
+
+func (j Job) Done() bool {
+	return j.Status == Complete || j.Status == Failed
+}
@@ -0,0 +1,111 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/google/uuid"
+)
+
+const BackupVersion = 1
+
+var ErrUnsupportedBackup = errors.New("backup version is not supported")
+
+// Backup is a consistent copy of the manager's store, taken at LastIndex
+type Backup struct {
+	Version   int
+	CreatedAt time.Time
+	LastIndex int
+	LastTerm  int
+	Workers   map[uuid.UUID]consensus.WorkerDump
+	Resources map[string]map[string]json.RawMessage
+}
+
+type RestoreReport struct {
+	Rescheduled int
+	Skipped     int
+	Resources   int
+}
+
+func (m *Manager) Backup() Backup {
+	dump := consensus.NewDump(m.Store)
+
+	// NOTE(SergeyCherepiuk): Pending events refer to the tasks of the current
+	// cluster, tasks from the backup are rescheduled by Restore instead
+	delete(dump.Resources, EventKind)
+
+	// NOTE(SergeyCherepiuk): The dump has the passwords redacted, restoring
+	// them would break the credentials, so they have to be set again
+	delete(dump.Resources, image.CredentialsKind)
+	return Backup{
+		Version:   BackupVersion,
+		CreatedAt: time.Now(),
+		LastIndex: dump.LastIndex,
+		LastTerm:  dump.LastTerm,
+		Workers:   dump.Workers,
+		Resources: dump.Resources,
+	}
+}
+
+// Restore brings the resources back and reschedules the tasks that should be
+// running onto the workers registered now, the workers from the backup are
+// not restored since they re-register by themselves. Tasks that are already
+// in the store are left untouched.
+func (m *Manager) Restore(backup Backup) (RestoreReport, error) {
+	var report RestoreReport
+	if backup.Version < 1 || backup.Version > BackupVersion {
+		return report, ErrUnsupportedBackup
+	}
+
+	// NOTE(SergeyCherepiuk): Resources are committed as one batch, so that a
+	// failed restore leaves none of them behind
+	cmds := make([]consensus.Command, 0)
+	for kind, resources := range backup.Resources {
+		for id, resource := range resources {
+			cmds = append(cmds, *consensus.NewSetResourceCommand(kind, id, resource))
+		}
+	}
+
+	if len(cmds) > 0 {
+		if _, err := m.Store.CommitChange(*consensus.NewBatchCommand(cmds...)); err != nil {
+			return report, err
+		}
+	}
+	report.Resources = len(cmds)
+
+	for _, worker := range backup.Workers {
+		for _, t := range worker.Tasks {
+			if _, err := m.Store.GetTask(t.Id); err == nil || !shouldRun(t) {
+				report.Skipped++
+				continue
+			}
+
+			// NOTE(SergeyCherepiuk): Workers of the backed up cluster may never
+			// come back, the task is pinned again wherever it is placed
+			t.State = task.Pending
+			t.Container.Id = ""
+			t.PinnedTo = nil
+			m.enqueueNow(task.Event{Task: t, Desired: task.Running})
+			report.Rescheduled++
+		}
+	}
+
+	return report, nil
+}
+
+func shouldRun(t task.Task) bool {
+	rp := t.Container.Config.RestartPolicy
+	switch {
+	case t.State == task.Finished:
+		return rp == container.Always
+	case t.State.Fail():
+		return rp == container.Always || rp == container.OnFailure
+	default:
+		return true
+	}
+}
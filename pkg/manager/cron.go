@@ -0,0 +1,302 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/cron"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/google/uuid"
+)
+
+const CronSyncInterval = time.Second
+
+var (
+	ErrCronNotFound    = errors.New("cron is not found")
+	ErrCronExists      = errors.New("cron with the same name already exists")
+	ErrInvalidCronName = errors.New("name of the cron must be alphanumeric, with '_', '.' or '-' inside")
+)
+
+var cronName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+func (m *Manager) CreateCron(c cron.Cron) error {
+	if !cronName.MatchString(c.Name) {
+		return ErrInvalidCronName
+	}
+
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	m.muCrons.Lock()
+	defer m.muCrons.Unlock()
+
+	if _, err := m.Store.GetResource(cron.Kind, c.Name); err == nil {
+		return ErrCronExists
+	}
+
+	c.Tasks, c.Active = make([]uuid.UUID, 0), 0
+	c.CreatedAt = time.Now()
+	c.LastScheduledAt, c.LastSuccessfulAt = time.Time{}, time.Time{}
+	return m.setCron(c)
+}
+
+func (m *Manager) Crons() ([]cron.Cron, error) {
+	all, err := consensus.AllResources[cron.Cron](m.Store, cron.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	crons := make([]cron.Cron, 0, len(all))
+	for _, c := range all {
+		crons = append(crons, c)
+	}
+	sort.Slice(crons, func(i, j int) bool { return crons[i].Name < crons[j].Name })
+	return crons, nil
+}
+
+// SuspendCron stops or resumes running the tasks of the cron on schedule,
+// the tasks that are running already are left as they are
+func (m *Manager) SuspendCron(name string, suspended bool) error {
+	m.muCrons.Lock()
+	defer m.muCrons.Unlock()
+
+	c, err := m.getCron(name)
+	if err != nil {
+		return err
+	}
+
+	c.Suspended = suspended
+	return m.setCron(c)
+}
+
+// DeleteCron removes the cron along with its history, the tasks that are
+// still running are stopped
+func (m *Manager) DeleteCron(name string) error {
+	m.muCrons.Lock()
+	defer m.muCrons.Unlock()
+
+	c, err := m.getCron(name)
+	if err != nil {
+		return err
+	}
+
+	cmd := consensus.NewRemoveResourceCommand(cron.Kind, name)
+	if _, err := m.Store.CommitChange(*cmd); err != nil {
+		return err
+	}
+
+	for _, id := range c.Tasks {
+		t, err := m.Store.GetTask(id)
+		if err != nil {
+			continue // NOTE(SergeyCherepiuk): Pending tasks are dropped by run
+		}
+
+		if t.State == task.Finished || t.State.Fail() {
+			m.Store.CommitChange(*consensus.NewRemoveTaskCommand(id))
+		} else {
+			m.EventsQueue.EnqueueNow(task.Event{Task: t, Desired: task.Finished})
+		}
+	}
+	return nil
+}
+
+func (m *Manager) getCron(name string) (cron.Cron, error) {
+	c, err := consensus.GetResource[cron.Cron](m.Store, cron.Kind, name)
+	if errors.Is(err, consensus.ErrResourceNotFound) {
+		return c, ErrCronNotFound
+	}
+	return c, err
+}
+
+func (m *Manager) setCron(c cron.Cron) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	cmd := consensus.NewSetResourceCommand(cron.Kind, c.Name, data)
+	_, err = m.Store.CommitChange(*cmd)
+	return err
+}
+
+// cronOwns fails for the tasks that their cron has let go of, since it has
+// been deleted or they have been replaced, so they are never run
+func (m *Manager) cronOwns(t task.Task) bool {
+	crons, err := m.Crons()
+	if err != nil {
+		return false
+	}
+
+	for _, c := range crons {
+		if c.Id == t.CronId {
+			return slices.Contains(c.Tasks, t.Id)
+		}
+	}
+	return false
+}
+
+// watchCrons runs the tasks of the crons once they are due, only the leader
+// does so
+func (m *Manager) watchCrons() {
+	ticker := time.NewTicker(CronSyncInterval)
+	defer ticker.Stop()
+
+	for m.tick(ticker) {
+		if !m.raft.IsLeader() {
+			continue
+		}
+
+		crons, err := m.Crons()
+		if err != nil {
+			continue
+		}
+
+		for _, c := range crons {
+			m.syncCron(c.Name)
+		}
+	}
+}
+
+func (m *Manager) syncCron(name string) {
+	m.muCrons.Lock()
+	defer m.muCrons.Unlock()
+
+	c, err := m.getCron(name)
+	if err != nil {
+		return
+	}
+
+	template := task.New(c.Container)
+	template.CronId = c.Id
+
+	// NOTE(SergeyCherepiuk): Tasks that are not in the store yet are waiting
+	// in the queue, so they are counted as active
+	var running, pending, succeeded, failed []uuid.UUID
+	lastSuccessfulAt := c.LastSuccessfulAt
+	for _, id := range c.Tasks {
+		t, err := m.Store.GetTask(id)
+		switch {
+		case err != nil:
+			pending = append(pending, id)
+		case t.State == task.Finished:
+			succeeded = append(succeeded, id)
+			if n := len(t.FinishedAt); n > 0 && t.FinishedAt[n-1].After(lastSuccessfulAt) {
+				lastSuccessfulAt = t.FinishedAt[n-1]
+			}
+		case t.State.Fail():
+			failed = append(failed, id)
+		default:
+			running = append(running, id)
+		}
+	}
+
+	m.requeueLostTasks(m.missingCronTasks, *template, pending)
+
+	updated := c
+	updated.LastSuccessfulAt = lastSuccessfulAt
+	updated.Tasks = slices.Clone(c.Tasks)
+
+	scheduledAt, due := dueTime(c, time.Now())
+	due = due && !c.Suspended
+	if due {
+		updated.LastScheduledAt = scheduledAt
+
+		switch c.ConcurrencyPolicy {
+		case cron.Forbid:
+			due = len(running)+len(pending) == 0
+		case cron.Replace:
+			for _, id := range running {
+				if t, err := m.Store.GetTask(id); err == nil {
+					m.EventsQueue.EnqueueNow(task.Event{Task: t, Desired: task.Finished})
+				}
+			}
+			updated.Tasks = slices.DeleteFunc(updated.Tasks, func(id uuid.UUID) bool {
+				return slices.Contains(pending, id)
+			})
+			running, pending = nil, nil
+		}
+	}
+
+	if due {
+		t := *template
+		updated.Tasks = append(updated.Tasks, t.Id)
+		pending = append(pending, t.Id)
+		m.EventsQueue.EnqueueNow(task.Event{Task: t, Desired: task.Running})
+	}
+
+	expired := make([]uuid.UUID, 0)
+	expired = append(expired, expiredHistory(succeeded, c.SuccessfulHistoryLimit)...)
+	expired = append(expired, expiredHistory(failed, c.FailedHistoryLimit)...)
+	updated.Tasks = slices.DeleteFunc(updated.Tasks, func(id uuid.UUID) bool {
+		return slices.Contains(expired, id)
+	})
+	updated.Active = len(running) + len(pending)
+
+	if !cronChanged(c, updated) {
+		return
+	}
+
+	if err := m.setCron(updated); err != nil {
+		return
+	}
+
+	// NOTE(SergeyCherepiuk): Expired tasks are removed from the store only
+	// once the cron has let go of them, otherwise they look like lost ones
+	for _, id := range expired {
+		m.Store.CommitChange(*consensus.NewRemoveTaskCommand(id))
+	}
+}
+
+// dueTime returns the time the task of the cron should have been run at
+// last, if it hasn't been run yet. Only the latest of the missed runs is
+// made up for.
+func dueTime(c cron.Cron, now time.Time) (time.Time, bool) {
+	schedule, err := cron.ParseSchedule(c.Schedule)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	last := c.LastScheduledAt
+	if last.IsZero() {
+		last = c.CreatedAt
+	}
+
+	next, err := schedule.Next(last, loc)
+	if err != nil || next.After(now) {
+		return time.Time{}, false
+	}
+
+	for {
+		after, err := schedule.Next(next, loc)
+		if err != nil || after.After(now) {
+			return next, true
+		}
+		next = after
+	}
+}
+
+// expiredHistory returns the oldest of the tasks above the limit
+func expiredHistory(ids []uuid.UUID, limit int) []uuid.UUID {
+	if len(ids) <= limit {
+		return nil
+	}
+	return ids[:len(ids)-limit]
+}
+
+func cronChanged(before, after cron.Cron) bool {
+	return !slices.Equal(before.Tasks, after.Tasks) ||
+		before.Active != after.Active ||
+		!before.LastScheduledAt.Equal(after.LastScheduledAt) ||
+		!before.LastSuccessfulAt.Equal(after.LastSuccessfulAt)
+}
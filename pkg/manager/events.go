@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+)
+
+// Events that nothing else would bring back are kept in the store until
+// they are handled, so that they outlive the queue of the leader that
+// accepted them. Jobs, crons and services find their lost tasks on their own.
+const EventKind = "events"
+
+func eventId(event task.Event) string {
+	return fmt.Sprintf("%s:%s", event.Task.Id, event.Desired)
+}
+
+func newSetEventCommand(event task.Event) (*consensus.Command, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return consensus.NewSetResourceCommand(EventKind, eventId(event), data), nil
+}
+
+func (m *Manager) enqueueNow(event task.Event) {
+	m.enqueueWithDelay(0, event)
+}
+
+// enqueueWithDelay queues the event even if it fails to persist it, losing
+// the event on failover is still better than not handling it at all
+func (m *Manager) enqueueWithDelay(delay time.Duration, event task.Event) {
+	if cmd, err := newSetEventCommand(event); err == nil {
+		m.Store.CommitChange(*cmd)
+	}
+	m.EventsQueue.EnqueueWithDelay(delay, event)
+}
+
+func (m *Manager) forgetEvent(event task.Event) {
+	if _, err := m.Store.GetResource(EventKind, eventId(event)); err != nil {
+		return
+	}
+
+	cmd := consensus.NewRemoveResourceCommand(EventKind, eventId(event))
+	m.Store.CommitChange(*cmd)
+}
+
+// watchLeadership puts the persisted events back to the queue whenever the
+// manager becomes the leader
+func (m *Manager) watchLeadership() {
+	ticker := time.NewTicker(EventQueueInterval)
+	defer ticker.Stop()
+
+	wasLeader := false
+	for m.tick(ticker) {
+		isLeader := m.IsLeader()
+		if isLeader && !wasLeader {
+			m.recoverEvents()
+		}
+		wasLeader = isLeader
+	}
+}
+
+func (m *Manager) recoverEvents() {
+	queued := make(map[string]bool)
+	for _, event := range m.EventsQueue.GetAll() {
+		queued[eventId(event)] = true
+	}
+
+	events, err := consensus.AllResources[task.Event](m.Store, EventKind)
+	if err != nil {
+		return
+	}
+
+	for id, event := range events {
+		if !queued[id] {
+			m.EventsQueue.EnqueueNow(event)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package manager
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/job"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/google/uuid"
+)
+
+const JobSyncInterval = time.Second
+
+func (m *Manager) RunJobs(jobs []job.Job) error {
+	for _, j := range jobs {
+		j.Status = job.Active
+		j.StartedAt = time.Now()
+		if err := m.setJob(j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) Jobs() ([]job.Job, error) {
+	jobs, err := consensus.AllResources[job.Job](m.Store, job.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]job.Job, 0, len(jobs))
+	for _, j := range jobs {
+		list = append(list, j)
+	}
+	slices.SortFunc(list, func(a, b job.Job) int { return a.StartedAt.Compare(b.StartedAt) })
+	return list, nil
+}
+
+func (m *Manager) setJob(j job.Job) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	cmd := consensus.NewSetResourceCommand(job.Kind, j.Id.String(), data)
+	_, err = m.Store.CommitChange(*cmd)
+	return err
+}
+
+// jobOwns fails for the tasks of the jobs that have ended or that the job
+// doesn't know about, so the tasks that are still waiting in the queue are
+// never run
+func (m *Manager) jobOwns(t task.Task) bool {
+	j, err := consensus.GetResource[job.Job](m.Store, job.Kind, t.JobId.String())
+	return err == nil && !j.Done() && slices.Contains(j.Tasks, t.Id)
+}
+
+// watchJobs drives the active jobs towards their completions, only the
+// leader does so
+func (m *Manager) watchJobs() {
+	ticker := time.NewTicker(JobSyncInterval)
+	defer ticker.Stop()
+
+	for m.tick(ticker) {
+		if !m.raft.IsLeader() {
+			continue
+		}
+
+		jobs, err := m.Jobs()
+		if err != nil {
+			continue
+		}
+
+		for _, j := range jobs {
+			if !j.Done() {
+				m.syncJob(j)
+			}
+		}
+	}
+}
+
+func (m *Manager) syncJob(j job.Job) {
+	// NOTE(SergeyCherepiuk): Tasks that are not in the store yet are waiting
+	// in the queue, so they are counted as active
+	var active, pending []uuid.UUID
+	succeeded, failed := 0, 0
+	for _, id := range j.Tasks {
+		t, err := m.Store.GetTask(id)
+		switch {
+		case err != nil:
+			pending = append(pending, id)
+		case t.State == task.Finished:
+			succeeded++
+		case t.State.Fail():
+			failed++
+		default:
+			active = append(active, id)
+		}
+	}
+
+	template := task.New(j.Container)
+	template.JobId = j.Id
+	m.requeueLostTasks(m.missingJobTasks, *template, pending)
+
+	updated := j
+	updated.Active, updated.Succeeded, updated.Failed = len(active)+len(pending), succeeded, failed
+
+	var created []task.Event
+	deadlineExceeded := j.ActiveDeadline > 0 && time.Since(j.StartedAt) > j.ActiveDeadline
+	switch {
+	case succeeded >= j.Completions:
+		updated.Status = job.Complete
+	case failed > j.BackoffLimit:
+		updated.Status, updated.Reason = job.Failed, job.BackoffLimitExceeded
+	case deadlineExceeded:
+		updated.Status, updated.Reason = job.Failed, job.DeadlineExceeded
+	default:
+		missing := min(j.Parallelism, j.Completions-succeeded) - updated.Active
+		for i := 0; i < missing; i++ {
+			t := task.New(j.Container)
+			t.JobId = j.Id
+			updated.Tasks = append(updated.Tasks, t.Id)
+			updated.Active++
+
+			created = append(created, task.Event{Task: *t, Desired: task.Running})
+		}
+	}
+
+	if updated.Done() {
+		updated.Active = 0
+		updated.FinishedAt = time.Now()
+	}
+
+	if !jobChanged(j, updated) {
+		return
+	}
+
+	if err := m.setJob(updated); err != nil {
+		return
+	}
+
+	// NOTE(SergeyCherepiuk): New tasks are queued only once the job knows
+	// about them, otherwise they would run untracked if the commit failed
+	for _, event := range created {
+		m.EventsQueue.EnqueueWithDelay(replaceBackOff(failed), event)
+	}
+
+	// NOTE(SergeyCherepiuk): Once the job has ended, its tasks that are
+	// still running are stopped and the pending ones are dropped by run
+	if updated.Done() {
+		for _, id := range active {
+			if t, err := m.Store.GetTask(id); err == nil {
+				m.EventsQueue.EnqueueNow(task.Event{Task: t, Desired: task.Finished})
+			}
+		}
+	}
+}
+
+// requeueLostTasks runs again the pending tasks that are neither in the
+// store nor in the queue, e.g. the queue of the previous leader is gone. A
+// task is considered lost only if it is missing on two syncs in a row,
+// since it leaves the queue a moment before it is put to the store
+func (m *Manager) requeueLostTasks(missing map[uuid.UUID]bool, template task.Task, pending []uuid.UUID) {
+	if len(pending) == 0 {
+		return
+	}
+
+	queued := make(map[uuid.UUID]bool)
+	for _, event := range m.EventsQueue.GetAll() {
+		queued[event.Task.Id] = true
+	}
+
+	for _, id := range pending {
+		if queued[id] {
+			delete(missing, id)
+			continue
+		}
+
+		if !missing[id] {
+			missing[id] = true
+			continue
+		}
+
+		delete(missing, id)
+		t := template
+		t.Id = id
+		m.EventsQueue.EnqueueNow(task.Event{Task: t, Desired: task.Running})
+	}
+}
+
+func jobChanged(before, after job.Job) bool {
+	return before.Status != after.Status ||
+		len(before.Tasks) != len(after.Tasks) ||
+		before.Active != after.Active ||
+		before.Succeeded != after.Succeeded ||
+		before.Failed != after.Failed
+}
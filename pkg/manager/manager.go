@@ -1,7 +1,9 @@
 package manager
 
 import (
+	"errors"
 	"net/http"
+	"sync"
 	"time"
 
 	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
@@ -23,46 +25,115 @@ const (
 	HeartbeatInterval    = 2 * time.Second
 	BackOffResetInterval = 5 * time.Minute
 
+	MaxReplayCommands = 256
+	SnapshotChunkSize = 64 * 1024
+
 	BackOffTimeCoefficient = 2
+
+	// Failed tasks of the jobs and services are replaced with a delay,
+	// doubled with every failure, up to the limit
+	ReplaceBackOffBase  = time.Second
+	ReplaceBackOffLimit = 5 * time.Minute
 )
 
+var ErrTaskNotAccepted = errors.New("task is not accepted by the worker")
+
 type Manager struct {
 	id                  uuid.UUID
 	node                node.Node
 	scheduler           scheduler.Scheduler
+	raft                *consensus.Raft
 	Store               consensus.Store
 	EventsQueue         *queue.TimeBasedQueue[task.Event]
 	WorkerMessagesQueue *queue.Queue[worker.Message]
+	syncingWorkers      sync.Map
+	hostNetwork         sync.Map           // NOTE(SergeyCherepiuk): Whether the worker shares the network of the host, by its id
+	missingJobTasks     map[uuid.UUID]bool // NOTE(SergeyCherepiuk): Used by watchJobs only
+	missingCronTasks    map[uuid.UUID]bool // NOTE(SergeyCherepiuk): Used by watchCrons only
+	missingServiceTasks map[uuid.UUID]bool // NOTE(SergeyCherepiuk): Used by watchServices only
+	muCrons             sync.Mutex
+	muServices          sync.Mutex
+	done                chan struct{}
+	stopOnce            sync.Once
 }
 
-func New(node node.Node, scheduler scheduler.Scheduler) *Manager {
+func New(node node.Node, scheduler scheduler.Scheduler, store consensus.Store, peers []string) *Manager {
+	raft := consensus.NewRaft(node.Addr.String(), peers, store)
+
 	manager := Manager{
 		id:                  uuid.New(),
 		node:                node,
 		scheduler:           scheduler,
-		Store:               consensus.NewLocalStore(),
+		raft:                raft,
+		Store:               raft,
 		EventsQueue:         queue.NewTimeBasedQueue[task.Event](EventQueueInterval),
 		WorkerMessagesQueue: queue.NewQueue[worker.Message](0),
+		missingJobTasks:     make(map[uuid.UUID]bool),
+		missingCronTasks:    make(map[uuid.UUID]bool),
+		missingServiceTasks: make(map[uuid.UUID]bool),
+		done:                make(chan struct{}),
 	}
 
+	raft.Start()
 	go manager.watchEventsQueue()
+	go manager.watchLeadership()
 	go manager.watchWorkerMessageQueue()
 	go manager.sendHeartbeats()
+	go manager.watchJobs()
+	go manager.watchCrons()
+	go manager.watchServices()
 
 	return &manager
 }
 
+// Stop brings down every loop of the manager along with its raft node, the
+// manager neither leads nor votes afterwards
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.done)
+		m.raft.Stop()
+		m.EventsQueue.Close()
+	})
+}
+
+func (m *Manager) stopped() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// tick waits for the next tick, it reports false once the manager is stopped
+func (m *Manager) tick(ticker *time.Ticker) bool {
+	select {
+	case <-m.done:
+		return false
+	case <-ticker.C:
+		return true
+	}
+}
+
+func (m *Manager) IsLeader() bool {
+	return m.raft.IsLeader()
+}
+
 func (m *Manager) AddWorker(wid uuid.UUID, addr node.Addr) {
+	if w, err := m.Store.GetWorker(wid); err == nil && w.Addr.String() == addr.String() {
+		return // NOTE(SergeyCherepiuk): Worker re-registers after leadership moves
+	}
+
 	worker := consensus.Worker{
 		Addr:  addr,
 		Tasks: make(map[uuid.UUID]task.Task),
 	}
-	cmd := consensus.NewSetWorkerCommand(m.Store.LastIndex()+1, wid, worker)
+	cmd := consensus.NewSetWorkerCommand(wid, worker)
 	m.Store.CommitChange(*cmd) // Error is ignored (SetWorker command cannot return an error)
 }
 
 func (m *Manager) RemoveWorker(wid uuid.UUID) error {
-	cmd := consensus.NewRemoveWorkerCommand(m.Store.LastIndex()+1, wid)
+	cmd := consensus.NewRemoveWorkerCommand(wid)
 	if _, err := m.Store.CommitChange(*cmd); err != nil {
 		return err
 	}
@@ -74,19 +145,35 @@ func (m *Manager) WorkerTasks(wid uuid.UUID) []task.Task {
 	if err != nil {
 		return make([]task.Task, 0)
 	}
+
+	w.MuTasks.RLock()
+	defer w.MuTasks.RUnlock()
 	return maps.Values(w.Tasks)
 }
 
 func (m *Manager) Tasks() []task.Task {
 	tasks := make([]task.Task, 0)
-	for _, worker := range m.Store.AllWorkers() {
-		tasks = append(tasks, maps.Values(worker.Tasks)...)
+	for id := range m.Store.AllWorkers() {
+		tasks = append(tasks, m.WorkerTasks(id)...)
 	}
 	return tasks
 }
 
 func (m *Manager) watchEventsQueue() {
-	for event := range m.EventsQueue.Out() {
+	for {
+		var event task.Event
+		select {
+		case <-m.done:
+			return
+		case event = <-m.EventsQueue.Out():
+		}
+
+		// NOTE(SergeyCherepiuk): The new leader takes over the persisted events,
+		// the rest are brought back by the controllers that issued them
+		if !m.IsLeader() {
+			continue
+		}
+
 		if m.Store.WorkersNumber() == 0 { // NOTE(SergeyCherepiuk): No workers available
 			m.EventsQueue.EnqueueNow(event)
 			time.Sleep(EventQueueInterval)
@@ -103,67 +190,133 @@ func (m *Manager) watchEventsQueue() {
 			m.scheduleRestart(event.Task)
 		}
 
+		// NOTE(SergeyCherepiuk): Waiting for the worker the task is pinned to
+		// must not hold up the other tasks
+		if errors.Is(err, scheduler.ErrPinnedWorkerAbsent) {
+			m.EventsQueue.EnqueueWithDelay(HeartbeatInterval, event)
+			continue
+		}
+
 		if err != nil {
 			m.EventsQueue.EnqueueNow(event)
 			time.Sleep(EventQueueInterval)
 			continue
 		}
+
+		m.forgetEvent(event)
 	}
 }
 
 func (m *Manager) watchWorkerMessageQueue() {
-	for {
+	for !m.stopped() {
 		message, err := m.WorkerMessagesQueue.Dequeue()
 		if err != nil {
 			time.Sleep(MessageQueueInterval)
 			continue
 		}
 
-		t := message.Task
+		// NOTE(SergeyCherepiuk): Messages are retried in place rather than put
+		// back, since the later messages about the same task must not overtake
+		for !m.stopped() {
+			err := m.handleWorkerMessage(message)
+			if err == nil {
+				break
+			}
+
+			if !errors.Is(err, consensus.ErrConditionFailed) {
+				time.Sleep(MessageQueueInterval)
+			}
+		}
+	}
+}
 
-		lastIndex := m.Store.LastIndex()
-		cmd := consensus.NewSetTaskCommand(lastIndex+1, message.From, t)
-		m.Store.CommitChange(*cmd)
+// handleWorkerMessage records the reported task, the commit is conditioned
+// on the task as it has just been read, so a failed condition only means
+// that the task has to be read once again
+func (m *Manager) handleWorkerMessage(message worker.Message) error {
+	t := message.Task
 
-		if t.State.Fail() || t.State == task.Finished {
-			rp := message.Task.Container.Config.RestartPolicy
+	workerId, _, err := m.Store.GetWorkerByTaskId(t.Id)
+	if err != nil || workerId != message.From { // NOTE(SergeyCherepiuk): Task has been removed or restarted already
+		return nil
+	}
 
-			shouldBeRestarted := rp == container.Always ||
-				(rp == container.OnFailure && t.State.Fail())
+	stored, err := m.Store.GetTask(t.Id)
+	if err != nil {
+		return nil
+	}
 
-			if shouldBeRestarted {
-				var desired task.State
-				if t.State == task.FailedOnStartup {
-					desired = task.RestartingWithBackOff
-				} else {
-					desired = task.Running
-				}
+	rp := t.Container.Config.RestartPolicy
+	shouldBeRestarted := (t.State.Fail() || t.State == task.Finished) &&
+		(rp == container.Always || (rp == container.OnFailure && t.State.Fail()))
 
-				cmd := consensus.NewRemoveTaskCommand(lastIndex+2, t.Id)
-				m.Store.CommitChange(*cmd)
+	var event task.Event
+	cmds := []consensus.Command{*consensus.NewSetTaskCommand(message.From, t)}
+	if shouldBeRestarted {
+		event = task.Event{Task: t, Desired: task.Running}
+		if t.State == task.FailedOnStartup {
+			event.Desired = task.RestartingWithBackOff
+		}
 
-				event := task.Event{Task: t, Desired: desired}
-				m.EventsQueue.EnqueueNow(event)
-			}
+		// NOTE(SergeyCherepiuk): The event is persisted along with the removal
+		// of the task, otherwise the task is gone if the leader fails in between
+		setEvent, err := newSetEventCommand(event)
+		if err != nil {
+			return nil
 		}
+		cmds = append(cmds, *consensus.NewRemoveTaskCommand(t.Id), *setEvent)
 	}
+
+	condition := consensus.Condition{TaskId: t.Id, WorkerId: message.From, State: stored.State}
+	cmd := consensus.NewConditionalCommand([]consensus.Condition{condition}, cmds...)
+	if _, err := m.Store.CommitChange(*cmd); err != nil {
+		return err
+	}
+
+	if shouldBeRestarted {
+		m.EventsQueue.EnqueueNow(event)
+	}
+	return nil
 }
 
 func (m *Manager) sendHeartbeats() {
-	for range time.Tick(HeartbeatInterval) {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	for m.tick(ticker) {
+		if !m.raft.IsLeader() {
+			continue
+		}
+
+		heartbeat := worker.Heartbeat{
+			ManagerAddr: m.node.Addr.String(),
+			LastIndex:   m.Store.LastIndex(),
+		}
+
 		for wid, worker := range m.Store.AllWorkers() {
-			resp, err := httpclient.Post(worker.Addr.String(), "/heartbeat", m.Store.LastIndex())
+			resp, err := httpclient.Post(worker.Addr.String(), "/heartbeat", heartbeat)
 
 			rescheduleTasks := err != nil || resp == nil ||
 				resp.Body == nil || resp.StatusCode != http.StatusOK
 
 			if rescheduleTasks {
-				cmd := consensus.NewRemoveWorkerCommand(m.Store.LastIndex()+1, wid)
-				m.Store.CommitChange(*cmd)
-
-				for _, t := range worker.Tasks {
+				tasks := m.WorkerTasks(wid)
+				events := make([]task.Event, 0, len(tasks))
+				cmds := []consensus.Command{*consensus.NewRemoveWorkerCommand(wid)}
+				for _, t := range tasks {
 					t.State = task.FailedAfterStartup
 					event := task.Event{Task: t, Desired: task.Running}
+					if setEvent, err := newSetEventCommand(event); err == nil {
+						cmds = append(cmds, *setEvent)
+					}
+					events = append(events, event)
+				}
+
+				if _, err := m.Store.CommitChange(*consensus.NewBatchCommand(cmds...)); err != nil {
+					continue
+				}
+
+				for _, event := range events {
 					m.EventsQueue.EnqueueNow(event)
 				}
 
@@ -176,27 +329,67 @@ func (m *Manager) sendHeartbeats() {
 			}
 
 			if off > 0 {
-				cmds := m.Store.GetLastNCommands(off)
-				go m.broadcastCommandsToWorker(worker.Addr, cmds...)
+				go m.syncWorker(worker.Addr, off)
 			}
 		}
 	}
 }
 
 func (m *Manager) run(t task.Task) error {
-	workers := m.Store.AllWorkers()
-	workerId, worker, err := m.scheduler.SelectWorker(t, workers)
+	if t.JobId != uuid.Nil && !m.jobOwns(t) {
+		return nil
+	}
+
+	if t.CronId != uuid.Nil && !m.cronOwns(t) {
+		return nil
+	}
+
+	if t.ServiceId != uuid.Nil && !m.serviceOwns(t) {
+		return nil
+	}
+
+	if _, err := m.Store.GetTask(t.Id); err == nil { // NOTE(SergeyCherepiuk): Placed by the previous leader already
+		return nil
+	}
+
+	workers := m.withoutPortConflicts(t, m.Store.AllWorkers())
+	selectWorker := m.scheduler.SelectWorker
+	if t.PinnedTo != nil {
+		selectWorker = scheduler.SelectPinned
+	}
+
+	workerId, worker, err := selectWorker(t, workers)
 	if err != nil {
 		return err
 	}
 
+	if t.PinnedTo == nil && t.Container.Config.HasPersistentMounts() {
+		addr := worker.Addr
+		t.PinnedTo = &addr
+	}
+
 	t.State = task.Scheduled
 
-	cmd := consensus.NewSetTaskCommand(m.Store.LastIndex()+1, workerId, t)
-	m.Store.CommitChange(*cmd)
+	cmd := consensus.NewSetTaskCommand(workerId, t)
+	if _, err := m.Store.CommitChange(*cmd); err != nil {
+		return err
+	}
 
-	httpclient.Post(worker.Addr.String(), "/task/run", t)
-	return nil
+	resp, err := httpclient.PostWithHeader(worker.Addr.String(), "/task/run", t, m.registryAuth(t))
+	if err == nil && resp.StatusCode != http.StatusBadRequest {
+		return nil // NOTE(SergeyCherepiuk): Worker reports the failed start by itself
+	}
+	if err == nil {
+		err = ErrTaskNotAccepted
+	}
+
+	// NOTE(SergeyCherepiuk): Worker has never got the task, it is taken back
+	// unless the worker has reported it in the meantime, so the retry places
+	// it again
+	condition := consensus.Condition{TaskId: t.Id, WorkerId: workerId, State: task.Scheduled}
+	rollback := consensus.NewConditionalCommand([]consensus.Condition{condition}, *consensus.NewRemoveTaskCommand(t.Id))
+	m.Store.CommitChange(*rollback)
+	return err
 }
 
 func (m *Manager) finish(t task.Task) error {
@@ -224,24 +417,77 @@ func (m *Manager) scheduleRestart(t task.Task) {
 	}
 
 	event := task.Event{Task: t, Desired: task.Running}
-	m.EventsQueue.EnqueueWithDelay(backOffTime, event)
+	m.enqueueWithDelay(backOffTime, event)
 }
 
-func (m *Manager) broadcastCommandsToWorker(addr node.Addr, cmds ...consensus.Command) {
-	resp, err := httpclient.Post(addr.String(), "/store/command", cmds)
-	if err != nil {
+func replaceBackOff(failed int) time.Duration {
+	if failed == 0 {
+		return 0
+	}
+
+	backOff := ReplaceBackOffBase
+	for i := 1; i < failed && backOff < ReplaceBackOffLimit; i++ {
+		backOff *= BackOffTimeCoefficient
+	}
+	return min(backOff, ReplaceBackOffLimit)
+}
+
+func (m *Manager) syncWorker(addr node.Addr, off int) {
+	if _, syncing := m.syncingWorkers.LoadOrStore(addr.String(), struct{}{}); syncing {
 		return
 	}
+	defer m.syncingWorkers.Delete(addr.String())
 
-	if resp.StatusCode == http.StatusCreated {
+	if off > MaxReplayCommands || off > m.Store.LogSize() {
+		m.sendSnapshotToWorker(addr)
 		return
 	}
 
+	cmds := m.Store.GetLastNCommands(off)
+	if off := m.broadcastCommandsToWorker(addr, cmds...); off > 0 {
+		m.sendSnapshotToWorker(addr) // NOTE(SergeyCherepiuk): Replaying again may never converge
+	}
+}
+
+func (m *Manager) broadcastCommandsToWorker(addr node.Addr, cmds ...consensus.Command) int {
+	resp, err := httpclient.Post(addr.String(), "/store/command", consensus.RedactCommands(cmds))
+	if err != nil {
+		return 0
+	}
+
+	if resp.StatusCode == http.StatusCreated {
+		return 0
+	}
+
 	var off int
 	httpinternal.Body(resp, &off)
+	return off
+}
 
-	if off > 0 {
-		cmds = m.Store.GetLastNCommands(off)
-		m.broadcastCommandsToWorker(addr, cmds...)
+func (m *Manager) sendSnapshotToWorker(addr node.Addr) {
+	snapshot := m.Store.Snapshot()
+
+	// NOTE(SergeyCherepiuk): Commands committed since the snapshot are read in
+	// one go, reading the last index first could leave a gap before the tail
+	tail, err := m.Store.GetCommandsAfter(snapshot.LastIndex)
+	if err != nil {
+		return
+	}
+
+	consensus.RedactResources(snapshot.State.Resources)
+	chunks, err := consensus.SplitSnapshot(snapshot, consensus.RedactCommands(tail), SnapshotChunkSize)
+	if err != nil {
+		return
+	}
+
+	for _, chunk := range chunks {
+		resp, err := httpclient.Post(addr.String(), "/store/snapshot", chunk)
+		if err != nil {
+			return
+		}
+
+		if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusCreated {
+			return
+		}
 	}
 }
@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"slices"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/SergeyCherepiuk/fleet/pkg/worker"
+	"github.com/google/uuid"
+)
+
+// withoutPortConflicts drops the workers that share the network of the host
+// with their tasks and already run a task exposing one of the ports of t
+func (m *Manager) withoutPortConflicts(t task.Task, ws map[uuid.UUID]consensus.Worker) map[uuid.UUID]consensus.Worker {
+	ports := t.Container.Config.ExposedPorts
+	if len(ports) == 0 {
+		return ws
+	}
+
+	available := make(map[uuid.UUID]consensus.Worker, len(ws))
+	for id, w := range ws {
+		if !exposesAny(w, t.Id, ports) || !m.sharesHostNetwork(id, w) {
+			available[id] = w
+		}
+	}
+	return available
+}
+
+func exposesAny(w consensus.Worker, except uuid.UUID, ports []uint16) bool {
+	w.MuTasks.RLock()
+	defer w.MuTasks.RUnlock()
+
+	for id, t := range w.Tasks {
+		occupied := t.State == task.Scheduled || t.State == task.Running || t.State == task.Unhealthy
+		if id == except || !occupied {
+			continue
+		}
+
+		for _, port := range t.Container.Config.ExposedPorts {
+			if slices.Contains(ports, port) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sharesHostNetwork asks the worker about its runtime once, the runtime of
+// the worker does not change while it is registered under the same id
+func (m *Manager) sharesHostNetwork(id uuid.UUID, w consensus.Worker) bool {
+	if shares, ok := m.hostNetwork.Load(id); ok {
+		return shares.(bool)
+	}
+
+	resp, err := httpclient.Get(w.Addr.String(), "/info")
+	if err != nil {
+		return false
+	}
+
+	var info worker.Info
+	if err := httpinternal.Body(resp, &info); err != nil {
+		return false
+	}
+
+	m.hostNetwork.Store(id, info.HostNetwork)
+	return info.HostNetwork
+}
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+)
+
+var (
+	ErrCredentialsNotFound    = errors.New("registry credentials are not found")
+	ErrInvalidCredentialsName = errors.New("name of the registry credentials must be alphanumeric, with '_', '.' or '-' inside")
+)
+
+var credentialsName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+func init() {
+	consensus.RegisterRedaction(image.CredentialsKind, redactCredentials)
+}
+
+func redactCredentials(resource json.RawMessage) json.RawMessage {
+	var creds image.Credentials
+	if err := json.Unmarshal(resource, &creds); err != nil {
+		return nil
+	}
+
+	creds.Password = ""
+	redacted, _ := json.Marshal(creds)
+	return redacted
+}
+
+// CredentialsInfo describes the registry credentials without revealing the
+// password
+type CredentialsInfo struct {
+	Name     string
+	Server   string
+	Username string
+}
+
+func (m *Manager) SetCredentials(name string, creds image.Credentials) error {
+	if !credentialsName.MatchString(name) {
+		return ErrInvalidCredentialsName
+	}
+
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+
+	cmd := consensus.NewSetResourceCommand(image.CredentialsKind, name, data)
+	_, err = m.Store.CommitChange(*cmd)
+	return err
+}
+
+func (m *Manager) RemoveCredentials(name string) error {
+	cmd := consensus.NewRemoveResourceCommand(image.CredentialsKind, name)
+	_, err := m.Store.CommitChange(*cmd)
+	if errors.Is(err, consensus.ErrResourceNotFound) {
+		return ErrCredentialsNotFound
+	}
+	return err
+}
+
+func (m *Manager) Credentials() ([]CredentialsInfo, error) {
+	all, err := consensus.AllResources[image.Credentials](m.Store, image.CredentialsKind)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]CredentialsInfo, 0, len(all))
+	for name, creds := range all {
+		infos = append(infos, CredentialsInfo{Name: name, Server: creds.Server, Username: creds.Username})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// checkCredentials fails if any of the tasks refers to the registry
+// credentials that don't exist, so the mistake is caught before the tasks
+// are scheduled
+func (m *Manager) checkCredentials(tasks []task.Task) error {
+	for _, t := range tasks {
+		name := t.Container.Image.Credentials
+		if name == "" {
+			continue
+		}
+
+		if _, err := m.Store.GetResource(image.CredentialsKind, name); err != nil {
+			return fmt.Errorf("%w: %q", ErrCredentialsNotFound, name)
+		}
+	}
+	return nil
+}
+
+// registryAuth hands the credentials of the task's image only to the worker
+// the task is placed on. The worker fails the task if they have been removed
+// in the meantime
+func (m *Manager) registryAuth(t task.Task) http.Header {
+	name := t.Container.Image.Credentials
+	if name == "" {
+		return nil
+	}
+
+	creds, err := consensus.GetResource[image.Credentials](m.Store, image.CredentialsKind, name)
+	if err != nil {
+		return nil
+	}
+
+	header := make(http.Header)
+	header.Set(image.AuthHeader, image.EncodeAuth(creds))
+	return header
+}
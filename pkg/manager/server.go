@@ -1,12 +1,22 @@
 package manager
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 
 	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/attach"
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/cron"
 	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/SergeyCherepiuk/fleet/pkg/job"
 	"github.com/SergeyCherepiuk/fleet/pkg/node"
+	"github.com/SergeyCherepiuk/fleet/pkg/service"
 	"github.com/SergeyCherepiuk/fleet/pkg/task"
 	"github.com/SergeyCherepiuk/fleet/pkg/worker"
 	"github.com/google/uuid"
@@ -14,10 +24,14 @@ import (
 )
 
 func StartServer(addr string, manager *Manager) error {
+	return NewServer(manager).Start(addr)
+}
+
+func NewServer(manager *Manager) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
 
-	workerGroup := e.Group("/worker")
+	workerGroup := e.Group("/worker", redirectToLeader(manager))
 	workerWithIdGroup := workerGroup.Group("/:id", parseId)
 
 	workerWithIdGroup.POST("", func(c echo.Context) error {
@@ -45,6 +59,21 @@ func StartServer(addr string, manager *Manager) error {
 		return c.NoContent(http.StatusOK)
 	})
 
+	workerWithIdGroup.POST("/gc", func(c echo.Context) error {
+		w, err := manager.Store.GetWorker(c.Get("id").(uuid.UUID))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+
+		resp, err := httpclient.Post(w.Addr.String(), "/gc?"+c.QueryString(), nil)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadGateway, err)
+		}
+		defer resp.Body.Close()
+
+		return c.Stream(resp.StatusCode, resp.Header.Get(echo.HeaderContentType), resp.Body)
+	})
+
 	workerGroup.POST("/event", func(c echo.Context) error {
 		var event task.Event
 		if err := c.Bind(&event); err != nil {
@@ -54,10 +83,27 @@ func StartServer(addr string, manager *Manager) error {
 			)
 		}
 
-		manager.EventsQueue.EnqueueNow(event)
+		manager.enqueueNow(event)
+		return c.NoContent(http.StatusCreated)
+	})
+
+	workerGroup.POST("/messages", func(c echo.Context) error {
+		var messages []worker.Message
+		if err := c.Bind(&messages); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid message format: %w", err),
+			)
+		}
+
+		for _, message := range messages {
+			manager.WorkerMessagesQueue.Enqueue(message)
+		}
 		return c.NoContent(http.StatusCreated)
 	})
 
+	// NOTE(SergeyCherepiuk): Kept for the workers that still send the messages
+	// one at a time, they are not upgraded along with the managers
 	workerGroup.POST("/message", func(c echo.Context) error {
 		var message worker.Message
 		if err := c.Bind(&message); err != nil {
@@ -90,7 +136,9 @@ func StartServer(addr string, manager *Manager) error {
 		return c.JSON(http.StatusOK, infos)
 	})
 
-	e.POST("/task/run", func(c echo.Context) error {
+	taskGroup := e.Group("/task", redirectToLeader(manager))
+
+	taskGroup.POST("/run", func(c echo.Context) error {
 		var tasks []task.Task
 		if err := c.Bind(&tasks); err != nil {
 			return echo.NewHTTPError(
@@ -99,14 +147,18 @@ func StartServer(addr string, manager *Manager) error {
 			)
 		}
 
+		if err := manager.checkCredentials(tasks); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
 		for _, t := range tasks {
 			event := task.Event{Task: t, Desired: task.Running}
-			manager.EventsQueue.EnqueueNow(event)
+			manager.enqueueNow(event)
 		}
 		return c.NoContent(http.StatusCreated)
 	})
 
-	e.POST("/task/stop/:id", func(c echo.Context) error {
+	taskGroup.POST("/stop/:id", func(c echo.Context) error {
 		id := c.Get("id").(uuid.UUID)
 		t, err := manager.Store.GetTask(id)
 		if err != nil {
@@ -114,11 +166,67 @@ func StartServer(addr string, manager *Manager) error {
 		}
 
 		event := task.Event{Task: t, Desired: task.Finished}
-		manager.EventsQueue.EnqueueNow(event)
+		manager.enqueueNow(event)
 		return c.NoContent(http.StatusCreated)
 	}, parseId)
 
-	e.GET("/task/list", func(c echo.Context) error {
+	taskGroup.GET("/logs/:id", func(c echo.Context) error {
+		id := c.Get("id").(uuid.UUID)
+		_, w, err := manager.Store.GetWorkerByTaskId(id)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, consensus.ErrTaskNotFound)
+		}
+
+		endpoint := fmt.Sprintf("/task/logs/%s?%s", id, c.QueryString())
+		return proxy(c, w.Addr, endpoint)
+	}, parseId)
+
+	taskGroup.GET("/exec/:id", func(c echo.Context) error {
+		id := c.Get("id").(uuid.UUID)
+		_, w, err := manager.Store.GetWorkerByTaskId(id)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, consensus.ErrTaskNotFound)
+		}
+
+		endpoint := fmt.Sprintf("/task/exec/%s?%s", id, c.QueryString())
+		upstream, resp, err := attach.Dial(w.Addr.String(), endpoint)
+		if errors.Is(err, attach.ErrNotUpgraded) {
+			defer resp.Body.Close()
+			return c.Stream(resp.StatusCode, resp.Header.Get(echo.HeaderContentType), resp.Body)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadGateway, err)
+		}
+		defer upstream.Close()
+
+		conn, err := attach.Upgrade(c.Response(), c.Request())
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		defer conn.Close()
+
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.CloseWrite()
+		}()
+		io.Copy(conn, upstream)
+		return nil
+	}, parseId)
+
+	taskGroup.GET("/stats", func(c echo.Context) error {
+		workerId, err := parseQueryId(c, "worker")
+		if err != nil {
+			return err
+		}
+
+		stats, err := manager.Stats(c.Request().Context(), workerId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		return c.JSON(http.StatusOK, stats)
+	})
+
+	taskGroup.GET("/list", func(c echo.Context) error {
 		events := manager.EventsQueue.GetAll()
 		pendingTasks := make([]task.Task, 0, len(events))
 		for _, event := range events {
@@ -129,12 +237,490 @@ func StartServer(addr string, manager *Manager) error {
 		return c.JSON(http.StatusOK, tasks)
 	})
 
-	e.GET("/task/list/:id", func(c echo.Context) error {
+	taskGroup.GET("/list/:id", func(c echo.Context) error {
 		id := c.Get("id").(uuid.UUID)
 		return c.JSON(http.StatusOK, manager.WorkerTasks(id))
 	}, parseId)
 
-	return e.Start(addr)
+	jobGroup := e.Group("/job", redirectToLeader(manager))
+
+	jobGroup.POST("/run", func(c echo.Context) error {
+		var jobs []job.Job
+		if err := c.Bind(&jobs); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid job format: %w", err),
+			)
+		}
+
+		templates := make([]task.Task, 0, len(jobs))
+		for _, j := range jobs {
+			templates = append(templates, *task.New(j.Container))
+		}
+
+		if err := manager.checkCredentials(templates); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		if err := manager.RunJobs(jobs); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return c.NoContent(http.StatusCreated)
+	})
+
+	jobGroup.GET("/list", func(c echo.Context) error {
+		jobs, err := manager.Jobs()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return c.JSON(http.StatusOK, jobs)
+	})
+
+	cronGroup := e.Group("/cron", redirectToLeader(manager))
+
+	cronGroup.POST("/create", func(c echo.Context) error {
+		var crons []cron.Cron
+		if err := c.Bind(&crons); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid cron format: %w", err),
+			)
+		}
+
+		templates := make([]task.Task, 0, len(crons))
+		for _, cr := range crons {
+			templates = append(templates, *task.New(cr.Container))
+		}
+
+		if err := manager.checkCredentials(templates); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		for _, cr := range crons {
+			err := manager.CreateCron(cr)
+			if errors.Is(err, ErrCronExists) {
+				return echo.NewHTTPError(http.StatusConflict, err)
+			}
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+		}
+		return c.NoContent(http.StatusCreated)
+	})
+
+	cronGroup.GET("/list", func(c echo.Context) error {
+		crons, err := manager.Crons()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return c.JSON(http.StatusOK, crons)
+	})
+
+	cronGroup.POST("/suspend/:name", func(c echo.Context) error {
+		return suspendCron(c, manager, true)
+	})
+
+	cronGroup.POST("/resume/:name", func(c echo.Context) error {
+		return suspendCron(c, manager, false)
+	})
+
+	cronGroup.DELETE("/:name", func(c echo.Context) error {
+		err := manager.DeleteCron(c.Param("name"))
+		if errors.Is(err, ErrCronNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	serviceGroup := e.Group("/service", redirectToLeader(manager))
+
+	serviceGroup.POST("/apply", func(c echo.Context) error {
+		var services []service.Service
+		if err := c.Bind(&services); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid service format: %w", err),
+			)
+		}
+
+		templates := make([]task.Task, 0, len(services))
+		for _, s := range services {
+			templates = append(templates, *task.New(s.Container))
+		}
+
+		if err := manager.checkCredentials(templates); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		for _, s := range services {
+			err := manager.ApplyService(s)
+			invalid := errors.Is(err, ErrInvalidServiceName) ||
+				errors.Is(err, ErrInvalidReplicas) ||
+				errors.Is(err, ErrInvalidStrategy)
+			if invalid {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, err)
+			}
+		}
+		return c.NoContent(http.StatusCreated)
+	})
+
+	serviceGroup.GET("/list", func(c echo.Context) error {
+		services, err := manager.Services()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return c.JSON(http.StatusOK, services)
+	})
+
+	serviceGroup.POST("/scale/:name", func(c echo.Context) error {
+		var replicas int
+		if err := c.Bind(&replicas); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid number of replicas: %w", err),
+			)
+		}
+
+		return updateService(c, manager.ScaleService(c.Param("name"), replicas))
+	})
+
+	serviceGroup.GET("/:name", func(c echo.Context) error {
+		s, err := manager.Service(c.Param("name"))
+		if errors.Is(err, ErrServiceNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return c.JSON(http.StatusOK, s)
+	})
+
+	serviceGroup.POST("/rollout/pause/:name", func(c echo.Context) error {
+		return updateService(c, manager.PauseRollout(c.Param("name")))
+	})
+
+	serviceGroup.POST("/rollout/resume/:name", func(c echo.Context) error {
+		return updateService(c, manager.ResumeRollout(c.Param("name")))
+	})
+
+	serviceGroup.POST("/rollout/undo/:name", func(c echo.Context) error {
+		revision, err := parseQueryIndex(c, "revision", 0)
+		if err != nil {
+			return err
+		}
+		return updateService(c, manager.UndoRollout(c.Param("name"), revision))
+	})
+
+	serviceGroup.DELETE("/:name", func(c echo.Context) error {
+		err := manager.DeleteService(c.Param("name"))
+		if errors.Is(err, ErrServiceNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	registryGroup := e.Group("/registry", redirectToLeader(manager))
+
+	registryGroup.PUT("/:name", func(c echo.Context) error {
+		var creds image.Credentials
+		if err := c.Bind(&creds); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid credentials format: %w", err),
+			)
+		}
+
+		err := manager.SetCredentials(c.Param("name"), creds)
+		if errors.Is(err, ErrInvalidCredentialsName) {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+
+		return c.NoContent(http.StatusCreated)
+	})
+
+	registryGroup.DELETE("/:name", func(c echo.Context) error {
+		err := manager.RemoveCredentials(c.Param("name"))
+		if errors.Is(err, ErrCredentialsNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+
+		return c.NoContent(http.StatusOK)
+	})
+
+	registryGroup.GET("/list", func(c echo.Context) error {
+		infos, err := manager.Credentials()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return c.JSON(http.StatusOK, infos)
+	})
+
+	storeGroup := e.Group("/store")
+
+	storeGroup.GET("/log", func(c echo.Context) error {
+		from, err := parseQueryIndex(c, "from", 1)
+		if err != nil {
+			return err
+		}
+
+		workerId, err := parseQueryId(c, "worker")
+		if err != nil {
+			return err
+		}
+
+		if workerId != uuid.Nil {
+			endpoint := fmt.Sprintf("/store/log?from=%d", from)
+			return proxyToWorker(c, manager, workerId, endpoint)
+		}
+		return c.JSON(http.StatusOK, consensus.Log(manager.Store, from))
+	})
+
+	storeGroup.GET("/dump", func(c echo.Context) error {
+		workerId, err := parseQueryId(c, "worker")
+		if err != nil {
+			return err
+		}
+
+		if workerId != uuid.Nil {
+			return proxyToWorker(c, manager, workerId, "/store/dump")
+		}
+		return c.JSON(http.StatusOK, consensus.NewDump(manager.Store))
+	})
+
+	storeGroup.GET("/watch", func(c echo.Context) error {
+		from, err := parseQueryIndex(c, "from", manager.Store.LastIndex()+1)
+		if err != nil {
+			return err
+		}
+
+		var filter consensus.WatchFilter
+		if filter.WorkerId, err = parseQueryId(c, "worker"); err != nil {
+			return err
+		}
+		if filter.TaskId, err = parseQueryId(c, "task"); err != nil {
+			return err
+		}
+
+		watcher, err := manager.Store.Watch(from, filter)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusGone, err)
+		}
+		defer watcher.Close()
+
+		resp := c.Response()
+		resp.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		resp.WriteHeader(http.StatusOK)
+		resp.Flush()
+
+		encoder := json.NewEncoder(resp)
+		for {
+			select {
+			case <-c.Request().Context().Done():
+				return nil
+			case event, ok := <-watcher.Events():
+				if !ok {
+					return nil
+				}
+
+				if err := encoder.Encode(event); err != nil {
+					return nil
+				}
+				resp.Flush()
+			}
+		}
+	})
+
+	clusterGroup := e.Group("/cluster", redirectToLeader(manager))
+
+	clusterGroup.GET("/backup", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, manager.Backup())
+	})
+
+	clusterGroup.POST("/restore", func(c echo.Context) error {
+		var backup Backup
+		if err := c.Bind(&backup); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid backup format: %w", err),
+			)
+		}
+
+		report, err := manager.Restore(backup)
+		if errors.Is(err, ErrUnsupportedBackup) {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+
+		return c.JSON(http.StatusCreated, report)
+	})
+
+	raftGroup := e.Group("/raft")
+
+	raftGroup.GET("/leader", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, manager.raft.Leader())
+	})
+
+	raftGroup.POST("/vote", func(c echo.Context) error {
+		var req consensus.VoteRequest
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid vote request format: %w", err),
+			)
+		}
+
+		return c.JSON(http.StatusOK, manager.raft.RequestVote(req))
+	})
+
+	raftGroup.POST("/append", func(c echo.Context) error {
+		var req consensus.AppendRequest
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid append request format: %w", err),
+			)
+		}
+
+		return c.JSON(http.StatusOK, manager.raft.AppendEntries(req))
+	})
+
+	raftGroup.POST("/snapshot", func(c echo.Context) error {
+		var req consensus.InstallSnapshotRequest
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid snapshot request format: %w", err),
+			)
+		}
+
+		return c.JSON(http.StatusOK, manager.raft.InstallSnapshot(req))
+	})
+
+	raftGroup.POST("/forward", func(c echo.Context) error {
+		var cmd consensus.Command
+		if err := c.Bind(&cmd); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid command format: %w", err),
+			)
+		}
+
+		if _, err := manager.raft.CommitChange(cmd); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+
+		return c.NoContent(http.StatusCreated)
+	})
+
+	return e
+}
+
+func suspendCron(c echo.Context, manager *Manager, suspended bool) error {
+	err := manager.SuspendCron(c.Param("name"), suspended)
+	if errors.Is(err, ErrCronNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func updateService(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrServiceNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	case errors.Is(err, ErrInvalidReplicas), errors.Is(err, ErrRevisionNotFound):
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func redirectToLeader(manager *Manager) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if manager.raft.IsLeader() {
+				return next(c)
+			}
+
+			leader := manager.raft.Leader()
+			if leader == "" {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, consensus.ErrNoLeader)
+			}
+
+			url := fmt.Sprintf("http://%s%s", leader, c.Request().RequestURI)
+			return c.Redirect(http.StatusTemporaryRedirect, url)
+		}
+	}
+}
+
+// proxyToWorker relays the response of the worker as is, letting the manager
+// serve the replica of the store kept by that worker
+func proxyToWorker(c echo.Context, manager *Manager, workerId uuid.UUID, endpoint string) error {
+	w, err := manager.Store.GetWorker(workerId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+
+	return proxy(c, w.Addr, endpoint)
+}
+
+func proxy(c echo.Context, addr node.Addr, endpoint string) error {
+	resp, err := httpclient.GetWithContext(c.Request().Context(), addr.String(), endpoint)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, err)
+	}
+	defer resp.Body.Close()
+
+	c.Response().Header().Set(echo.HeaderContentType, resp.Header.Get(echo.HeaderContentType))
+	c.Response().WriteHeader(resp.StatusCode)
+	httpinternal.Stream(c.Response(), resp.Body)
+	return nil
+}
+
+func parseQueryIndex(c echo.Context, name string, def int) (int, error) {
+	param := c.QueryParam(name)
+	if param == "" {
+		return def, nil
+	}
+
+	index, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid index format")
+	}
+	return index, nil
+}
+
+func parseQueryId(c echo.Context, name string) (uuid.UUID, error) {
+	param := c.QueryParam(name)
+	if param == "" {
+		return uuid.Nil, nil
+	}
+
+	id, err := uuid.Parse(param)
+	if err != nil {
+		return uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+	return id, nil
 }
 
 func parseId(next echo.HandlerFunc) echo.HandlerFunc {
@@ -0,0 +1,566 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/SergeyCherepiuk/fleet/pkg/service"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/google/uuid"
+)
+
+const (
+	ServiceSyncInterval = time.Second
+
+	// ServiceStableInterval is how long a replica has to be running and
+	// ready for the failures of the service to be forgotten
+	ServiceStableInterval = 10 * time.Second
+)
+
+var (
+	ErrServiceNotFound    = errors.New("service is not found")
+	ErrInvalidServiceName = errors.New("name of the service must be alphanumeric, with '_', '.' or '-' inside")
+	ErrInvalidReplicas    = errors.New("number of replicas must not be negative")
+	ErrInvalidStrategy    = errors.New("max surge and max unavailable must not be negative and must not be both zero")
+	ErrRevisionNotFound   = errors.New("revision is not found in the history of the service")
+)
+
+var serviceName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ApplyService creates the service or updates the existing one. The change
+// of the container makes a new revision, which is rolled out to the
+// replicas.
+func (m *Manager) ApplyService(s service.Service) error {
+	if !serviceName.MatchString(s.Name) {
+		return ErrInvalidServiceName
+	}
+
+	if s.Replicas < 0 {
+		return ErrInvalidReplicas
+	}
+
+	if !validStrategy(s.Strategy) {
+		return ErrInvalidStrategy
+	}
+
+	m.muServices.Lock()
+	defer m.muServices.Unlock()
+
+	existing, err := m.getService(s.Name)
+	if errors.Is(err, ErrServiceNotFound) {
+		s.Tasks, s.Stopping = make([]service.Replica, 0), make([]uuid.UUID, 0)
+		s.Running, s.Ready, s.Failures = 0, 0, 0
+		s.Revision, s.Revisions = 0, make([]service.Revision, 0)
+		s.CreatedAt = time.Now()
+		addRevision(&s, s.Container)
+		return m.setService(s)
+	}
+	if err != nil {
+		return err
+	}
+
+	existing.Replicas = s.Replicas
+	existing.Strategy = s.Strategy
+	if !sameContainer(existing.Container, s.Container) {
+		addRevision(&existing, s.Container)
+	}
+	return m.setService(existing)
+}
+
+func (m *Manager) Services() ([]service.Service, error) {
+	all, err := consensus.AllResources[service.Service](m.Store, service.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]service.Service, 0, len(all))
+	for _, s := range all {
+		services = append(services, s)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+func (m *Manager) Service(name string) (service.Service, error) {
+	return m.getService(name)
+}
+
+func (m *Manager) ScaleService(name string, replicas int) error {
+	if replicas < 0 {
+		return ErrInvalidReplicas
+	}
+
+	return m.updateService(name, func(s *service.Service) error {
+		s.Replicas = replicas
+		return nil
+	})
+}
+
+// PauseRollout stops replacing the old replicas with the ones of the
+// current revision, the missing replicas are still run
+func (m *Manager) PauseRollout(name string) error {
+	return m.updateService(name, func(s *service.Service) error {
+		s.Rollout.Paused = true
+		return nil
+	})
+}
+
+// ResumeRollout continues the rollout, paused by hand or on failure
+func (m *Manager) ResumeRollout(name string) error {
+	return m.updateService(name, func(s *service.Service) error {
+		s.Rollout.Paused, s.Rollout.Reason, s.Rollout.Failures = false, "", 0
+		return nil
+	})
+}
+
+// UndoRollout rolls the container of the revision back out, as the new
+// revision. The revision before the current one is used if zero is given.
+func (m *Manager) UndoRollout(name string, revision int) error {
+	return m.updateService(name, func(s *service.Service) error {
+		if revision == 0 {
+			for _, r := range s.Revisions {
+				if r.Number < s.Revision {
+					revision = r.Number
+				}
+			}
+		}
+
+		if revision == 0 {
+			return fmt.Errorf("%w: there is none before %d", ErrRevisionNotFound, s.Revision)
+		}
+
+		container, found := s.RevisionContainer(revision)
+		if !found || revision == s.Revision {
+			return fmt.Errorf("%w: %d", ErrRevisionNotFound, revision)
+		}
+
+		addRevision(s, container)
+		return nil
+	})
+}
+
+// DeleteService removes the service and stops all of its replicas
+func (m *Manager) DeleteService(name string) error {
+	m.muServices.Lock()
+	defer m.muServices.Unlock()
+
+	s, err := m.getService(name)
+	if err != nil {
+		return err
+	}
+
+	cmd := consensus.NewRemoveResourceCommand(service.Kind, name)
+	if _, err := m.Store.CommitChange(*cmd); err != nil {
+		return err
+	}
+
+	for _, r := range s.Tasks {
+		t, err := m.Store.GetTask(r.TaskId)
+		if err != nil {
+			continue // NOTE(SergeyCherepiuk): Pending tasks are dropped by run
+		}
+
+		if !t.State.Fail() && t.State != task.Finished {
+			m.EventsQueue.EnqueueNow(task.Event{Task: t, Desired: task.Finished})
+		}
+	}
+	return nil
+}
+
+func (m *Manager) updateService(name string, update func(s *service.Service) error) error {
+	m.muServices.Lock()
+	defer m.muServices.Unlock()
+
+	s, err := m.getService(name)
+	if err != nil {
+		return err
+	}
+
+	if err := update(&s); err != nil {
+		return err
+	}
+	return m.setService(s)
+}
+
+func (m *Manager) getService(name string) (service.Service, error) {
+	s, err := consensus.GetResource[service.Service](m.Store, service.Kind, name)
+	if errors.Is(err, consensus.ErrResourceNotFound) {
+		return s, ErrServiceNotFound
+	}
+	return s, err
+}
+
+func (m *Manager) setService(s service.Service) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	cmd := consensus.NewSetResourceCommand(service.Kind, s.Name, data)
+	_, err = m.Store.CommitChange(*cmd)
+	return err
+}
+
+// serviceOwns fails for the replicas that their service has let go of, so
+// they are never run
+func (m *Manager) serviceOwns(t task.Task) bool {
+	services, err := m.Services()
+	if err != nil {
+		return false
+	}
+
+	for _, s := range services {
+		if s.Id == t.ServiceId {
+			return s.Owns(t.Id)
+		}
+	}
+	return false
+}
+
+// watchServices keeps the services at their desired number of replicas and
+// rolls their revisions out, only the leader does so. Replicas lost along
+// with the worker, failed or stopped by hand are all replaced the same way.
+func (m *Manager) watchServices() {
+	ticker := time.NewTicker(ServiceSyncInterval)
+	defer ticker.Stop()
+
+	for m.tick(ticker) {
+		if !m.raft.IsLeader() {
+			continue
+		}
+
+		services, err := m.Services()
+		if err != nil {
+			continue
+		}
+
+		for _, s := range services {
+			m.syncService(s.Name)
+		}
+	}
+}
+
+func (m *Manager) syncService(name string) {
+	m.muServices.Lock()
+	defer m.muServices.Unlock()
+
+	s, err := m.getService(name)
+	if err != nil {
+		return
+	}
+
+	// NOTE(SergeyCherepiuk): Tasks that are not in the store yet are waiting
+	// in the queue, so they are counted as the replicas
+	var replicas, pending, dead []service.Replica
+	available := make(map[uuid.UUID]bool)
+	updated := s
+	updated.Running, updated.Ready = 0, 0
+	for _, r := range s.Tasks {
+		t, err := m.Store.GetTask(r.TaskId)
+		switch {
+		case err != nil:
+			replicas, pending = append(replicas, r), append(pending, r)
+		case t.State == task.Finished || t.State.Fail():
+			dead = append(dead, r)
+			if t.State.Fail() {
+				updated.Failures++
+			}
+		default:
+			replicas = append(replicas, r)
+			if t.State != task.Running {
+				continue
+			}
+
+			updated.Running++
+			if t.Ready {
+				updated.Ready++
+				available[r.TaskId] = true
+			}
+			if t.Ready && time.Since(t.StartedAt[len(t.StartedAt)-1]) > ServiceStableInterval {
+				updated.Failures = 0
+			}
+		}
+	}
+
+	for revision, ids := range groupByRevision(pending) {
+		if container, found := s.RevisionContainer(revision); found {
+			m.requeueLostTasks(m.missingServiceTasks, replicaTemplate(s, revision, container), ids)
+		}
+	}
+
+	current, old := splitByRevision(replicas, s.Revision)
+	rolling := len(old) > 0
+
+	if rolling {
+		for _, r := range dead {
+			if t, err := m.Store.GetTask(r.TaskId); err == nil && r.Revision == s.Revision && t.State.Fail() {
+				updated.Rollout.Failures++
+			}
+		}
+
+		if !updated.Rollout.Paused && updated.Rollout.Failures >= service.RolloutFailureThreshold {
+			updated.Rollout.Paused = true
+			updated.Rollout.Reason = fmt.Sprintf(
+				"%d replicas of revision %d have failed", updated.Rollout.Failures, s.Revision,
+			)
+		}
+	}
+
+	plan := rolloutPlan{available: available}
+	switch {
+	case !rolling:
+		plan.scale(current, s.Replicas, s.Revision)
+	case updated.Rollout.Paused:
+		revision := s.Rollout.StableRevision
+		if _, found := s.RevisionContainer(revision); !found {
+			revision = s.Revision
+		}
+		plan.scale(replicas, s.Replicas, revision)
+	default:
+		plan.roll(current, old, s.Replicas, s.Strategy, s.Revision)
+	}
+
+	updated.Tasks = slices.DeleteFunc(slices.Clone(s.Tasks), func(r service.Replica) bool {
+		return slices.Contains(dead, r) || slices.Contains(plan.remove, r)
+	})
+
+	for revision, count := range plan.create {
+		container, _ := s.RevisionContainer(revision)
+		template := replicaTemplate(s, revision, container)
+		for i := 0; i < count; i++ {
+			t := template
+			t.Id = uuid.New()
+			updated.Tasks = append(updated.Tasks, service.Replica{TaskId: t.Id, Revision: revision})
+			m.EventsQueue.EnqueueWithDelay(replaceBackOff(updated.Failures), task.Event{Task: t, Desired: task.Running})
+		}
+	}
+
+	stopped := make([]uuid.UUID, 0)
+	updated.Stopping = slices.DeleteFunc(slices.Clone(s.Stopping), func(id uuid.UUID) bool {
+		t, err := m.Store.GetTask(id)
+		if err != nil || t.State == task.Finished || t.State.Fail() {
+			stopped = append(stopped, id)
+			return true
+		}
+		return false
+	})
+	for _, r := range plan.remove {
+		if !slices.Contains(pending, r) {
+			updated.Stopping = append(updated.Stopping, r.TaskId)
+		}
+	}
+
+	updateRolloutStatus(&updated, available)
+	trimRevisions(&updated)
+
+	if !serviceChanged(s, updated) {
+		return
+	}
+
+	if err := m.setService(updated); err != nil {
+		return
+	}
+
+	for _, r := range plan.remove {
+		if t, err := m.Store.GetTask(r.TaskId); err == nil {
+			m.EventsQueue.EnqueueNow(task.Event{Task: t, Desired: task.Finished})
+		}
+	}
+
+	// NOTE(SergeyCherepiuk): Replaced and stopped replicas are removed from
+	// the store only once the service has let go of them, otherwise they
+	// look like lost ones
+	for _, r := range dead {
+		stopped = append(stopped, r.TaskId)
+	}
+	for _, id := range stopped {
+		m.Store.CommitChange(*consensus.NewRemoveTaskCommand(id))
+	}
+}
+
+// rolloutPlan is the replicas to let go of and the number of the replicas
+// to run of every revision
+type rolloutPlan struct {
+	available map[uuid.UUID]bool
+	remove    []service.Replica
+	create    map[int]int
+}
+
+// scale brings the replicas to the desired number, running the missing
+// ones of the revision, and letting go of the pending replicas first, then
+// the most recent ones
+func (p *rolloutPlan) scale(replicas []service.Replica, desired, revision int) {
+	if missing := desired - len(replicas); missing > 0 {
+		p.create = map[int]int{revision: missing}
+		return
+	}
+
+	extra := len(replicas) - desired
+	p.removeFirst(replicas, extra, func(r service.Replica) bool { return !p.available[r.TaskId] }, true)
+	p.removeFirst(replicas, extra-len(p.remove), func(service.Replica) bool { return true }, true)
+}
+
+// roll replaces the old replicas with the current ones, staying within the
+// surge above and the unavailable replicas below the desired number
+func (p *rolloutPlan) roll(current, old []service.Replica, desired int, strategy service.Strategy, revision int) {
+	if extra := len(current) - desired; extra > 0 {
+		p.removeFirst(current, extra, func(r service.Replica) bool { return !p.available[r.TaskId] }, true)
+		p.removeFirst(current, extra-len(p.remove), func(service.Replica) bool { return true }, true)
+	}
+
+	total := len(current) + len(old)
+	if missing := min(desired-len(current), desired+strategy.MaxSurge-total); missing > 0 {
+		p.create = map[int]int{revision: missing}
+	}
+
+	// NOTE(SergeyCherepiuk): Old replicas that are not available can go
+	// right away, since the availability doesn't drop by them
+	availableCount := 0
+	for _, r := range append(slices.Clone(current), old...) {
+		if p.available[r.TaskId] && !slices.Contains(p.remove, r) {
+			availableCount++
+		}
+	}
+
+	p.removeFirst(old, len(old), func(r service.Replica) bool { return !p.available[r.TaskId] }, false)
+	p.removeFirst(old, availableCount-(desired-strategy.MaxUnavailable), func(r service.Replica) bool { return p.available[r.TaskId] }, false)
+}
+
+// removeFirst lets go of up to n replicas that match, starting with the
+// most recent ones if asked, otherwise with the oldest ones
+func (p *rolloutPlan) removeFirst(replicas []service.Replica, n int, match func(service.Replica) bool, recentFirst bool) {
+	for i := range replicas {
+		if n <= 0 {
+			return
+		}
+
+		r := replicas[i]
+		if recentFirst {
+			r = replicas[len(replicas)-1-i]
+		}
+
+		if match(r) && !slices.Contains(p.remove, r) {
+			p.remove = append(p.remove, r)
+			n--
+		}
+	}
+}
+
+func updateRolloutStatus(s *service.Service, available map[uuid.UUID]bool) {
+	current, old := splitByRevision(s.Tasks, s.Revision)
+	s.Rollout.Updated, s.Rollout.Old, s.Rollout.UpdatedAvailable = len(current), len(old), 0
+	for _, r := range current {
+		if available[r.TaskId] {
+			s.Rollout.UpdatedAvailable++
+		}
+	}
+
+	complete := len(old) == 0 && len(current) == s.Replicas && s.Rollout.UpdatedAvailable == s.Replicas
+	switch {
+	case s.Rollout.Paused:
+		s.Rollout.Status = service.Paused
+	case complete:
+		s.Rollout.Status = service.Complete
+		s.Rollout.StableRevision, s.Rollout.Failures = s.Revision, 0
+	default:
+		s.Rollout.Status = service.Progressing
+	}
+}
+
+func addRevision(s *service.Service, container container.Container) {
+	number := s.Revision + 1
+	for _, r := range s.Revisions {
+		number = max(number, r.Number+1)
+	}
+
+	s.Revision, s.Container = number, container
+	s.Revisions = append(s.Revisions, service.Revision{
+		Number:    number,
+		Container: container,
+		CreatedAt: time.Now(),
+	})
+	// NOTE(SergeyCherepiuk): The rollout paused by hand stays paused, the one
+	// paused on failure goes on with the new revision
+	s.Rollout = service.Rollout{
+		Status:         service.Progressing,
+		Paused:         s.Rollout.Paused && s.Rollout.Reason == "",
+		StableRevision: s.Rollout.StableRevision,
+	}
+	trimRevisions(s)
+}
+
+// trimRevisions drops the oldest revisions above the limit, but the current
+// and the stable ones and the ones the replicas are still run from
+func trimRevisions(s *service.Service) {
+	inUse := func(number int) bool {
+		if number == s.Revision || number == s.Rollout.StableRevision {
+			return true
+		}
+		return slices.ContainsFunc(s.Tasks, func(r service.Replica) bool { return r.Revision == number })
+	}
+
+	for i := 0; i < len(s.Revisions) && len(s.Revisions) > service.RevisionHistoryLimit; {
+		if inUse(s.Revisions[i].Number) {
+			i++
+			continue
+		}
+		s.Revisions = slices.Delete(s.Revisions, i, i+1)
+	}
+}
+
+func replicaTemplate(s service.Service, revision int, container container.Container) task.Task {
+	t := task.New(container)
+	t.ServiceId = s.Id
+	t.Revision = revision
+	return *t
+}
+
+func groupByRevision(replicas []service.Replica) map[int][]uuid.UUID {
+	groups := make(map[int][]uuid.UUID)
+	for _, r := range replicas {
+		groups[r.Revision] = append(groups[r.Revision], r.TaskId)
+	}
+	return groups
+}
+
+func splitByRevision(replicas []service.Replica, revision int) (current, old []service.Replica) {
+	for _, r := range replicas {
+		if r.Revision == revision {
+			current = append(current, r)
+		} else {
+			old = append(old, r)
+		}
+	}
+	return current, old
+}
+
+func validStrategy(strategy service.Strategy) bool {
+	return strategy.MaxSurge >= 0 && strategy.MaxUnavailable >= 0 &&
+		(strategy.MaxSurge > 0 || strategy.MaxUnavailable > 0)
+}
+
+// NOTE(SergeyCherepiuk): Containers of the revisions are compared by their
+// specs, as they are sent by the CLI
+func sameContainer(a, b container.Container) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aData, bData)
+}
+
+func serviceChanged(before, after service.Service) bool {
+	return !slices.Equal(before.Tasks, after.Tasks) ||
+		!slices.Equal(before.Stopping, after.Stopping) ||
+		before.Running != after.Running ||
+		before.Ready != after.Ready ||
+		before.Failures != after.Failures ||
+		before.Rollout != after.Rollout ||
+		len(before.Revisions) != len(after.Revisions)
+}
@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/worker"
+	"github.com/google/uuid"
+)
+
+const StatsTimeout = 10 * time.Second
+
+// Stats collects the resource usage of the tasks from the workers, or only
+// from the given one unless its id is nil. Workers that don't respond in
+// time are left out
+func (m *Manager) Stats(ctx context.Context, workerId uuid.UUID) ([]worker.TaskStats, error) {
+	workers := m.Store.AllWorkers()
+	if workerId != uuid.Nil {
+		w, err := m.Store.GetWorker(workerId)
+		if err != nil {
+			return nil, err
+		}
+		workers = map[uuid.UUID]consensus.Worker{workerId: w}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, StatsTimeout)
+	defer cancel()
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		stats = make([]worker.TaskStats, 0)
+	)
+
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w consensus.Worker) {
+			defer wg.Done()
+
+			resp, err := httpclient.GetWithContext(ctx, w.Addr.String(), "/stats")
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+
+			var workerStats []worker.TaskStats
+			if resp.StatusCode != http.StatusOK || httpinternal.Body(resp, &workerStats) != nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			stats = append(stats, workerStats...)
+		}(w)
+	}
+
+	wg.Wait()
+	return stats, nil
+}
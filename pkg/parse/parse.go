@@ -5,11 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/SergeyCherepiuk/fleet/pkg/cron"
 	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/SergeyCherepiuk/fleet/pkg/job"
+	"github.com/SergeyCherepiuk/fleet/pkg/service"
 	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/moby/sys/signal"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,9 +25,22 @@ type ManifestEntry struct {
 	Image             string
 	Env               map[string]string
 	Labels            container.Labels
+	Mounts            []container.Mount
+	Entrypoint        []string
+	Command           []string
+	Args              []string
+	User              string
+	Hostname          string
+	WorkingDir        string                      `yaml:"workingDir"`
+	StopSignal        string                      `yaml:"stopSignal"`
+	StopTimeout       time.Duration               `yaml:"stopTimeout"`
+	PullPolicy        image.PullPolicy            `yaml:"pullPolicy"`
+	Credentials       string                      `yaml:"registryCredentials"`
 	ExposedPorts      []uint16                    `yaml:"exposedPorts"`
 	RestartPolicy     container.RestartPolicy     `yaml:"restartPolicy"`
 	RequiredResources container.RequiredResources `yaml:"requiredResources"`
+	HealthCheck       *container.HealthCheck      `yaml:"healthCheck"`
+	ReadinessCheck    *container.HealthCheck      `yaml:"readinessCheck"`
 }
 
 func (me *ManifestEntry) validate() error {
@@ -27,6 +48,19 @@ func (me *ManifestEntry) validate() error {
 		return errors.New("image is not provided for one of the tasks")
 	}
 
+	knownPullPolicy := me.PullPolicy == image.PullAlways ||
+		me.PullPolicy == image.PullIfNotPresent ||
+		me.PullPolicy == image.PullNever
+
+	if me.PullPolicy == "" {
+		me.PullPolicy = image.PullIfNotPresent
+	} else if !knownPullPolicy {
+		return fmt.Errorf(
+			"unknown pull policy, available options: %q, %q, %q",
+			image.PullAlways, image.PullIfNotPresent, image.PullNever,
+		)
+	}
+
 	knownRestartPolicy := me.RestartPolicy == "never" ||
 		me.RestartPolicy == "on-failure" ||
 		me.RestartPolicy == "always"
@@ -48,17 +82,162 @@ func (me *ManifestEntry) validate() error {
 		me.ExposedPorts = make([]uint16, 0)
 	}
 
+	if err := me.validateProcess(); err != nil {
+		return err
+	}
+
+	for _, m := range me.Mounts {
+		if err := validateMount(m); err != nil {
+			return fmt.Errorf("invalid mount %q: %w", m.Target, err)
+		}
+	}
+
+	if err := validateCheck(me.HealthCheck, me.ExposedPorts); err != nil {
+		return fmt.Errorf("invalid health check: %w", err)
+	}
+
+	if err := validateCheck(me.ReadinessCheck, me.ExposedPorts); err != nil {
+		return fmt.Errorf("invalid readiness check: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	// NOTE(SergeyCherepiuk): Names are resolved inside of the container,
+	// so only their shape can be checked
+	userName = regexp.MustCompile(`^[a-zA-Z0-9_.-]+(:[a-zA-Z0-9_.-]+)?$`)
+	hostname = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+)
+
+func (me *ManifestEntry) validateProcess() error {
+	if me.WorkingDir != "" && !path.IsAbs(me.WorkingDir) {
+		return errors.New("working directory must be an absolute path")
+	}
+
+	if me.User != "" && !userName.MatchString(me.User) {
+		return errors.New("user must be in the form of user[:group]")
+	}
+
+	if me.Hostname != "" && !hostname.MatchString(me.Hostname) {
+		return errors.New("hostname must be a valid RFC 1123 label")
+	}
+
+	if me.StopSignal != "" {
+		if _, err := signal.ParseSignal(me.StopSignal); err != nil {
+			return fmt.Errorf("invalid stop signal: %w", err)
+		}
+	}
+
+	if me.StopTimeout < 0 {
+		return errors.New("stop timeout must not be negative")
+	}
+
+	return nil
+}
+
+// NOTE(SergeyCherepiuk): Same as the names of docker's volumes
+var volumeName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+func validateMount(m container.Mount) error {
+	if !path.IsAbs(m.Target) {
+		return errors.New("target must be an absolute path")
+	}
+
+	if m.Ephemeral && m.Type != container.VolumeMount {
+		return errors.New("only volumes can be ephemeral")
+	}
+
+	switch m.Type {
+	case container.VolumeMount:
+		if m.Ephemeral && m.Source != "" {
+			return errors.New("ephemeral volumes are anonymous, source must be empty")
+		}
+		if !m.Ephemeral && !volumeName.MatchString(m.Source) {
+			return errors.New("source must be a valid volume name")
+		}
+	case container.BindMount:
+		if !path.IsAbs(m.Source) {
+			return errors.New("source must be an absolute path")
+		}
+	case container.TmpfsMount:
+		if m.Source != "" {
+			return errors.New("tmpfs mounts have no source")
+		}
+	default:
+		return fmt.Errorf(
+			"unknown type, available options: %q, %q, %q",
+			container.VolumeMount, container.BindMount, container.TmpfsMount,
+		)
+	}
+
+	return nil
+}
+
+func validateCheck(check *container.HealthCheck, exposedPorts []uint16) error {
+	if check == nil {
+		return nil
+	}
+
+	switch check.Type {
+	case container.ExecCheck:
+		if len(check.Command) == 0 {
+			return errors.New("command is not provided")
+		}
+	case container.HTTPCheck, container.TCPCheck:
+		if !slices.Contains(exposedPorts, check.Port) {
+			return errors.New("port must be one of the exposed ports")
+		}
+		if check.Type == container.HTTPCheck && check.Path == "" {
+			check.Path = container.DefaultCheckPath
+		}
+	default:
+		return fmt.Errorf(
+			"unknown type, available options: %q, %q, %q",
+			container.ExecCheck, container.HTTPCheck, container.TCPCheck,
+		)
+	}
+
+	if check.Interval < 0 || check.Timeout < 0 || check.Retries < 0 || check.StartPeriod < 0 {
+		return errors.New("interval, timeout, retries and start period must not be negative")
+	}
+
+	if check.Interval == 0 {
+		check.Interval = container.DefaultCheckInterval
+	}
+	if check.Timeout == 0 {
+		check.Timeout = container.DefaultCheckTimeout
+	}
+	if check.Retries == 0 {
+		check.Retries = container.DefaultCheckRetries
+	}
+
 	return nil
 }
 
 func (me *ManifestEntry) toTask() task.Task {
-	image := image.Image{Ref: me.Image}
+	image := image.Image{
+		Ref:         me.Image,
+		PullPolicy:  me.PullPolicy,
+		Credentials: me.Credentials,
+	}
 	container := container.New(image, container.Config{
 		ExposedPorts:      me.ExposedPorts,
 		Env:               joinEnvs(me.Env),
 		Labels:            me.Labels,
 		RestartPolicy:     me.RestartPolicy,
 		RequiredResources: me.RequiredResources,
+		Mounts:            me.Mounts,
+		Entrypoint:        me.Entrypoint,
+		Command:           me.Command,
+		Args:              me.Args,
+		WorkingDir:        me.WorkingDir,
+		User:              me.User,
+		Hostname:          me.Hostname,
+		StopSignal:        me.StopSignal,
+		StopTimeout:       me.StopTimeout,
+		HealthCheck:       me.HealthCheck,
+		ReadinessCheck:    me.ReadinessCheck,
 	})
 	return *task.New(*container)
 }
@@ -71,28 +250,250 @@ func joinEnvs(env map[string]string) []string {
 	return joined
 }
 
-func Parse(filepath string) ([]task.Task, error) {
+// Kinds of the manifest's entries
+const (
+	TaskKind    = "task"
+	JobKind     = "job"
+	CronKind    = "cron"
+	ServiceKind = "service"
+)
+
+type Manifest struct {
+	Tasks    []task.Task
+	Jobs     []job.Job
+	Crons    []cron.Cron
+	Services []service.Service
+}
+
+// manifestItem is one entry of the manifest, the task describes the task to
+// run on its own or the template of the job's tasks
+type manifestItem struct {
+	Kind string
+	Name string
+
+	Completions           *int
+	Parallelism           *int
+	BackoffLimit          *int `yaml:"backoffLimit"`
+	ActiveDeadlineSeconds *int `yaml:"activeDeadlineSeconds"`
+
+	Schedule               string
+	Timezone               string
+	ConcurrencyPolicy      cron.ConcurrencyPolicy `yaml:"concurrencyPolicy"`
+	SuccessfulHistoryLimit *int                   `yaml:"successfulHistoryLimit"`
+	FailedHistoryLimit     *int                   `yaml:"failedHistoryLimit"`
+
+	Replicas       *int
+	MaxSurge       *int `yaml:"maxSurge"`
+	MaxUnavailable *int `yaml:"maxUnavailable"`
+
+	Task ManifestEntry
+}
+
+func (mi *manifestItem) validate() error {
+	if mi.Kind == "" {
+		mi.Kind = TaskKind
+	}
+
+	hasJobFields := mi.Completions != nil || mi.Parallelism != nil ||
+		mi.BackoffLimit != nil || mi.ActiveDeadlineSeconds != nil
+	hasCronFields := mi.Schedule != "" || mi.Timezone != "" || mi.ConcurrencyPolicy != "" ||
+		mi.SuccessfulHistoryLimit != nil || mi.FailedHistoryLimit != nil
+
+	if hasJobFields && mi.Kind != JobKind {
+		return errors.New("completions, parallelism, backoff limit and active deadline are only allowed for jobs")
+	}
+
+	if hasCronFields && mi.Kind != CronKind {
+		return errors.New("schedule, timezone, concurrency policy and history limits are only allowed for crons")
+	}
+
+	hasServiceFields := mi.Replicas != nil || mi.MaxSurge != nil || mi.MaxUnavailable != nil
+	if hasServiceFields && mi.Kind != ServiceKind {
+		return errors.New("replicas, max surge and max unavailable are only allowed for services")
+	}
+
+	if mi.Name != "" && mi.Kind != CronKind && mi.Kind != ServiceKind {
+		return errors.New("name is only allowed for crons and services")
+	}
+
+	switch mi.Kind {
+	case TaskKind:
+		return mi.Task.validate()
+	case JobKind:
+		return mi.validateJob()
+	case CronKind:
+		return mi.validateCron()
+	case ServiceKind:
+		return mi.validateService()
+	default:
+		return fmt.Errorf(
+			"unknown kind, available options: %q, %q, %q, %q",
+			TaskKind, JobKind, CronKind, ServiceKind,
+		)
+	}
+}
+
+func (mi *manifestItem) validateJob() error {
+	// NOTE(SergeyCherepiuk): Job replaces the failed tasks by itself,
+	// restarting them in place would hide the failures from it
+	if mi.Task.RestartPolicy != "" && mi.Task.RestartPolicy != container.Never {
+		return fmt.Errorf("restart policy of the job must be %q", container.Never)
+	}
+
+	if err := mi.Task.validate(); err != nil {
+		return err
+	}
+
+	if mi.Completions != nil && *mi.Completions < 1 {
+		return errors.New("completions must be positive")
+	}
+
+	if mi.Parallelism != nil && *mi.Parallelism < 1 {
+		return errors.New("parallelism must be positive")
+	}
+
+	if mi.BackoffLimit != nil && *mi.BackoffLimit < 0 {
+		return errors.New("backoff limit must not be negative")
+	}
+
+	if mi.ActiveDeadlineSeconds != nil && *mi.ActiveDeadlineSeconds < 0 {
+		return errors.New("active deadline must not be negative")
+	}
+
+	return nil
+}
+
+func (mi *manifestItem) validateCron() error {
+	// NOTE(SergeyCherepiuk): The failed runs are kept as the history of the
+	// cron, the next run is the retry
+	if mi.Task.RestartPolicy != "" && mi.Task.RestartPolicy != container.Never {
+		return fmt.Errorf("restart policy of the cron must be %q", container.Never)
+	}
+
+	if err := mi.Task.validate(); err != nil {
+		return err
+	}
+
+	if mi.Name == "" {
+		return errors.New("name is not provided for one of the crons")
+	}
+
+	if mi.ConcurrencyPolicy == "" {
+		mi.ConcurrencyPolicy = cron.Allow
+	}
+
+	return mi.toCron().Validate()
+}
+
+func (mi *manifestItem) validateService() error {
+	// NOTE(SergeyCherepiuk): Service replaces the replicas that have
+	// stopped by itself
+	if mi.Task.RestartPolicy != "" && mi.Task.RestartPolicy != container.Never {
+		return fmt.Errorf("restart policy of the service must be %q", container.Never)
+	}
+
+	if err := mi.Task.validate(); err != nil {
+		return err
+	}
+
+	if mi.Name == "" {
+		return errors.New("name is not provided for one of the services")
+	}
+
+	if mi.Replicas != nil && *mi.Replicas < 0 {
+		return errors.New("replicas must not be negative")
+	}
+
+	maxSurge := valueOr(mi.MaxSurge, service.DefaultMaxSurge)
+	maxUnavailable := valueOr(mi.MaxUnavailable, service.DefaultMaxUnavailable)
+	if maxSurge < 0 || maxUnavailable < 0 {
+		return errors.New("max surge and max unavailable must not be negative")
+	}
+
+	if maxSurge == 0 && maxUnavailable == 0 {
+		return errors.New("max surge and max unavailable must not be both zero")
+	}
+
+	return nil
+}
+
+func (mi *manifestItem) toJob() job.Job {
+	t := mi.Task.toTask()
+	activeDeadline := time.Duration(valueOr(mi.ActiveDeadlineSeconds, 0)) * time.Second
+	return *job.New(
+		t.Container,
+		valueOr(mi.Completions, job.DefaultCompletions),
+		valueOr(mi.Parallelism, job.DefaultParallelism),
+		valueOr(mi.BackoffLimit, job.DefaultBackoffLimit),
+		activeDeadline,
+	)
+}
+
+func (mi *manifestItem) toCron() cron.Cron {
+	t := mi.Task.toTask()
+	return *cron.New(
+		mi.Name,
+		mi.Schedule,
+		mi.Timezone,
+		mi.ConcurrencyPolicy,
+		valueOr(mi.SuccessfulHistoryLimit, cron.DefaultSuccessfulHistoryLimit),
+		valueOr(mi.FailedHistoryLimit, cron.DefaultFailedHistoryLimit),
+		t.Container,
+	)
+}
+
+func (mi *manifestItem) toService() service.Service {
+	t := mi.Task.toTask()
+	strategy := service.Strategy{
+		MaxSurge:       valueOr(mi.MaxSurge, service.DefaultMaxSurge),
+		MaxUnavailable: valueOr(mi.MaxUnavailable, service.DefaultMaxUnavailable),
+	}
+	return *service.New(mi.Name, valueOr(mi.Replicas, service.DefaultReplicas), strategy, t.Container)
+}
+
+func valueOr(value *int, fallback int) int {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+func Parse(filepath string) (Manifest, error) {
 	content, err := os.ReadFile(filepath)
 	if err != nil {
-		return nil, err
+		return Manifest{}, err
 	}
 
 	r := bytes.NewReader(content)
 	d := yaml.NewDecoder(r)
 	d.KnownFields(true)
 
-	var entries []struct{ Task ManifestEntry }
-	if err := d.Decode(&entries); err != nil {
-		return nil, err
+	var items []manifestItem
+	if err := d.Decode(&items); err != nil {
+		return Manifest{}, err
 	}
 
-	tasks := make([]task.Task, 0, len(entries))
-	for _, entry := range entries {
-		if err := entry.Task.validate(); err != nil {
-			return nil, err
+	manifest := Manifest{
+		Tasks:    make([]task.Task, 0),
+		Jobs:     make([]job.Job, 0),
+		Crons:    make([]cron.Cron, 0),
+		Services: make([]service.Service, 0),
+	}
+	for _, item := range items {
+		if err := item.validate(); err != nil {
+			return Manifest{}, err
 		}
 
-		tasks = append(tasks, entry.Task.toTask())
+		switch item.Kind {
+		case TaskKind:
+			manifest.Tasks = append(manifest.Tasks, item.Task.toTask())
+		case JobKind:
+			manifest.Jobs = append(manifest.Jobs, item.toJob())
+		case CronKind:
+			manifest.Crons = append(manifest.Crons, item.toCron())
+		case ServiceKind:
+			manifest.Services = append(manifest.Services, item.toService())
+		}
 	}
-	return tasks, nil
+	return manifest, nil
 }
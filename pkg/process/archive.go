@@ -0,0 +1,168 @@
+package process
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+var ErrNotExecutable = errors.New("image reference does not point to an executable")
+
+var archiveExtensions = []string{".tar", ".tar.gz", ".tgz"}
+
+// prepare resolves the image reference, which is either a path to a local
+// executable or "<archive>:<path>" where the archive is extracted into the
+// root filesystem of the process and the path points to the executable
+// inside of it
+func prepare(ref, dir string) (executable, rootfs string, err error) {
+	archive, inner, ok := splitArchiveRef(ref)
+	if !ok {
+		executable, err = filepath.Abs(ref)
+		if err != nil {
+			return "", "", err
+		}
+		return executable, "", checkExecutable(executable)
+	}
+
+	rootfs = filepath.Join(dir, "rootfs")
+	if err := extract(archive, rootfs); err != nil {
+		return "", "", err
+	}
+
+	inner = filepath.Join("/", inner)
+	if err := checkExecutable(filepath.Join(rootfs, inner)); err != nil {
+		return "", "", err
+	}
+
+	if !canChroot() {
+		return filepath.Join(rootfs, inner), rootfs, nil
+	}
+	return inner, rootfs, nil
+}
+
+func splitArchiveRef(ref string) (archive, inner string, ok bool) {
+	for _, ext := range archiveExtensions {
+		if index := strings.Index(ref, ext+":"); index != -1 {
+			return ref[:index+len(ext)], ref[index+len(ext)+1:], true
+		}
+	}
+	return "", "", false
+}
+
+func checkExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("%w: %s", ErrNotExecutable, path)
+	}
+	return nil
+}
+
+func extract(archive, dst string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if !strings.HasSuffix(archive, ".tar") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dst, filepath.Join("/", header.Name))
+		if err := checkInside(dst, path); err != nil {
+			return err
+		}
+
+		if err := extractEntry(tr, header, path); err != nil {
+			return err
+		}
+	}
+}
+
+// checkInside rejects the entries written through a symlink that leads out
+// of the root filesystem, be it the entry itself or one of its parents
+func checkInside(root, path string) error {
+	for d := path; strings.HasPrefix(d, root) && d != root; d = filepath.Dir(d) {
+		if _, err := os.Lstat(d); err != nil {
+			continue // NOTE(SergeyCherepiuk): Directory is created later
+		}
+
+		resolved, err := filepath.EvalSymlinks(d)
+		if err != nil {
+			return err
+		}
+
+		rootResolved, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return err
+		}
+
+		if resolved != rootResolved && !strings.HasPrefix(resolved, rootResolved+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry escapes the root filesystem: %s", path)
+		}
+		return nil
+	}
+	return nil
+}
+
+func extractEntry(tr *tar.Reader, header *tar.Header, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(header.Mode).Perm()
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(path, mode)
+
+	case tar.TypeSymlink:
+		return os.Symlink(header.Linkname, path)
+
+	case tar.TypeReg:
+		// NOTE(SergeyCherepiuk): An earlier entry may have put a symlink in
+		// place of the file, it is replaced rather than written through
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|syscall.O_NOFOLLOW, mode)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(file, tr)
+		return err
+
+	default:
+		return nil // NOTE(SergeyCherepiuk): Devices, hard links, etc. are skipped
+	}
+}
@@ -0,0 +1,108 @@
+package process
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	name     string
+	linkname string
+	body     string
+}
+
+func writeArchive(t *testing.T, entries ...entry) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0o755, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		if e.linkname != "" {
+			header = &tar.Header{Name: e.name, Linkname: e.linkname, Mode: 0o777, Typeflag: tar.TypeSymlink}
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractRejectsWritesThroughSymlinks(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "victim")
+
+	tests := map[string][]entry{
+		"symlinked file": {
+			{name: "bin/app", linkname: outside},
+			{name: "bin/app", body: "overwritten"},
+		},
+		"symlinked directory": {
+			{name: "bin", linkname: filepath.Dir(outside)},
+			{name: "bin/victim", body: "overwritten"},
+		},
+		"relative symlink": {
+			{name: "app", linkname: "../../../../../../../../" + outside},
+			{name: "app", body: "overwritten"},
+		},
+	}
+
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(outside, []byte("original"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			rootfs := filepath.Join(t.TempDir(), "rootfs")
+			if err := extract(writeArchive(t, entries...), rootfs); err == nil {
+				t.Fatal("archive is extracted despite writing outside of the root filesystem")
+			}
+
+			data, err := os.ReadFile(outside)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "original" {
+				t.Fatalf("file outside of the root filesystem is overwritten with %q", data)
+			}
+		})
+	}
+}
+
+func TestExtractReplacesSymlinksInside(t *testing.T) {
+	archive := writeArchive(t,
+		entry{name: "bin/real", body: "real"},
+		entry{name: "bin/app", linkname: "real"},
+		entry{name: "bin/app", body: "app"},
+	)
+
+	rootfs := filepath.Join(t.TempDir(), "rootfs")
+	if err := extract(archive, rootfs); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"bin/real": "real", "bin/app": "app"} {
+		data, err := os.ReadFile(filepath.Join(rootfs, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("%s contains %q instead of %q", name, data, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package process
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+)
+
+var (
+	ErrHostnameUnsupported = errors.New("hostname can't be set by the exec runtime")
+	ErrInvalidUser         = errors.New("user must be numeric (uid[:gid]) for the exec runtime")
+	ErrRelativeEntrypoint  = errors.New("entrypoint must be an absolute path inside of the archive")
+)
+
+// command builds the process out of the config, the executable pointed by
+// the image reference is the default entrypoint. Paths of the config are
+// the ones inside of the root filesystem
+func command(executable, rootfs string, config container.Config) (*exec.Cmd, error) {
+	if config.Hostname != "" {
+		return nil, ErrHostnameUnsupported
+	}
+
+	entrypoint, args := config.ProcessArgs([]string{executable}, nil)
+	full := append(slices.Clone(entrypoint), args...)
+	if len(full) == 0 {
+		return nil, ErrEmptyCommand
+	}
+
+	name := full[0]
+	if config.Entrypoint != nil {
+		if rootfs != "" && !filepath.IsAbs(name) {
+			return nil, ErrRelativeEntrypoint
+		}
+		name = inRoot(rootfs, name)
+	}
+
+	cmd := exec.Command(name, full[1:]...)
+	cmd.Dir = "/"
+	if config.WorkingDir != "" {
+		cmd.Dir = inRoot(rootfs, config.WorkingDir)
+	}
+	return cmd, nil
+}
+
+// inRoot returns the path the process sees the given one at, it is the
+// same unless the root filesystem can't be changed
+func inRoot(rootfs, path string) string {
+	if rootfs == "" || canChroot() {
+		return path
+	}
+	return filepath.Join(rootfs, path)
+}
+
+func parseUser(user string) (uid, gid uint32, err error) {
+	uidStr, gidStr, hasGroup := strings.Cut(user, ":")
+
+	parsed, err := strconv.ParseUint(uidStr, 10, 32)
+	if err != nil {
+		return 0, 0, ErrInvalidUser
+	}
+	uid, gid = uint32(parsed), uint32(parsed)
+
+	if hasGroup {
+		parsed, err := strconv.ParseUint(gidStr, 10, 32)
+		if err != nil {
+			return 0, 0, ErrInvalidUser
+		}
+		gid = uint32(parsed)
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,24 @@
+package process
+
+import (
+	"context"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+)
+
+// NOTE(SergeyCherepiuk): Statuses are named after the docker ones, since
+// the worker maps them to the states of the tasks
+func (r *Runtime) ContainerState(_ context.Context, id string) (container.State, error) {
+	r.mu.Lock()
+	p, ok := r.processes[id]
+	r.mu.Unlock()
+
+	if !ok {
+		return container.State{}, ErrProcessNotFound
+	}
+
+	if !p.exited() {
+		return container.State{Status: "running"}, nil
+	}
+	return container.State{Status: "exited", ExitCode: p.exitCode}, nil
+}
@@ -0,0 +1,20 @@
+package process
+
+import (
+	"context"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+)
+
+func (r *Runtime) Containers(_ context.Context) ([]container.Container, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fleetConts := make([]container.Container, 0, len(r.processes))
+	for _, p := range r.processes {
+		if p.container.Config.Labels[container.TypeLabelKey] == container.TypeLabelValue {
+			fleetConts = append(fleetConts, p.container)
+		}
+	}
+	return fleetConts, nil
+}
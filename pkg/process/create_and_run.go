@@ -0,0 +1,127 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/google/uuid"
+)
+
+// NOTE(SergeyCherepiuk): Stdout and stderr share the file, the same way
+// docker merges them into the logs of a container
+const OutputFile = "output.log"
+
+func (r *Runtime) CreateAndRun(_ context.Context, cont container.Container) (string, error) {
+	id := uuid.NewString()
+	dir := filepath.Join(r.dir, id)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	p, err := r.start(id, dir, cont)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.processes[id] = p
+	r.mu.Unlock()
+
+	r.events.Publish(c14n.Event{ContainerId: id, State: container.State{Status: "running"}})
+	go func() {
+		p.wait()
+		state := container.State{Status: "exited", ExitCode: p.exitCode}
+		r.events.Publish(c14n.Event{ContainerId: id, State: state})
+	}()
+
+	return id, nil
+}
+
+func (r *Runtime) start(id, dir string, cont container.Container) (*process, error) {
+	executable, rootfs, err := prepare(cont.Image.Ref, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := command(executable, rootfs, cont.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	mounts, err := r.mount(dir, rootfs, cont.Config.Mounts)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := os.Create(filepath.Join(dir, OutputFile))
+	if err != nil {
+		unmountAll(mounts)
+		return nil, err
+	}
+	defer output.Close()
+
+	// NOTE(SergeyCherepiuk): Limits are best effort, the process runs without
+	// them if cgroup v2 is not available or not writable, the reason is left
+	// in its output for the logs of the task
+	resources := cont.Config.RequiredResources
+	cgroup, cgroupFd, err := createCgroup(id, resources)
+	if err != nil && (resources.CPU > 0 || resources.Memory > 0) {
+		fmt.Fprintf(output, "fleet: resource limits are not applied: %v\n", err)
+	}
+	if cgroupFd != nil {
+		defer cgroupFd.Close()
+	}
+
+	cmd.Env = cont.Config.Env
+	cmd.Stdout, cmd.Stderr = output, output
+	cmd.SysProcAttr = sysProcAttr(rootfs, cgroupFd)
+
+	if cont.Config.User != "" {
+		uid, gid, err := parseUser(cont.Config.User)
+		if err == nil {
+			err = setCredential(cmd.SysProcAttr, uid, gid)
+		}
+		if err != nil {
+			removeCgroup(cgroup)
+			unmountAll(mounts)
+			return nil, err
+		}
+	}
+
+	if err := cmd.Start(); err != nil {
+		removeCgroup(cgroup)
+		unmountAll(mounts)
+		return nil, err
+	}
+
+	cont.Id = id
+	p := process{
+		container: cont,
+		cmd:       cmd,
+		dir:       dir,
+		cgroup:    cgroup,
+		mounts:    mounts,
+		done:      make(chan struct{}),
+	}
+	return &p, nil
+}
+
+func (p *process) wait() {
+	p.cmd.Wait()
+	p.exitCode = exitCode(p.cmd.ProcessState)
+	close(p.done)
+}
+
+func (p *process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
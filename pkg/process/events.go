@@ -0,0 +1,11 @@
+package process
+
+import (
+	"context"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+)
+
+func (r *Runtime) Events(ctx context.Context) (<-chan c14n.Event, <-chan error) {
+	return r.events.Subscribe(ctx)
+}
@@ -0,0 +1,65 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+)
+
+var ErrEmptyCommand = errors.New("command is empty")
+
+func (r *Runtime) Exec(ctx context.Context, id string, opts c14n.ExecOptions, streams c14n.ExecStreams) (int, error) {
+	r.mu.Lock()
+	p, ok := r.processes[id]
+	r.mu.Unlock()
+
+	if !ok || p.exited() {
+		return 0, ErrProcessNotFound
+	}
+
+	if len(opts.Cmd) == 0 {
+		return 0, ErrEmptyCommand
+	}
+
+	args := enterArgs(p.cmd.Process.Pid, opts.Cmd)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(append([]string{}, p.container.Config.Env...), opts.Env...)
+
+	if !opts.Tty {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = streams.Stdin, streams.Output, streams.Output
+		return exitCodeOf(cmd.Run())
+	}
+
+	master, slave, err := openPty(opts.Width, opts.Height)
+	if err != nil {
+		return 0, err
+	}
+	defer master.Close()
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr = ttyAttr()
+
+	err = cmd.Start()
+	slave.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	if streams.Stdin != nil {
+		go io.Copy(master, streams.Stdin)
+	}
+	io.Copy(streams.Output, master) // NOTE(SergeyCherepiuk): Fails with EIO once the process exits
+
+	return exitCodeOf(cmd.Wait())
+}
+
+func exitCodeOf(err error) (int, error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitCode(exitErr.ProcessState), nil
+	}
+	return 0, err
+}
@@ -0,0 +1,51 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+)
+
+var (
+	ErrPullUnsupported         = errors.New("exec runtime can't pull images, they must be present on the node")
+	ErrImageRemovalUnsupported = errors.New("exec runtime can't remove images, they are the files of the node")
+)
+
+func (r *Runtime) ImagePresent(_ context.Context, ref string) (bool, error) {
+	path := ref
+	if archive, _, ok := splitArchiveRef(ref); ok {
+		path = archive
+	}
+
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NOTE(SergeyCherepiuk): Local files are always up to date, so pulling
+// succeeds as long as they are present
+func (r *Runtime) PullImage(ctx context.Context, ref string, _ *image.Credentials) error {
+	present, err := r.ImagePresent(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if !present {
+		return ErrPullUnsupported
+	}
+	return nil
+}
+
+// NOTE(SergeyCherepiuk): Images of the exec runtime are the files of the
+// node, they are never listed, so never collected. The copies extracted for
+// the processes are removed along with them
+func (r *Runtime) Images(context.Context) ([]image.Info, error) {
+	return make([]image.Info, 0), nil
+}
+
+func (r *Runtime) RemoveImage(context.Context, string) error {
+	return ErrImageRemovalUnsupported
+}
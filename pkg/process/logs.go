@@ -0,0 +1,22 @@
+package process
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+)
+
+func (r *Runtime) Logs(ctx context.Context, id string, opts c14n.LogsOptions) (io.ReadCloser, error) {
+	r.mu.Lock()
+	p, ok := r.processes[id]
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, ErrProcessNotFound
+	}
+
+	alive := func() bool { return !p.exited() }
+	return c14n.TailFile(ctx, filepath.Join(p.dir, OutputFile), opts, alive)
+}
@@ -0,0 +1,97 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+)
+
+const VolumesDir = "volumes"
+
+var ErrMountsUnsupported = errors.New("mounts require the process to run as root from an archive")
+
+// mount attaches the storage to the root filesystem before the process is
+// started, it sees the mounts through the copy of the mount namespace.
+// Named volumes are kept in the directory of the runtime, the ephemeral
+// ones in the directory of the process. Mounted paths are returned, so
+// they can be unmounted later
+func (r *Runtime) mount(dir, rootfs string, mounts []container.Mount) ([]string, error) {
+	if len(mounts) == 0 {
+		return nil, nil
+	}
+
+	if rootfs == "" || !canChroot() {
+		return nil, ErrMountsUnsupported
+	}
+
+	mounted := make([]string, 0, len(mounts))
+	for i, m := range mounts {
+		target := filepath.Join(rootfs, m.Target)
+		if err := checkInside(rootfs, target); err != nil {
+			unmountAll(mounted)
+			return nil, err
+		}
+
+		if err := r.mountOne(dir, target, i, m); err != nil {
+			unmountAll(mounted)
+			return nil, err
+		}
+		mounted = append(mounted, target)
+	}
+	return mounted, nil
+}
+
+func (r *Runtime) mountOne(dir, target string, i int, m container.Mount) error {
+	if m.Type == container.TmpfsMount {
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		return tmpfsMount(target, m.ReadOnly)
+	}
+
+	source := m.Source
+	if m.Type == container.VolumeMount {
+		source = filepath.Join(r.dir, VolumesDir, m.Source)
+		if m.Ephemeral {
+			source = filepath.Join(dir, VolumesDir, fmt.Sprint(i))
+		}
+
+		if err := os.MkdirAll(source, 0755); err != nil {
+			return err
+		}
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	// NOTE(SergeyCherepiuk): Files are bind mounted onto files
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+		var file *os.File
+		if file, err = os.OpenFile(target, os.O_CREATE, 0644); err == nil {
+			file.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return bindMount(source, target, m.ReadOnly)
+}
+
+// unmountAll fails if any of the paths stays mounted, in which case the
+// directory of the process must not be removed, since the data of the
+// mount would be removed along with it
+func unmountAll(mounted []string) error {
+	var errs []error
+	for i := len(mounted) - 1; i >= 0; i-- {
+		errs = append(errs, unmount(mounted[i]))
+	}
+	return errors.Join(errs...)
+}
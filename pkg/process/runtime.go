@@ -0,0 +1,51 @@
+package process
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+)
+
+var DefaultDir = filepath.Join(os.TempDir(), "fleet-exec")
+
+// Runtime launches the executable pointed by the image reference directly,
+// without any container engine. Processes are tracked in memory, so they
+// are not picked up again once the worker restarts
+type Runtime struct {
+	dir string
+
+	mu        sync.Mutex
+	processes map[string]*process
+
+	events c14n.Broadcaster
+}
+
+type process struct {
+	container container.Container
+	cmd       *exec.Cmd
+	dir       string
+	cgroup    string
+	mounts    []string
+	done      chan struct{}
+	exitCode  int
+}
+
+func New(dir string) (*Runtime, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	runtime := Runtime{
+		dir:       dir,
+		processes: make(map[string]*process),
+	}
+	return &runtime, nil
+}
+
+func (r *Runtime) Name() string {
+	return "exec"
+}
@@ -0,0 +1,113 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+)
+
+var ErrStatsUnavailable = errors.New("stats are not available, the process is not in a cgroup")
+
+// NOTE(SergeyCherepiuk): Stats are read from the cgroup of the process, the
+// network is shared with the host so its usage is not reported
+func (r *Runtime) Stats(ctx context.Context, id string) (c14n.Stats, error) {
+	r.mu.Lock()
+	p, ok := r.processes[id]
+	r.mu.Unlock()
+
+	if !ok || p.exited() {
+		return c14n.Stats{}, ErrProcessNotFound
+	}
+
+	if p.cgroup == "" {
+		return c14n.Stats{}, ErrStatsUnavailable
+	}
+
+	before, err := cpuTime(p.cgroup)
+	if err != nil {
+		return c14n.Stats{}, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return c14n.Stats{}, ctx.Err()
+	case <-time.After(c14n.StatsInterval):
+	}
+
+	after, err := cpuTime(p.cgroup)
+	if err != nil {
+		return c14n.Stats{}, err
+	}
+
+	stats := c14n.Stats{CPU: c14n.CPUCores(before, after, c14n.StatsInterval)}
+
+	usage, err := readUint(filepath.Join(p.cgroup, "memory.current"))
+	if err != nil {
+		return c14n.Stats{}, err
+	}
+	memoryStat, _ := readKeyed(filepath.Join(p.cgroup, "memory.stat"))
+	stats.MemoryUsage = usage - min(memoryStat["inactive_file"], usage)
+	stats.MemoryLimit, _ = readUint(filepath.Join(p.cgroup, "memory.max")) // NOTE(SergeyCherepiuk): "max" is no limit
+
+	// NOTE(SergeyCherepiuk): io.stat is there only if the io controller is
+	// enabled for the cgroups of fleet
+	if content, err := os.ReadFile(filepath.Join(p.cgroup, "io.stat")); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			for _, field := range strings.Fields(line) {
+				key, value, _ := strings.Cut(field, "=")
+				n, _ := strconv.ParseUint(value, 10, 64)
+				switch key {
+				case "rbytes":
+					stats.BlockRead += n
+				case "wbytes":
+					stats.BlockWrite += n
+				}
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// cpuTime returns the CPU time used by the cgroup in nanoseconds
+func cpuTime(cgroup string) (uint64, error) {
+	stat, err := readKeyed(filepath.Join(cgroup, "cpu.stat"))
+	if err != nil {
+		return 0, err
+	}
+	return stat["usage_usec"] * uint64(time.Microsecond), nil
+}
+
+func readUint(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
+
+// readKeyed parses the files of the "<key> <value>" lines, e.g. cpu.stat
+func readKeyed(path string) (map[string]uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+			values[key] = n
+		}
+	}
+	return values, nil
+}
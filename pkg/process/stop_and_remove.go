@@ -0,0 +1,55 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	mobysignal "github.com/moby/sys/signal"
+)
+
+const StopTimeout = 10 * time.Second
+
+var ErrProcessNotFound = errors.New("process is not found")
+
+func (r *Runtime) StopAndRemove(_ context.Context, id string) error {
+	r.mu.Lock()
+	p, ok := r.processes[id]
+	delete(r.processes, id)
+	r.mu.Unlock()
+
+	if !ok {
+		return ErrProcessNotFound
+	}
+
+	if !p.exited() {
+		sig, timeout := stopSettings(p.container.Config)
+		signal(p.cmd.Process, sig)
+		select {
+		case <-p.done:
+		case <-time.After(timeout):
+			signal(p.cmd.Process, syscall.SIGKILL)
+			<-p.done
+		}
+	}
+
+	removeCgroup(p.cgroup)
+	if err := unmountAll(p.mounts); err != nil {
+		return err
+	}
+	return os.RemoveAll(p.dir)
+}
+
+func stopSettings(config container.Config) (syscall.Signal, time.Duration) {
+	sig, timeout := syscall.SIGTERM, StopTimeout
+	if parsed, err := mobysignal.ParseSignal(config.StopSignal); err == nil && config.StopSignal != "" {
+		sig = parsed
+	}
+	if config.StopTimeout > 0 {
+		timeout = config.StopTimeout
+	}
+	return sig, timeout
+}
@@ -0,0 +1,194 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+)
+
+const (
+	CgroupParent = "fleet"
+	CPUPeriod    = 100_000
+)
+
+var CgroupRoot = "/sys/fs/cgroup"
+
+func canChroot() bool {
+	return os.Geteuid() == 0
+}
+
+// NOTE(SergeyCherepiuk): Namespaces require root, the network one is shared
+// with the host so the exposed ports are reachable as is
+func sysProcAttr(rootfs string, cgroup *os.File) *syscall.SysProcAttr {
+	attr := syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGKILL}
+	if os.Geteuid() == 0 {
+		attr.Cloneflags = syscall.CLONE_NEWPID | syscall.CLONE_NEWNS |
+			syscall.CLONE_NEWUTS | syscall.CLONE_NEWIPC
+		attr.Chroot = rootfs
+	}
+
+	if cgroup != nil {
+		attr.UseCgroupFD = true
+		attr.CgroupFD = int(cgroup.Fd())
+	}
+	return &attr
+}
+
+// createCgroup creates the cgroup for the process, the returned error tells
+// why the limits are not (fully) applied, the cgroup might be created anyway
+func createCgroup(id string, resources container.RequiredResources) (string, *os.File, error) {
+	if _, err := os.Stat(filepath.Join(CgroupRoot, "cgroup.controllers")); err != nil {
+		return "", nil, errors.New("cgroup v2 is not mounted")
+	}
+
+	parent := filepath.Join(CgroupRoot, CgroupParent)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", nil, err
+	}
+
+	var errs []error
+	for _, dir := range []string{CgroupRoot, parent} {
+		control := filepath.Join(dir, "cgroup.subtree_control")
+		if err := os.WriteFile(control, []byte("+cpu +memory"), 0644); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	path := filepath.Join(parent, id)
+	if err := os.Mkdir(path, 0755); err != nil {
+		return "", nil, errors.Join(append(errs, err)...)
+	}
+
+	if resources.CPU > 0 {
+		quota := fmt.Sprintf("%d %d", int64(resources.CPU*CPUPeriod), CPUPeriod)
+		if err := os.WriteFile(filepath.Join(path, "cpu.max"), []byte(quota), 0644); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if resources.Memory > 0 {
+		if err := os.WriteFile(filepath.Join(path, "memory.max"), []byte(fmt.Sprint(resources.Memory)), 0644); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		os.Remove(path)
+		return "", nil, errors.Join(append(errs, err)...)
+	}
+	return path, fd, errors.Join(errs...)
+}
+
+func removeCgroup(path string) {
+	if path != "" {
+		os.Remove(path)
+	}
+}
+
+func signal(p *os.Process, sig syscall.Signal) {
+	syscall.Kill(-p.Pid, sig)
+}
+
+func exitCode(state *os.ProcessState) int {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
+
+// enterArgs runs the command inside of the namespaces of the process, it
+// is run on the host as is if they can't be entered
+func enterArgs(pid int, cmd []string) []string {
+	nsenter, err := exec.LookPath("nsenter")
+	if os.Geteuid() != 0 || err != nil {
+		return cmd
+	}
+
+	target := fmt.Sprint(pid)
+	args := []string{nsenter, "--target", target, "--mount", "--uts", "--ipc", "--pid", "--root", "--wd", "--"}
+	return append(args, cmd...)
+}
+
+func ttyAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+}
+
+func openPty(width, height uint) (master, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var unlock int32
+	if err := ioctl(master, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	var n uint32
+	if err := ioctl(master, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	if width > 0 && height > 0 {
+		size := struct{ rows, cols, x, y uint16 }{uint16(height), uint16(width), 0, 0}
+		ioctl(slave, syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&size)))
+	}
+	return master, slave, nil
+}
+
+func ioctl(f *os.File, req, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func bindMount(source, target string, readOnly bool) error {
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return err
+	}
+
+	// NOTE(SergeyCherepiuk): Bind mounts ignore the read-only flag until
+	// they are remounted
+	if readOnly {
+		flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | syscall.MS_REC)
+		if err := syscall.Mount("", target, "", flags, ""); err != nil {
+			unmount(target)
+			return err
+		}
+	}
+	return nil
+}
+
+func tmpfsMount(target string, readOnly bool) error {
+	flags := uintptr(syscall.MS_NOSUID | syscall.MS_NODEV)
+	if readOnly {
+		flags |= syscall.MS_RDONLY
+	}
+	return syscall.Mount("tmpfs", target, "tmpfs", flags, "mode=1777")
+}
+
+func unmount(target string) error {
+	return syscall.Unmount(target, syscall.MNT_DETACH)
+}
+
+func setCredential(attr *syscall.SysProcAttr, uid, gid uint32) error {
+	attr.Credential = &syscall.Credential{Uid: uid, Gid: gid}
+	return nil
+}
@@ -0,0 +1,67 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+)
+
+func fakeCgroupRoot(t *testing.T) string {
+	t.Helper()
+
+	root := CgroupRoot
+	CgroupRoot = t.TempDir()
+	t.Cleanup(func() { CgroupRoot = root })
+	return CgroupRoot
+}
+
+func TestCreateCgroupAppliesLimits(t *testing.T) {
+	root := fakeCgroupRoot(t)
+	os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu memory"), 0644)
+
+	path, fd, err := createCgroup("limited", container.RequiredResources{CPU: 0.5, Memory: 64 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	if quota, _ := os.ReadFile(filepath.Join(path, "cpu.max")); string(quota) != "50000 100000" {
+		t.Fatalf("cpu quota is %q", quota)
+	}
+	if limit, _ := os.ReadFile(filepath.Join(path, "memory.max")); string(limit) != "67108864" {
+		t.Fatalf("memory limit is %q", limit)
+	}
+}
+
+func TestCreateCgroupReportsFailedWrites(t *testing.T) {
+	root := fakeCgroupRoot(t)
+	os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu memory"), 0644)
+
+	// NOTE(SergeyCherepiuk): Controllers can't be enabled for the children of
+	// the parent, since its control file is a directory
+	os.MkdirAll(filepath.Join(root, CgroupParent, "cgroup.subtree_control"), 0755)
+
+	path, fd, err := createCgroup("limited", container.RequiredResources{Memory: 64 << 20})
+	if err == nil {
+		t.Fatal("failed write is not reported")
+	}
+	if fd == nil {
+		t.Fatalf("cgroup is not created: %v", err)
+	}
+	defer fd.Close()
+
+	if limit, _ := os.ReadFile(filepath.Join(path, "memory.max")); string(limit) != "67108864" {
+		t.Fatalf("memory limit is %q", limit)
+	}
+}
+
+func TestCreateCgroupWithoutCgroupV2(t *testing.T) {
+	fakeCgroupRoot(t)
+
+	path, fd, err := createCgroup("limited", container.RequiredResources{CPU: 1})
+	if err == nil || path != "" || fd != nil {
+		t.Fatalf("cgroup is created at %q: %v", path, err)
+	}
+}
@@ -0,0 +1,61 @@
+//go:build !linux
+
+package process
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+)
+
+func canChroot() bool {
+	return false
+}
+
+func sysProcAttr(string, *os.File) *syscall.SysProcAttr {
+	return nil
+}
+
+func createCgroup(string, container.RequiredResources) (string, *os.File, error) {
+	return "", nil, nil
+}
+
+func removeCgroup(string) {}
+
+func signal(p *os.Process, sig syscall.Signal) {
+	p.Signal(sig)
+}
+
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
+
+func enterArgs(_ int, cmd []string) []string {
+	return cmd
+}
+
+func ttyAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+func openPty(uint, uint) (*os.File, *os.File, error) {
+	return nil, nil, errors.New("terminal is not supported on this platform")
+}
+
+func bindMount(string, string, bool) error {
+	return ErrMountsUnsupported
+}
+
+func tmpfsMount(string, bool) error {
+	return ErrMountsUnsupported
+}
+
+func unmount(string) error {
+	return nil
+}
+
+func setCredential(*syscall.SysProcAttr, uint32, uint32) error {
+	return errors.New("user can't be set on this platform")
+}
@@ -11,8 +11,21 @@ import (
 var (
 	ErrNoAvailableWorkers = errors.New("no available workers")
 	ErrNoCapableWorkers   = errors.New("no capable workers")
+	ErrPinnedWorkerAbsent = errors.New("worker the task is pinned to is not available")
 )
 
 type Scheduler interface {
 	SelectWorker(task task.Task, workers map[uuid.UUID]consensus.Worker) (uuid.UUID, consensus.Worker, error)
 }
+
+// SelectPinned returns the worker on the host the task is pinned to, the task
+// waits for a worker to come back there if it is gone, since its data is there.
+// The port is not compared, workers listen on a new one after every restart
+func SelectPinned(t task.Task, ws map[uuid.UUID]consensus.Worker) (uuid.UUID, consensus.Worker, error) {
+	for id, w := range ws {
+		if w.Addr.Addr.Equal(t.PinnedTo.Addr) {
+			return id, w, nil
+		}
+	}
+	return uuid.Nil, consensus.Worker{}, ErrPinnedWorkerAbsent
+}
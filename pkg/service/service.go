@@ -0,0 +1,135 @@
+// Package service describes the long-running tasks that are kept at the
+// desired number of replicas and updated by rolling them out.
+package service
+
+import (
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/google/uuid"
+)
+
+// Kind is the kind of the resources the services are kept in the store
+// under, by their names
+const Kind = "services"
+
+const (
+	DefaultReplicas       = 1
+	DefaultMaxSurge       = 1
+	DefaultMaxUnavailable = 0
+
+	// RevisionHistoryLimit is the number of revisions kept for the rollbacks,
+	// the ones still run by the replicas are never dropped
+	RevisionHistoryLimit = 10
+
+	// RolloutFailureThreshold is the number of the replicas of the new
+	// revision that may fail before the rollout is paused
+	RolloutFailureThreshold = 3
+)
+
+type Service struct {
+	Id        uuid.UUID
+	Name      string
+	Replicas  int
+	Strategy  Strategy
+	Container container.Container
+
+	// Revision is the number of the revision the container is of, the
+	// revisions are kept in order, the current one included
+	Revision  int
+	Revisions []Revision
+	Rollout   Rollout
+
+	// Tasks are the replicas the service owns, the ones that are waiting in
+	// the queue included, in order
+	Tasks   []Replica
+	Running int
+	Ready   int
+
+	// Stopping are the replicas let go of, they are removed from the store
+	// once they have stopped
+	Stopping []uuid.UUID
+
+	// Failures is the number of replicas failed in a row, replacing them is
+	// delayed more with every one of them
+	Failures int
+
+	CreatedAt time.Time
+}
+
+// Strategy bounds the rolling update, by how many replicas the service may
+// go above and below the desired number while the old replicas are replaced
+type Strategy struct {
+	MaxSurge       int
+	MaxUnavailable int
+}
+
+type Replica struct {
+	TaskId   uuid.UUID
+	Revision int
+}
+
+type Revision struct {
+	Number    int
+	Container container.Container
+	CreatedAt time.Time
+}
+
+type RolloutStatus string
+
+const (
+	Progressing RolloutStatus = "Progressing"
+	Complete    RolloutStatus = "Complete"
+	Paused      RolloutStatus = "Paused"
+)
+
+type Rollout struct {
+	Status RolloutStatus
+	Paused bool
+	Reason string // NOTE(SergeyCherepiuk): Set if the rollout was paused on failure
+
+	// Failures is the number of the replicas of the current revision failed
+	// since the rollout has started
+	Failures int
+
+	// StableRevision is the last revision rolled out completely, the missing
+	// replicas are run from it while the rollout is paused
+	StableRevision int
+
+	Updated          int
+	UpdatedAvailable int
+	Old              int
+}
+
+func New(name string, replicas int, strategy Strategy, container container.Container) *Service {
+	return &Service{
+		Id:        uuid.New(),
+		Name:      name,
+		Replicas:  replicas,
+		Strategy:  strategy,
+		Container: container,
+		Revisions: make([]Revision, 0),
+		Tasks:     make([]Replica, 0),
+		Stopping:  make([]uuid.UUID, 0),
+	}
+}
+
+// RevisionContainer returns the container of the revision if it is still
+// kept in the history
+func (s Service) RevisionContainer(number int) (container.Container, bool) {
+	for _, r := range s.Revisions {
+		if r.Number == number {
+			return r.Container, true
+		}
+	}
+	return container.Container{}, false
+}
+
+func (s Service) Owns(taskId uuid.UUID) bool {
+	for _, r := range s.Tasks {
+		if r.TaskId == taskId {
+			return true
+		}
+	}
+	return false
+}
@@ -4,13 +4,14 @@ import (
 	"time"
 
 	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/SergeyCherepiuk/fleet/pkg/node"
 	"github.com/google/uuid"
 )
 
 type State string
 
 func (s State) Fail() bool {
-	return s == FailedOnStartup || s == FailedAfterStartup
+	return s == FailedOnStartup || s == FailedAfterStartup || s == Unhealthy
 }
 
 const (
@@ -20,6 +21,7 @@ const (
 	Finished              State = "Finished"
 	FailedOnStartup       State = "FailedOnStartup"
 	FailedAfterStartup    State = "FailedAfterStartup"
+	Unhealthy             State = "Unhealthy"
 	RestartingWithBackOff State = "RestartingWithBackOff"
 )
 
@@ -27,6 +29,24 @@ type Task struct {
 	Id        uuid.UUID
 	State     State
 	Container container.Container
+	Ready     bool   // NOTE(SergeyCherepiuk): Passes the readiness check, if any
+	Reason    string // NOTE(SergeyCherepiuk): Why the task has failed to start, if it has
+
+	// PinnedTo is the worker the task is always run on, it is set once the
+	// task with persistent volumes is scheduled for the first time. Only the
+	// host is matched, so the task follows the worker across its restarts
+	PinnedTo *node.Addr
+
+	// JobId is the job the task is run by, nil if it is run on its own
+	JobId uuid.UUID
+
+	// CronId is the cron the task is run on schedule by, nil if it is not
+	CronId uuid.UUID
+
+	// ServiceId is the service the task is a replica of, nil if it is not,
+	// Revision is the revision of the service the replica is run from
+	ServiceId uuid.UUID
+	Revision  int
 
 	StartedAt  []time.Time
 	FinishedAt []time.Time
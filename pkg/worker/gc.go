@@ -0,0 +1,182 @@
+package worker
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+	"github.com/SergeyCherepiuk/fleet/pkg/node"
+	"golang.org/x/exp/maps"
+)
+
+const (
+	ImageGCInterval = 5 * time.Minute
+
+	// Images are collected once the disk usage goes above the high
+	// watermark, until it drops below the low one
+	ImageGCHighWatermark = 0.85
+	ImageGCLowWatermark  = 0.80
+
+	// ImageMinAge keeps the images that have been used recently, so the one
+	// that is just pulled is not removed before its container is created
+	ImageMinAge = 2 * time.Minute
+)
+
+type GCReport struct {
+	Removed   []string
+	Reclaimed uint64
+}
+
+// collectImages runs the image GC whenever the disk usage is above the high
+// watermark
+func (w *Worker) collectImages() {
+	ticker := time.NewTicker(ImageGCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		disk, err := node.Disk()
+		if err != nil || usage(disk) < ImageGCHighWatermark {
+			continue
+		}
+		w.CollectImages(context.Background(), false)
+	}
+}
+
+// CollectImages removes the images none of the tasks need, the least
+// recently used first, until the disk usage drops to the low watermark.
+// All of them are removed if all is set
+func (w *Worker) CollectImages(ctx context.Context, all bool) (GCReport, error) {
+	w.muGC.Lock()
+	defer w.muGC.Unlock()
+
+	report := GCReport{Removed: make([]string, 0)}
+
+	var target uint64
+	if !all {
+		disk, err := node.Disk()
+		if err != nil {
+			return report, err
+		}
+
+		target = bytesAbove(disk, ImageGCLowWatermark)
+		if target == 0 {
+			return report, nil
+		}
+	}
+
+	images, err := w.runtime.Images(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	for _, img := range w.unusedImages(images) {
+		if !all && report.Reclaimed >= target {
+			break
+		}
+
+		// NOTE(SergeyCherepiuk): Images used by the containers outside of
+		// the cluster can't be removed, they are skipped
+		if err := w.runtime.RemoveImage(ctx, img.Id); err != nil {
+			continue
+		}
+
+		report.Removed = append(report.Removed, imageName(img))
+		report.Reclaimed += img.Size
+		w.forgetImage(img)
+	}
+
+	w.muImages.Lock()
+	w.reclaimed += report.Reclaimed
+	w.muImages.Unlock()
+	return report, nil
+}
+
+// unusedImages leaves out the images of the tasks that are not done yet and
+// the ones used recently, the rest are sorted from the least recently used
+func (w *Worker) unusedImages(images []image.Info) []image.Info {
+	inUse := make(map[string]bool)
+	if worker, err := w.store.GetWorker(w.Id); err == nil {
+		worker.MuTasks.RLock()
+		for _, t := range worker.Tasks {
+			if !terminal(t.State) {
+				inUse[image.Normalize(t.Container.Image.Ref)] = true
+			}
+		}
+		worker.MuTasks.RUnlock()
+	}
+
+	w.muImages.Lock()
+	lastUsed := maps.Clone(w.imagesUsed)
+	w.muImages.Unlock()
+
+	used := func(img image.Info) time.Time {
+		var last time.Time
+		for _, ref := range img.Refs {
+			if t := lastUsed[image.Normalize(ref)]; t.After(last) {
+				last = t
+			}
+		}
+		return last
+	}
+
+	unused := slices.DeleteFunc(slices.Clone(images), func(img image.Info) bool {
+		for _, ref := range img.Refs {
+			if inUse[image.Normalize(ref)] {
+				return true
+			}
+		}
+		return time.Since(used(img)) < ImageMinAge
+	})
+
+	// NOTE(SergeyCherepiuk): Images the worker has never used go first
+	slices.SortStableFunc(unused, func(a, b image.Info) int {
+		return used(a).Compare(used(b))
+	})
+	return unused
+}
+
+func (w *Worker) useImage(ref string) {
+	w.muImages.Lock()
+	defer w.muImages.Unlock()
+	w.imagesUsed[image.Normalize(ref)] = time.Now()
+}
+
+func (w *Worker) forgetImage(img image.Info) {
+	w.muImages.Lock()
+	defer w.muImages.Unlock()
+	for _, ref := range img.Refs {
+		delete(w.imagesUsed, image.Normalize(ref))
+	}
+}
+
+func imageName(img image.Info) string {
+	if len(img.Refs) == 0 {
+		return img.Id
+	}
+	return img.Refs[0]
+}
+
+func usage(disk node.DiskStat) float64 {
+	if disk.Total == 0 {
+		return 0
+	}
+	return float64(disk.Total-disk.Available) / float64(disk.Total)
+}
+
+// bytesAbove returns how much has to be freed for the disk usage to drop to
+// the watermark
+func bytesAbove(disk node.DiskStat, watermark float64) uint64 {
+	used := disk.Total - disk.Available
+	allowed := uint64(float64(disk.Total) * watermark)
+	if used <= allowed {
+		return 0
+	}
+	return used - allowed
+}
@@ -0,0 +1,205 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/container"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+)
+
+// startProbes runs the health and readiness checks of the container until
+// it stops or the worker does
+func (w *Worker) startProbes(t task.Task) {
+	config := t.Container.Config
+	if config.HealthCheck == nil && config.ReadinessCheck == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.muContainers.Lock()
+	w.probes[t.Container.Id] = cancel
+	w.muContainers.Unlock()
+
+	if config.HealthCheck != nil {
+		go w.probe(ctx, t.Container.Id, *config.HealthCheck, w.livenessHandler(t.Container.Id, config.HealthCheck.Retries))
+	}
+	if config.ReadinessCheck != nil {
+		go w.probe(ctx, t.Container.Id, *config.ReadinessCheck, w.readinessHandler(t.Container.Id, config.ReadinessCheck.Retries, t.Ready))
+	}
+}
+
+// ensureProbes starts the probes of the running task unless they are running
+// already, the tasks adopted by the worker have not been started by it
+func (w *Worker) ensureProbes(t task.Task) {
+	w.muContainers.Lock()
+	_, probed := w.probes[t.Container.Id]
+	reported, ok := w.reported[t.Container.Id]
+	w.muContainers.Unlock()
+
+	// NOTE(SergeyCherepiuk): Containers that are being stopped may still be
+	// running, their probes must not come back
+	if !probed && !(ok && terminal(reported)) {
+		w.startProbes(t)
+	}
+}
+
+func (w *Worker) stopProbes(containerId string) {
+	w.muContainers.Lock()
+	defer w.muContainers.Unlock()
+
+	if cancel, ok := w.probes[containerId]; ok {
+		cancel()
+		delete(w.probes, containerId)
+	}
+}
+
+// probe passes the result of every check that counts to the handler, until
+// the handler returns false
+func (w *Worker) probe(ctx context.Context, containerId string, check container.HealthCheck, handle func(healthy bool) bool) {
+	startedAt := time.Now()
+	succeeded := false
+
+	ticker := time.NewTicker(check.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		err := w.check(ctx, containerId, check)
+		if ctx.Err() != nil {
+			return
+		}
+
+		succeeded = succeeded || err == nil
+		if err != nil && !succeeded && time.Since(startedAt) < check.StartPeriod {
+			continue
+		}
+
+		if !handle(err == nil) {
+			return
+		}
+	}
+}
+
+// livenessHandler reports the container as unhealthy and removes it once
+// the check fails the given number of times in a row
+func (w *Worker) livenessHandler(containerId string, retries int) func(bool) bool {
+	failures := 0
+	return func(healthy bool) bool {
+		if healthy {
+			failures = 0
+			return true
+		}
+
+		failures++
+		if failures < retries {
+			return true
+		}
+
+		t, ok := w.taskByContainerId(containerId)
+		if ok && w.report(t, task.Unhealthy) {
+			w.runtime.StopAndRemove(context.Background(), containerId)
+		}
+		return false
+	}
+}
+
+// readinessHandler reports the changes of the readiness, the container
+// becomes not ready once the check fails the given number of times in a row
+func (w *Worker) readinessHandler(containerId string, retries int, ready bool) func(bool) bool {
+	failures := 0
+	return func(healthy bool) bool {
+		if healthy {
+			failures = 0
+		} else {
+			failures++
+		}
+
+		switch {
+		case healthy && !ready:
+			ready = true
+		case !healthy && ready && failures >= retries:
+			ready = false
+		default:
+			return true
+		}
+
+		t, ok := w.taskByContainerId(containerId)
+		if !ok || t.State != task.Running {
+			return true
+		}
+
+		t.Ready = ready
+		w.sendMessage(Message{From: w.Id, Task: t})
+		return true
+	}
+}
+
+func (w *Worker) check(ctx context.Context, containerId string, check container.HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	switch check.Type {
+	case container.ExecCheck:
+		opts := c14n.ExecOptions{Cmd: check.Command}
+		streams := c14n.ExecStreams{Output: io.Discard}
+		code, err := w.runtime.Exec(ctx, containerId, opts, streams)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return fmt.Errorf("check exited with code %d", code)
+		}
+		return nil
+
+	case container.HTTPCheck:
+		addr, err := w.runtime.HostAddr(ctx, containerId, check.Port)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+check.Path, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("check responded with status %d", resp.StatusCode)
+		}
+		return nil
+
+	case container.TCPCheck:
+		addr, err := w.runtime.HostAddr(ctx, containerId, check.Port)
+		if err != nil {
+			return err
+		}
+
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	default:
+		return fmt.Errorf("unknown check type %q", check.Type)
+	}
+}
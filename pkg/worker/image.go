@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
+)
+
+var (
+	ErrImageNotPresent = errors.New("image is not present on the node and the pull policy is never")
+	ErrNoCredentials   = errors.New("registry credentials are not handed over with the task")
+)
+
+// pullImage makes the image available to the runtime according to its pull
+// policy, so the restarts don't have to reach the registry every time. Auth
+// comes from the manager along with the task, the store of the worker has
+// the passwords redacted
+func (w *Worker) pullImage(ctx context.Context, img image.Image, auth *image.Credentials) error {
+	if img.PullPolicy != image.PullAlways {
+		present, err := w.runtime.ImagePresent(ctx, img.Ref)
+		if err != nil {
+			return fmt.Errorf("failed to inspect image: %w", err)
+		}
+		if present {
+			return nil
+		}
+		if img.PullPolicy == image.PullNever {
+			return ErrImageNotPresent
+		}
+	}
+
+	if img.Credentials != "" && auth == nil {
+		return fmt.Errorf("%w: %q", ErrNoCredentials, img.Credentials)
+	}
+
+	if err := w.runtime.PullImage(ctx, img.Ref, auth); err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+	return nil
+}
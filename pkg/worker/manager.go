@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+)
+
+const (
+	MessageQueueInterval = 100 * time.Millisecond
+	MessageRetryInterval = 500 * time.Millisecond
+)
+
+var ErrNoManagerAvailable = errors.New("no manager is available")
+
+type Heartbeat struct {
+	ManagerAddr string
+	LastIndex   int
+}
+
+func (w *Worker) managerAddr() string {
+	w.muManager.RLock()
+	defer w.muManager.RUnlock()
+	return w.leaderAddr
+}
+
+// followManager switches the worker to the given manager if it is not the
+// one the worker is talking to already, registering on it once again
+func (w *Worker) followManager(addr string) {
+	w.muManager.Lock()
+	changed := w.leaderAddr != addr
+	w.leaderAddr = addr
+	w.muManager.Unlock()
+
+	if changed {
+		w.register()
+	}
+}
+
+func (w *Worker) discoverManager() error {
+	for _, addr := range w.managerAddrs {
+		resp, err := httpclient.Get(addr, "/raft/leader")
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		var leader string
+		if err := httpinternal.Body(resp, &leader); err != nil || leader == "" {
+			continue
+		}
+
+		w.muManager.Lock()
+		w.leaderAddr = leader
+		w.muManager.Unlock()
+		return nil
+	}
+	return ErrNoManagerAvailable
+}
+
+func (w *Worker) postToManager(endpoint string, payload any) error {
+	resp, err := httpclient.Post(w.managerAddr(), endpoint, payload)
+	if err == nil && delivered(resp) {
+		return nil
+	}
+
+	if err := w.discoverManager(); err != nil {
+		return err
+	}
+
+	resp, err = httpclient.Post(w.managerAddr(), endpoint, payload)
+	if err != nil {
+		return err
+	}
+
+	if !delivered(resp) {
+		return errors.New(httpinternal.ErrorMessage(resp.Body))
+	}
+	return nil
+}
+
+func delivered(resp *http.Response) bool {
+	return resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices
+}
+
+func (w *Worker) register() error {
+	endpoint := fmt.Sprintf("/worker/%s", w.Id)
+	return w.postToManager(endpoint, w.Node.Addr)
+}
+
+func (w *Worker) sendMessage(message Message) {
+	w.messages.Enqueue(message)
+}
+
+// deliverMessages sends the queued messages in order and in batches,
+// holding on to the undelivered ones until a manager (possibly a newly
+// elected one) accepts them
+func (w *Worker) deliverMessages() {
+	for !w.stopped() {
+		messages := slices.Clone(w.messages.GetAll())
+		if len(messages) == 0 {
+			time.Sleep(MessageQueueInterval)
+			continue
+		}
+
+		if err := w.postToManager("/worker/messages", messages); err != nil {
+			time.Sleep(MessageRetryInterval)
+			continue
+		}
+
+		for range messages {
+			w.messages.Pop()
+		}
+	}
+}
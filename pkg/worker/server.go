@@ -2,15 +2,27 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	httpinternal "github.com/SergeyCherepiuk/fleet/internal/http"
+	"github.com/SergeyCherepiuk/fleet/pkg/attach"
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
 	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
 	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
 func StartServer(addr string, worker *Worker) error {
+	return NewServer(worker).Start(addr)
+}
+
+func NewServer(worker *Worker) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
 
@@ -23,8 +35,20 @@ func StartServer(addr string, worker *Worker) error {
 			)
 		}
 
+		var auth *image.Credentials
+		if header := c.Request().Header.Get(image.AuthHeader); header != "" {
+			creds, err := image.DecodeAuth(header)
+			if err != nil {
+				return echo.NewHTTPError(
+					http.StatusBadRequest,
+					fmt.Errorf("invalid registry auth format: %w", err),
+				)
+			}
+			auth = &creds
+		}
+
 		ctx := context.Background()
-		if err := worker.Run(ctx, t); err != nil {
+		if err := worker.Run(ctx, t, auth); err != nil {
 			c.JSON(http.StatusInternalServerError, t)
 		}
 
@@ -48,6 +72,79 @@ func StartServer(addr string, worker *Worker) error {
 		return c.JSON(http.StatusOK, t)
 	})
 
+	e.GET("/task/logs/:id", func(c echo.Context) error {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+		}
+
+		opts, err := parseLogsOptions(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		logs, err := worker.Logs(c.Request().Context(), id, opts)
+		if errors.Is(err, consensus.ErrTaskNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		if errors.Is(err, c14n.ErrSinceUnsupported) {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		defer logs.Close()
+
+		resp := c.Response()
+		resp.Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+		resp.WriteHeader(http.StatusOK)
+		resp.Flush()
+
+		httpinternal.Stream(resp, logs)
+		return nil
+	})
+
+	e.GET("/task/exec/:id", func(c echo.Context) error {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+		}
+
+		opts, stdin, err := parseExecOptions(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		if err := worker.CheckTask(id); err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+
+		conn, err := attach.Upgrade(c.Response(), c.Request())
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		defer conn.Close()
+
+		// NOTE(SergeyCherepiuk): The hijacked connection is not tied to the
+		// request, the session is cancelled once the client is found gone
+		ctx, cancel := context.WithCancel(c.Request().Context())
+		defer cancel()
+
+		out := &attach.SyncWriter{W: conn}
+		go attach.KeepAlive(ctx, out, cancel)
+
+		streams := c14n.ExecStreams{Output: attach.OutputWriter{W: out}}
+		if stdin {
+			streams.Stdin = conn
+		}
+
+		code, err := worker.Exec(ctx, id, opts, streams)
+		if err != nil {
+			return attach.WriteFrame(out, attach.Error, []byte(err.Error()))
+		}
+		return attach.WriteExit(out, code)
+	})
+
 	e.GET("/resources/available", func(c echo.Context) error {
 		resources, err := worker.AvailableResources()
 		if err != nil {
@@ -73,6 +170,64 @@ func StartServer(addr string, worker *Worker) error {
 		return c.NoContent(http.StatusCreated)
 	})
 
+	e.POST("/store/snapshot", func(c echo.Context) error {
+		var chunk consensus.SnapshotChunk
+		if err := c.Bind(&chunk); err != nil {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Errorf("invalid snapshot chunk format: %w", err),
+			)
+		}
+
+		done, err := worker.ReceiveSnapshotChunk(chunk)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusConflict, err)
+		}
+
+		if !done {
+			return c.NoContent(http.StatusAccepted)
+		}
+		return c.NoContent(http.StatusCreated)
+	})
+
+	e.GET("/store/log", func(c echo.Context) error {
+		from, err := strconv.Atoi(c.QueryParam("from"))
+		if err != nil && c.QueryParam("from") != "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid index format")
+		}
+
+		return c.JSON(http.StatusOK, worker.StoreLog(from))
+	})
+
+	e.GET("/store/dump", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, worker.StoreDump())
+	})
+
+	e.GET("/stats", func(c echo.Context) error {
+		stats, err := worker.Stats(c.Request().Context())
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return c.JSON(http.StatusOK, stats)
+	})
+
+	e.POST("/gc", func(c echo.Context) error {
+		var all bool
+		if param := c.QueryParam("all"); param != "" {
+			var err error
+			if all, err = strconv.ParseBool(param); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("invalid all format: %w", err))
+			}
+		}
+
+		report, err := worker.CollectImages(c.Request().Context(), all)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+
+		return c.JSON(http.StatusOK, report)
+	})
+
 	e.GET("/info", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, worker.Info())
 	})
@@ -82,14 +237,82 @@ func StartServer(addr string, worker *Worker) error {
 			return c.NoContent(http.StatusInternalServerError)
 		}
 
-		var lastIndex int
-		if err := c.Bind(&lastIndex); err != nil {
+		var heartbeat Heartbeat
+		if err := c.Bind(&heartbeat); err != nil {
 			return c.JSON(http.StatusOK, 0)
 		}
 
-		off := worker.CheckStoreSynchronization(lastIndex)
+		if heartbeat.ManagerAddr != "" {
+			go worker.followManager(heartbeat.ManagerAddr)
+		}
+
+		off := worker.CheckStoreSynchronization(heartbeat.LastIndex)
 		return c.JSON(http.StatusOK, off)
 	})
 
-	return e.Start(addr)
+	return e
+}
+
+func parseLogsOptions(c echo.Context) (c14n.LogsOptions, error) {
+	var (
+		opts c14n.LogsOptions
+		err  error
+	)
+
+	if param := c.QueryParam("follow"); param != "" {
+		if opts.Follow, err = strconv.ParseBool(param); err != nil {
+			return opts, fmt.Errorf("invalid follow format: %w", err)
+		}
+	}
+
+	if param := c.QueryParam("tail"); param != "" {
+		if opts.Tail, err = strconv.Atoi(param); err != nil {
+			return opts, fmt.Errorf("invalid tail format: %w", err)
+		}
+	}
+
+	if param := c.QueryParam("since"); param != "" {
+		if opts.Since, err = time.Parse(time.RFC3339, param); err != nil {
+			return opts, fmt.Errorf("invalid since format: %w", err)
+		}
+	}
+
+	return opts, nil
+}
+
+func parseExecOptions(c echo.Context) (c14n.ExecOptions, bool, error) {
+	query := c.QueryParams()
+	opts := c14n.ExecOptions{Cmd: query["cmd"], Env: query["env"]}
+
+	var (
+		stdin bool
+		err   error
+	)
+
+	if param := query.Get("tty"); param != "" {
+		if opts.Tty, err = strconv.ParseBool(param); err != nil {
+			return opts, false, fmt.Errorf("invalid tty format: %w", err)
+		}
+	}
+
+	if param := query.Get("stdin"); param != "" {
+		if stdin, err = strconv.ParseBool(param); err != nil {
+			return opts, false, fmt.Errorf("invalid stdin format: %w", err)
+		}
+	}
+
+	for name, size := range map[string]*uint{"width": &opts.Width, "height": &opts.Height} {
+		if param := query.Get(name); param != "" {
+			n, err := strconv.ParseUint(param, 10, 16)
+			if err != nil {
+				return opts, false, fmt.Errorf("invalid %s format: %w", name, err)
+			}
+			*size = uint(n)
+		}
+	}
+
+	if len(opts.Cmd) == 0 {
+		return opts, false, errors.New("command is not provided")
+	}
+	return opts, stdin, nil
 }
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/task"
+	"github.com/google/uuid"
+)
+
+type TaskStats struct {
+	TaskId   uuid.UUID
+	WorkerId uuid.UUID
+	Image    string
+	Stats    c14n.Stats
+}
+
+// Stats samples the resource usage of the running tasks concurrently, the
+// tasks whose stats can't be read, e.g. they have just exited, are left out
+func (w *Worker) Stats(ctx context.Context) ([]TaskStats, error) {
+	worker, err := w.store.GetWorker(w.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	worker.MuTasks.RLock()
+	running := make([]task.Task, 0, len(worker.Tasks))
+	for _, t := range worker.Tasks {
+		if t.State == task.Running && t.Container.Id != "" {
+			running = append(running, t)
+		}
+	}
+	worker.MuTasks.RUnlock()
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		stats = make([]TaskStats, 0, len(running))
+	)
+
+	for _, t := range running {
+		wg.Add(1)
+		go func(t task.Task) {
+			defer wg.Done()
+
+			s, err := w.runtime.Stats(ctx, t.Container.Id)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			stats = append(stats, TaskStats{
+				TaskId:   t.Id,
+				WorkerId: w.Id,
+				Image:    t.Container.Image.Ref,
+				Stats:    s,
+			})
+		}(t)
+	}
+
+	wg.Wait()
+	return stats, nil
+}
@@ -2,24 +2,30 @@ package worker
 
 import (
 	"context"
-	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"os/signal"
-	"strings"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/SergeyCherepiuk/fleet/pkg/c14n"
+	"github.com/SergeyCherepiuk/fleet/pkg/collections/queue"
 	"github.com/SergeyCherepiuk/fleet/pkg/consensus"
 	"github.com/SergeyCherepiuk/fleet/pkg/container"
-	"github.com/SergeyCherepiuk/fleet/pkg/httpclient"
+	"github.com/SergeyCherepiuk/fleet/pkg/image"
 	"github.com/SergeyCherepiuk/fleet/pkg/node"
 	"github.com/SergeyCherepiuk/fleet/pkg/task"
 	"github.com/google/uuid"
+	"golang.org/x/exp/maps"
 )
 
 const (
-	InspectInterval        = time.Second
+	// ReconcileInterval is how often the states of the tasks are compared
+	// with the containers, in case some of the events have been missed
+	ReconcileInterval      = 30 * time.Second
+	RetryInterval          = time.Second
+	EventMatchTimeout      = 10 * time.Second
 	ShutdownTimeoutSeconds = 5
 )
 
@@ -28,8 +34,24 @@ type Worker struct {
 	Node         node.Node
 	runtime      c14n.Runtime
 	store        consensus.Store
-	managerAddr  string
-	shutdownCmds chan *exec.Cmd
+	snapshots    *consensus.SnapshotReceiver
+	managerAddrs []string
+	muManager    sync.RWMutex
+	leaderAddr   string
+	messages     *queue.Queue[Message]
+	muContainers sync.Mutex
+	reported     map[string]task.State
+	started      map[string]task.Task
+	probes       map[string]context.CancelFunc
+	reconcileNow chan struct{}
+	muGC         sync.Mutex
+	muImages     sync.Mutex
+	imagesUsed   map[string]time.Time
+	reclaimed    uint64
+	heartbeats   chan struct{}
+	guarded      bool
+	done         chan struct{}
+	stopOnce     sync.Once
 }
 
 type Message struct {
@@ -37,48 +59,102 @@ type Message struct {
 	Task task.Task
 }
 
-func New(node node.Node, runtime c14n.Runtime, managerAddr string) *Worker {
+func New(node node.Node, runtime c14n.Runtime, managerAddrs []string) *Worker {
 	worker := &Worker{
 		Id:           uuid.New(),
 		Node:         node,
 		runtime:      runtime,
 		store:        consensus.NewLocalStore(),
-		managerAddr:  managerAddr,
-		shutdownCmds: make(chan *exec.Cmd),
+		snapshots:    consensus.NewSnapshotReceiver(),
+		managerAddrs: managerAddrs,
+		leaderAddr:   managerAddrs[0],
+		messages:     queue.NewQueue[Message](0),
+		reported:     make(map[string]task.State),
+		started:      make(map[string]task.Task),
+		probes:       make(map[string]context.CancelFunc),
+		reconcileNow: make(chan struct{}, 1),
+		imagesUsed:   make(map[string]time.Time),
+		heartbeats:   make(chan struct{}, 1),
+		done:         make(chan struct{}),
 	}
+	return worker
+}
 
-	worker.register()
-	go worker.inspectTasks()
-	go worker.spawnShutdownProcesses()
-	go worker.catchInterrupt()
+// Start registers the worker on the manager and starts running the tasks
+// assigned to it
+func (w *Worker) Start() {
+	w.register()
+	go w.deliverMessages()
+	go w.watchEvents()
+	go w.reconcileTasks()
+	go w.collectImages()
+}
 
-	return worker
+// Stop stops the background work started by Start, the tasks are left
+// running, as if the worker has lost the connection to the managers
+func (w *Worker) Stop() {
+	w.stopOnce.Do(func() { close(w.done) })
 }
 
-func (w *Worker) Run(ctx context.Context, t task.Task) error {
+// GuardProcess makes the process shut down the worker and all of its
+// containers once it stops receiving heartbeats or gets interrupted
+func (w *Worker) GuardProcess() {
+	w.guarded = true
+	go w.guardHeartbeats()
+	go w.catchInterrupt()
+}
+
+func (w *Worker) stopped() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *Worker) Run(ctx context.Context, t task.Task, auth *image.Credentials) error {
 	defer func() {
 		message := Message{From: w.Id, Task: t}
-		httpclient.Post(w.managerAddr, "/worker/message", message)
+		w.sendMessage(message)
 	}()
 
+	w.useImage(t.Container.Image.Ref)
+	if err := w.pullImage(ctx, t.Container.Image, auth); err != nil {
+		t.State = task.FailedOnStartup
+		t.Reason = err.Error()
+		return err
+	}
+
 	id, err := w.runtime.CreateAndRun(ctx, t.Container)
 	if err != nil {
 		t.State = task.FailedOnStartup
+		t.Reason = err.Error()
 		return err
 	}
 
 	t.Container.Id = id
 	t.State = task.Running
+	t.Reason = ""
 	t.StartedAt = append(t.StartedAt, time.Now())
+	t.Ready = t.Container.Config.ReadinessCheck == nil
+	w.setStarted(t)
+	w.startProbes(t)
 	return nil
 }
 
 func (w *Worker) Finish(ctx context.Context, t task.Task) error {
 	defer func() {
 		message := Message{From: w.Id, Task: t}
-		httpclient.Post(w.managerAddr, "/worker/message", message)
+		w.sendMessage(message)
 	}()
 
+	// NOTE(SergeyCherepiuk): The container exits once it is stopped, that
+	// must not be reported as a failure
+	w.setReported(t.Container.Id, task.Finished)
+	w.stopProbes(t.Container.Id)
+	t.Ready = false
+
 	if err := w.runtime.StopAndRemove(ctx, t.Container.Id); err != nil {
 		t.State = task.FailedAfterStartup
 		return err
@@ -95,18 +171,28 @@ type Info struct {
 	ManagerAddr string
 	TasksCount  int
 	RuntimeName string
+	HostNetwork bool // NOTE(SergeyCherepiuk): Tasks exposing the same port can not run on the worker together
+
+	// ReclaimedBytes is the disk space freed by the image GC so far
+	ReclaimedBytes uint64
 }
 
 func (w *Worker) Info() *Info {
 	workerFromStore, _ := w.store.GetWorker(w.Id)
 	tasksCount := len(workerFromStore.Tasks)
 
+	w.muImages.Lock()
+	reclaimed := w.reclaimed
+	w.muImages.Unlock()
+
 	return &Info{
-		Id:          w.Id,
-		Addr:        w.Node.Addr,
-		ManagerAddr: w.managerAddr,
-		TasksCount:  tasksCount,
-		RuntimeName: w.runtime.Name(),
+		Id:             w.Id,
+		Addr:           w.Node.Addr,
+		ManagerAddr:    w.managerAddr(),
+		TasksCount:     tasksCount,
+		RuntimeName:    w.runtime.Name(),
+		HostNetwork:    c14n.SharesHostNetwork(w.runtime),
+		ReclaimedBytes: reclaimed,
 	}
 }
 
@@ -119,13 +205,67 @@ func (w *Worker) CommitChanges(cmds ...consensus.Command) (int, error) {
 	return 0, nil
 }
 
+func (w *Worker) ReceiveSnapshotChunk(chunk consensus.SnapshotChunk) (bool, error) {
+	return w.snapshots.Receive(w.store, chunk)
+}
+
+func (w *Worker) Logs(ctx context.Context, taskId uuid.UUID, opts c14n.LogsOptions) (io.ReadCloser, error) {
+	id, err := w.containerId(taskId)
+	if err != nil {
+		return nil, err
+	}
+	return w.runtime.Logs(ctx, id, opts)
+}
+
+func (w *Worker) Exec(ctx context.Context, taskId uuid.UUID, opts c14n.ExecOptions, streams c14n.ExecStreams) (int, error) {
+	id, err := w.containerId(taskId)
+	if err != nil {
+		return 0, err
+	}
+	return w.runtime.Exec(ctx, id, opts, streams)
+}
+
+// CheckTask fails unless the task is assigned to the worker and has been
+// started already
+func (w *Worker) CheckTask(taskId uuid.UUID) error {
+	_, err := w.containerId(taskId)
+	return err
+}
+
+func (w *Worker) containerId(taskId uuid.UUID) (string, error) {
+	worker, err := w.store.GetWorker(w.Id)
+	if err != nil {
+		return "", err
+	}
+
+	worker.MuTasks.RLock()
+	t, ok := worker.Tasks[taskId]
+	worker.MuTasks.RUnlock()
+
+	if !ok || t.Container.Id == "" {
+		return "", consensus.ErrTaskNotFound
+	}
+	return t.Container.Id, nil
+}
+
+func (w *Worker) StoreLog(fromIndex int) []consensus.LogEntry {
+	return consensus.Log(w.store, fromIndex)
+}
+
+func (w *Worker) StoreDump() consensus.Dump {
+	return consensus.NewDump(w.store)
+}
+
 func (w *Worker) CancleShutdown() error {
-	cmd := <-w.shutdownCmds
-	if cmd == nil || cmd.Process == nil {
+	if !w.guarded {
 		return nil
 	}
 
-	return exec.Command("kill", "-9", fmt.Sprint(cmd.Process.Pid)).Run()
+	select {
+	case w.heartbeats <- struct{}{}:
+	default:
+	}
+	return nil
 }
 
 func (w *Worker) CheckStoreSynchronization(lastIndex int) int {
@@ -175,12 +315,6 @@ func (w *Worker) ReservedResources() (container.RequiredResources, error) {
 	return resources, nil
 }
 
-func (w *Worker) register() error {
-	endpoint := fmt.Sprintf("/worker/%s", w.Id)
-	_, err := httpclient.Post(w.managerAddr, endpoint, w.Node.Addr)
-	return err
-}
-
 func mapState(state container.State) task.State {
 	switch state {
 	case container.State{Status: "created", ExitCode: 0},
@@ -198,75 +332,261 @@ func mapState(state container.State) task.State {
 	}
 }
 
-func (w *Worker) inspectTasks() {
-	ctx := context.Background()
-	for {
-		containers, err := w.runtime.Containers(ctx)
-		if err != nil {
-			continue
-		}
+func terminal(state task.State) bool {
+	return state == task.Finished || state.Fail()
+}
 
-		containerIdsToStates := make(map[string]task.State)
-		for _, container := range containers {
-			state, _ := w.runtime.ContainerState(ctx, container.Id)
-			containerIdsToStates[container.Id] = mapState(state)
+// watchEvents drives the states of the tasks by the events of the runtime,
+// subscribing again whenever the stream ends
+func (w *Worker) watchEvents() {
+	for !w.stopped() {
+		ctx, cancel := context.WithCancel(context.Background())
+		events, errs := w.runtime.Events(ctx)
+
+		// NOTE(SergeyCherepiuk): Events might have been missed while the
+		// stream was down
+		w.requestReconcile()
+		w.consumeEvents(events, errs)
+		cancel()
+
+		select {
+		case <-w.done:
+		case <-time.After(RetryInterval):
 		}
+	}
+}
 
-		worker, err := w.store.GetWorker(w.Id)
-		if err != nil {
-			continue
+type unmatchedEvent struct {
+	c14n.Event
+	receivedAt time.Time
+}
+
+func (w *Worker) consumeEvents(events <-chan c14n.Event, errs <-chan error) {
+	// NOTE(SergeyCherepiuk): The container might change its state before
+	// the task with its id is replicated to the store, such events are
+	// retried for a while
+	unmatched := make([]unmatchedEvent, 0)
+	ticker := time.NewTicker(MessageQueueInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-errs:
+			return
+		case event := <-events:
+			if !w.handleEvent(event) {
+				unmatched = append(unmatched, unmatchedEvent{Event: event, receivedAt: time.Now()})
+			}
+		case <-ticker.C:
+			unmatched = slices.DeleteFunc(unmatched, func(e unmatchedEvent) bool {
+				return w.handleEvent(e.Event) || time.Since(e.receivedAt) > EventMatchTimeout
+			})
 		}
+	}
+}
+
+// handleEvent returns false if none of the tasks runs in the container
+func (w *Worker) handleEvent(event c14n.Event) bool {
+	t, found := w.taskByContainerId(event.ContainerId)
+	if !found {
+		return false
+	}
+
+	state := mapState(event.State)
+	if w.report(t, state) && terminal(state) {
+		w.runtime.StopAndRemove(context.Background(), t.Container.Id)
+	}
+	return true
+}
 
+// taskByContainerId looks the task up in the store, falling back to the
+// tasks started by the worker that are not replicated to the store yet
+func (w *Worker) taskByContainerId(containerId string) (task.Task, bool) {
+	if worker, err := w.store.GetWorker(w.Id); err == nil {
 		worker.MuTasks.RLock()
+		defer worker.MuTasks.RUnlock()
+
 		for _, t := range worker.Tasks {
-			actualState, ok := containerIdsToStates[t.Container.Id]
-			if !ok && t.State == task.Running {
-				t.State = task.FailedAfterStartup
-				message := Message{From: w.Id, Task: t}
-				httpclient.Post(w.managerAddr, "/worker/message", message)
+			if t.Container.Id == containerId {
+				return t, true
 			}
+		}
+	}
 
-			if !ok {
-				continue
-			}
+	w.muContainers.Lock()
+	defer w.muContainers.Unlock()
+	t, ok := w.started[containerId]
+	return t, ok
+}
 
-			if t.State != actualState {
-				t.State = actualState
-				message := Message{From: w.Id, Task: t}
-				httpclient.Post(w.managerAddr, "/worker/message", message)
-			}
+func (w *Worker) requestReconcile() {
+	select {
+	case w.reconcileNow <- struct{}{}:
+	default:
+	}
+}
 
-			if actualState == task.Finished || actualState.Fail() {
-				w.runtime.StopAndRemove(ctx, t.Container.Id)
-			}
+// reconcileTasks is the safety net for the events, comparing the states of
+// all the tasks with their containers
+func (w *Worker) reconcileTasks() {
+	for !w.stopped() {
+		interval := ReconcileInterval
+		if err := w.reconcile(context.Background()); err != nil {
+			interval = RetryInterval
 		}
-		worker.MuTasks.RUnlock()
 
-		time.Sleep(InspectInterval)
+		select {
+		case <-w.done:
+		case <-w.reconcileNow:
+		case <-time.After(interval):
+		}
 	}
 }
 
-func (w *Worker) spawnShutdownProcesses() {
-	for {
-		sleep := fmt.Sprintf("sleep %d", ShutdownTimeoutSeconds)
-		kill := fmt.Sprintf("kill -15 %d", os.Getpid())
-		stop := fmt.Sprintf(
-			"docker rm -f $(docker ps -qaf 'label=%s=%s')",
-			container.TypeLabelKey, container.TypeLabelValue,
-		)
+func (w *Worker) reconcile(ctx context.Context) error {
+	containers, err := w.runtime.Containers(ctx)
+	if err != nil {
+		return err
+	}
+
+	containerIdsToStates := make(map[string]task.State)
+	for _, container := range containers {
+		state, _ := w.runtime.ContainerState(ctx, container.Id)
+		containerIdsToStates[container.Id] = mapState(state)
+	}
+
+	worker, err := w.store.GetWorker(w.Id)
+	if err != nil {
+		return err
+	}
+
+	worker.MuTasks.RLock()
+	tasks := maps.Values(worker.Tasks)
+	worker.MuTasks.RUnlock()
+
+	for _, t := range tasks {
+		actualState, ok := containerIdsToStates[t.Container.Id]
+		if !ok && t.State == task.Running {
+			w.report(t, task.FailedAfterStartup)
+		}
+
+		if !ok {
+			continue
+		}
+
+		w.report(t, actualState)
+		if terminal(actualState) {
+			w.runtime.StopAndRemove(ctx, t.Container.Id)
+		}
 
-		commands := strings.Join([]string{sleep, kill, stop}, "; ")
-		cmd := exec.Command("/bin/sh", "-c", commands)
-		cmd.Start()
-		w.shutdownCmds <- cmd
+		if t.State == task.Running && actualState == task.Running {
+			w.ensureProbes(t)
+		}
+	}
+
+	w.pruneContainers(tasks)
+	return nil
+}
+
+// report sends the state of the task to the manager, unless it is already
+// known or has been reported but not replicated yet. The containers never
+// leave a terminal state, later changes are ignored
+func (w *Worker) report(t task.Task, state task.State) bool {
+	w.muContainers.Lock()
+	last, ok := w.reported[t.Container.Id]
+	if ok && (last == state || terminal(last)) || !ok && t.State == state {
+		w.muContainers.Unlock()
+		return false
+	}
+	w.reported[t.Container.Id] = state
+	w.muContainers.Unlock()
+
+	if terminal(state) {
+		w.stopProbes(t.Container.Id)
+		t.Ready = false
+		t.FinishedAt = append(t.FinishedAt, time.Now())
+	}
+
+	t.State = state
+	message := Message{From: w.Id, Task: t}
+	w.sendMessage(message)
+	return true
+}
+
+func (w *Worker) setReported(containerId string, state task.State) {
+	w.muContainers.Lock()
+	defer w.muContainers.Unlock()
+	w.reported[containerId] = state
+}
+
+func (w *Worker) setStarted(t task.Task) {
+	w.muContainers.Lock()
+	defer w.muContainers.Unlock()
+	w.started[t.Container.Id] = t
+}
+
+// pruneContainers forgets the states that the store has caught up with, the
+// containers that are not used by the tasks anymore and the started tasks
+// that are replicated already (or never will be)
+func (w *Worker) pruneContainers(tasks []task.Task) {
+	containerIdsToStates := make(map[string]task.State, len(tasks))
+	for _, t := range tasks {
+		containerIdsToStates[t.Container.Id] = t.State
+	}
+
+	w.muContainers.Lock()
+	defer w.muContainers.Unlock()
+
+	for id, state := range w.reported {
+		if stored, ok := containerIdsToStates[id]; !ok || stored == state {
+			delete(w.reported, id)
+		}
+	}
+
+	for id, t := range w.started {
+		_, stored := containerIdsToStates[id]
+		if stored || time.Since(t.StartedAt[len(t.StartedAt)-1]) > ReconcileInterval {
+			delete(w.started, id)
+		}
+	}
+}
+
+// guardHeartbeats shuts the worker down once the heartbeats stop coming, the
+// managers reschedule its tasks elsewhere by then, so they must not keep
+// running here
+func (w *Worker) guardHeartbeats() {
+	timeout := ShutdownTimeoutSeconds * time.Second
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.heartbeats:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(timeout)
+		case <-timer.C:
+			w.shutdown()
+		}
 	}
 }
 
 func (w *Worker) catchInterrupt() {
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt)
 	<-ch
 
+	w.shutdown()
+}
+
+// shutdown stops and removes the containers through the runtime the worker
+// runs them with and exits the process
+func (w *Worker) shutdown() {
 	defer os.Exit(0)
 
 	ctx := context.Background()